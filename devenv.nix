@@ -9,12 +9,12 @@ let
   outputScadPath = "./output/output.scad";
   previewPngPath = "./output.png";
   outputStlPath = "./output/output.stl";
+  outputPartsPath = "./output/parts";
 in
 {
   packages = with pkgs; [
     golangci-lint
     graphviz
-    inotify-tools
   ];
 
   languages.go = {
@@ -36,7 +36,7 @@ in
       };
 
       watch = {
-        exec = "./build/render-loop.bash";
+        exec = "${lib.getExe go} run . watch --source . ${outputScadPath}";
       };
     };
 
@@ -78,14 +78,16 @@ in
         exec = "${lib.getExe go} run . render --production ${outputScadPath}";
         after = [ "app:makeOutputDir" ];
       };
+      "app:render-parts" = {
+        exec = "${lib.getExe go} run . render --production --split-parts --out-dir ${outputPartsPath}";
+      };
 
       "app:render-preview" = {
-        exec = "${lib.getExe openscad} -o ${previewPngPath} ${outputScadPath}";
-        after = [ "app:render-prod" ];
+        exec = "${lib.getExe go} run . render --production --format png --openscad ${lib.getExe openscad} ${previewPngPath}";
       };
       "app:render-stl" = {
-        exec = "${lib.getExe openscad} -o ${outputStlPath} ${outputScadPath}";
-        after = [ "app:render-prod" ];
+        exec = "${lib.getExe go} run . render --production --format stl ${outputStlPath}";
+        after = [ "app:makeOutputDir" ];
       };
 
       "app:test" = {
@@ -4,9 +4,12 @@ go 1.25.0
 
 require (
 	github.com/alecthomas/kong v1.9.0
+	github.com/fsnotify/fsnotify v1.10.1
 	github.com/go-gl/mathgl v1.0.0
 	github.com/ljanyst/ghostscad v0.2.2
+	github.com/pelletier/go-toml/v2 v2.4.3
 	github.com/stretchr/testify v1.10.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -15,8 +18,8 @@ require (
 	github.com/yuin/goldmark v1.4.13 // indirect
 	golang.org/x/image v0.0.0-20190321063152-3fc05d484e9f // indirect
 	golang.org/x/mod v0.24.0 // indirect
+	golang.org/x/sys v0.31.0 // indirect
 	golang.org/x/tools v0.31.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
 
 tool golang.org/x/tools/cmd/godoc
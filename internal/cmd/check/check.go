@@ -0,0 +1,134 @@
+package check
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"text/tabwriter"
+
+	"github.com/yeldiRium/3d-rack-brackets/internal/cmd/globals"
+	"github.com/yeldiRium/3d-rack-brackets/internal/cmd/output"
+	"github.com/yeldiRium/3d-rack-brackets/internal/cmd/render"
+	"github.com/yeldiRium/3d-rack-brackets/internal/mesh"
+	"github.com/yeldiRium/3d-rack-brackets/internal/printability"
+)
+
+type CheckCmd struct {
+	MaxOverhangAngle float64  `default:"45" group:"printer" help:"Largest angle from vertical in degrees at which surfaces may face down without support." placeholder:"DEGREES"`
+	MaxBridgeLength  float64  `default:"10" group:"printer" help:"Longest distance in mm that horizontal surfaces facing down may bridge." placeholder:"MM"`
+	Nozzle           float64  `default:"0.4" group:"printer" help:"Diameter of the printer's nozzle in mm." placeholder:"MM"`
+	MinWallThickness *float64 `group:"printer" help:"Thinnest wall in mm that prints. Defaults to two --nozzle diameters." placeholder:"MM"`
+	MinHoleDiameter  float64  `default:"2" group:"printer" help:"Smallest hole diameter in mm that prints." placeholder:"MM"`
+
+	Format string `default:"table" enum:"table,json" help:"Format of the report (${enum})." short:"f"`
+
+	Output string `arg:"" default:"-" type:"path"`
+
+	Print render.PrintFlags `embed:""`
+}
+
+func (check *CheckCmd) Validate() error {
+	if check.MaxOverhangAngle < 0 || check.MaxOverhangAngle > 90 {
+		return fmt.Errorf("--max-overhang-angle must be between 0 and 90, got %g", check.MaxOverhangAngle)
+	}
+	if check.Nozzle <= 0 {
+		return fmt.Errorf("--nozzle must be positive, got %g", check.Nozzle)
+	}
+
+	return nil
+}
+
+// partIssue is an issue of a single print part.
+type partIssue struct {
+	Part string `json:"part"`
+	printability.Issue
+}
+
+// Run analyses every selected print part in its print orientation and
+// reports the issues it finds. It fails if there are any, so that it can
+// guard exports.
+func (check *CheckCmd) Run(globals *globals.Globals) error {
+	_, printParts, err := check.Print.RenderCmd().PrintParts()
+	if err != nil {
+		return err
+	}
+
+	settings := check.settings()
+	issues := []partIssue{}
+	for _, printPart := range printParts {
+		globals.Logger.Debug("checking part", slog.String("part", printPart.Name))
+
+		shape, err := printPart.Primitive()
+		if err != nil {
+			return err
+		}
+		partMesh, err := mesh.FromPrimitive(shape)
+		if err != nil {
+			return fmt.Errorf("failed to mesh %s: %w", printPart.Name, err)
+		}
+		holes, err := mesh.PrimitiveHoles(shape)
+		if err != nil {
+			return fmt.Errorf("failed to find the holes of %s: %w", printPart.Name, err)
+		}
+
+		for _, issue := range printability.Check(partMesh, holes, settings) {
+			issues = append(issues, partIssue{Part: printPart.Name, Issue: issue})
+		}
+	}
+
+	outputFile, err := output.Open(check.Output, globals.Stdout)
+	if err != nil {
+		return fmt.Errorf("failed to open output stream: %w", err)
+	}
+	defer func() { _ = outputFile.Close() }()
+
+	switch check.Format {
+	case "json":
+		encoder := json.NewEncoder(outputFile)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(issues)
+	case "table":
+		err = writeTable(outputFile, issues)
+	default:
+		panic("unknown report format. this should not happen")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	if err := outputFile.Close(); err != nil {
+		return err
+	}
+
+	if len(issues) > 0 {
+		return fmt.Errorf("found %d printability issues", len(issues))
+	}
+	globals.Logger.Info("found no printability issues")
+
+	return nil
+}
+
+func (check *CheckCmd) settings() printability.Settings {
+	minWallThickness := 2 * check.Nozzle
+	if check.MinWallThickness != nil {
+		minWallThickness = *check.MinWallThickness
+	}
+
+	return printability.Settings{
+		MaxOverhangAngle: check.MaxOverhangAngle,
+		MaxBridgeLength:  check.MaxBridgeLength,
+		MinWallThickness: minWallThickness,
+		MinHoleDiameter:  check.MinHoleDiameter,
+	}
+}
+
+func writeTable(writer io.Writer, issues []partIssue) error {
+	table := tabwriter.NewWriter(writer, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(table, "part\tissue\tposition (mm)\tdetails")
+	for _, issue := range issues {
+		_, _ = fmt.Fprintf(table, "%s\t%s\t%.1f, %.1f, %.1f\t%s\n",
+			issue.Part, issue.Kind, issue.Position.X(), issue.Position.Y(), issue.Position.Z(), issue.Message)
+	}
+
+	return table.Flush()
+}
@@ -0,0 +1,151 @@
+package estimate
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"text/tabwriter"
+
+	"github.com/yeldiRium/3d-rack-brackets/internal/cmd/globals"
+	"github.com/yeldiRium/3d-rack-brackets/internal/cmd/output"
+	"github.com/yeldiRium/3d-rack-brackets/internal/cmd/render"
+	"github.com/yeldiRium/3d-rack-brackets/internal/filament"
+	"github.com/yeldiRium/3d-rack-brackets/internal/mesh"
+)
+
+type EstimateCmd struct {
+	Material         string   `default:"PLA" group:"filament" help:"Material of the filament. The densities of PLA, PETG and ABS are known, other materials need --density."`
+	Density          *float64 `group:"filament" help:"Density of the filament in g/cm³. Overrides the density of --material." placeholder:"G/CM3"`
+	FilamentDiameter float64  `default:"1.75" group:"filament" help:"Diameter of the filament in mm." placeholder:"MM"`
+	Infill           *float64 `group:"filament" help:"Infill of the parts' interior in percent. Parts are estimated solid without it." placeholder:"PERCENT"`
+	ShellThickness   float64  `default:"1.2" group:"filament" help:"Thickness of the solid walls, top and bottom of the parts in mm. Only used with --infill." placeholder:"MM"`
+
+	Format string `default:"table" enum:"table,json" help:"Format of the report (${enum})." short:"f"`
+
+	Output string `arg:"" default:"-" type:"path"`
+
+	Print render.PrintFlags `embed:""`
+}
+
+func (estimate *EstimateCmd) Validate() error {
+	if estimate.Density == nil {
+		if _, err := filament.LookupMaterial(estimate.Material); err != nil {
+			return fmt.Errorf("%w, or give its --density", err)
+		}
+	} else if *estimate.Density <= 0 {
+		return fmt.Errorf("--density must be positive, got %g", *estimate.Density)
+	}
+	if estimate.FilamentDiameter <= 0 {
+		return fmt.Errorf("--filament-diameter must be positive, got %g", estimate.FilamentDiameter)
+	}
+	if estimate.Infill != nil && (*estimate.Infill < 0 || *estimate.Infill > 100) {
+		return fmt.Errorf("--infill must be between 0 and 100, got %g", *estimate.Infill)
+	}
+	if estimate.ShellThickness < 0 {
+		return fmt.Errorf("--shell-thickness must not be negative, got %g", estimate.ShellThickness)
+	}
+
+	return nil
+}
+
+// partEstimate is the estimate of a single print part.
+type partEstimate struct {
+	Name string `json:"name"`
+	filament.Estimate
+}
+
+// report is the estimate of every print part and their total.
+type report struct {
+	Material string            `json:"material"`
+	Parts    []partEstimate    `json:"parts"`
+	Total    filament.Estimate `json:"total"`
+}
+
+// Run meshes every selected print part and estimates the filament it takes.
+func (estimate *EstimateCmd) Run(globals *globals.Globals) error {
+	_, printParts, err := estimate.Print.RenderCmd().PrintParts()
+	if err != nil {
+		return err
+	}
+
+	settings := estimate.settings()
+	result := report{Material: estimate.Material}
+	for _, printPart := range printParts {
+		globals.Logger.Debug("measuring part", slog.String("part", printPart.Name))
+
+		shape, err := printPart.Primitive()
+		if err != nil {
+			return err
+		}
+		partMesh, err := mesh.FromPrimitive(shape)
+		if err != nil {
+			return fmt.Errorf("failed to mesh %s: %w", printPart.Name, err)
+		}
+
+		partResult := settings.Estimate(partMesh.Volume(), partMesh.Area())
+		result.Parts = append(result.Parts, partEstimate{Name: printPart.Name, Estimate: partResult})
+		result.Total = result.Total.Add(partResult)
+	}
+
+	outputFile, err := output.Open(estimate.Output, globals.Stdout)
+	if err != nil {
+		return fmt.Errorf("failed to open output stream: %w", err)
+	}
+	defer func() { _ = outputFile.Close() }()
+
+	switch estimate.Format {
+	case "json":
+		encoder := json.NewEncoder(outputFile)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(result)
+	case "table":
+		err = result.writeTable(outputFile)
+	default:
+		panic("unknown report format. this should not happen")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+
+	return outputFile.Close()
+}
+
+// settings returns the filament settings for the flags, which were validated
+// before.
+func (estimate *EstimateCmd) settings() filament.Settings {
+	settings := filament.Settings{
+		Diameter:       estimate.FilamentDiameter,
+		Infill:         100,
+		ShellThickness: estimate.ShellThickness,
+	}
+	if estimate.Density != nil {
+		settings.Density = *estimate.Density
+	} else {
+		material, err := filament.LookupMaterial(estimate.Material)
+		if err != nil {
+			panic("the material was validated. this should not happen")
+		}
+		settings.Density = material.Density
+	}
+	if estimate.Infill != nil {
+		settings.Infill = *estimate.Infill
+	}
+
+	return settings
+}
+
+func (report report) writeTable(writer io.Writer) error {
+	table := tabwriter.NewWriter(writer, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(table, "part\tvolume (cm³)\tfilament (m)\tmass (g)")
+	for _, part := range report.Parts {
+		writeRow(table, part.Name, part.Estimate)
+	}
+	writeRow(table, "total", report.Total)
+
+	return table.Flush()
+}
+
+func writeRow(writer io.Writer, name string, estimate filament.Estimate) {
+	_, _ = fmt.Fprintf(writer, "%s\t%.1f\t%.2f\t%.1f\n", name, estimate.Volume, estimate.Length, estimate.Mass)
+}
@@ -1,12 +1,21 @@
 package globals
 
 import (
+	"context"
 	"io"
 	"log/slog"
 )
 
 type Globals struct {
+	// Context is cancelled when the program is interrupted.
+	Context context.Context //nolint:containedctx
+
 	Debug  bool
 	Logger *slog.Logger
 	Stdout io.Writer
+
+	// Cleanup releases what the program set up before running the command,
+	// e.g. it stops the CPU profile. Commands that replace the process must
+	// call it first, since deferred calls do not run then.
+	Cleanup func()
 }
@@ -0,0 +1,65 @@
+package inspect
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/yeldiRium/3d-rack-brackets/internal/cmd/globals"
+	"github.com/yeldiRium/3d-rack-brackets/internal/cmd/output"
+	"github.com/yeldiRium/3d-rack-brackets/internal/cmd/render"
+	"github.com/yeldiRium/3d-rack-brackets/internal/design"
+	"github.com/yeldiRium/3d-rack-brackets/internal/shapes"
+	"github.com/yeldiRium/3d-rack-brackets/internal/shapes/rack"
+)
+
+type InspectCmd struct {
+	Config design.Flag `help:"Load the rack design from a YAML, JSON or TOML file. Flags override its values." placeholder:"FILE" type:"existingfile"`
+
+	Format string `default:"dot" enum:"dot,json" help:"Format of the anchor graph (${enum})." short:"f"`
+
+	Output string `arg:"" default:"-" type:"path"`
+
+	Rack render.RackFlags `embed:""`
+}
+
+// Run resolves the rack's anchors and writes the anchor graph, starting at the
+// foot. The graph is written even if resolving fails, so that the failure can
+// be diagnosed with it.
+func (inspect *InspectCmd) Run(globals *globals.Globals) error {
+	globals.Logger.Debug("starting to inspect", slog.String("format", inspect.Format), slog.String("output", inspect.Output))
+	rackConfig, err := inspect.Rack.Config()
+	if err != nil {
+		return err
+	}
+
+	shape := rack.MakeRack(rackConfig)
+	resolveErr := shapes.ResolveAnchors(shape.Foot)
+	if resolveErr != nil {
+		resolveErr = fmt.Errorf("failed to resolve anchors: %w", resolveErr)
+	}
+
+	outputFile, err := output.Open(inspect.Output, globals.Stdout)
+	if err != nil {
+		return fmt.Errorf("failed to open output stream: %w", err)
+	}
+	defer func() { _ = outputFile.Close() }()
+
+	graph := shapes.CollectGraph(shape.Foot)
+	switch inspect.Format {
+	case "json":
+		encoder := json.NewEncoder(outputFile)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(graph)
+	case "dot":
+		err = graph.WriteDOT(outputFile)
+	default:
+		panic("unknown graph format. this should not happen")
+	}
+	if err != nil {
+		return errors.Join(resolveErr, fmt.Errorf("failed to write anchor graph: %w", err))
+	}
+
+	return errors.Join(resolveErr, outputFile.Close())
+}
@@ -0,0 +1,33 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// Stdout is the path that selects standard output instead of a file.
+const Stdout = "-"
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+// Open creates the file at path for writing, or returns stdout if path is
+// Stdout. Closing the returned writer does not close stdout.
+func Open(path string, stdout io.Writer) (io.WriteCloser, error) {
+	if path == Stdout {
+		return nopCloser{stdout}, nil
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open output file: %w", err)
+	}
+
+	return file, nil
+}
@@ -0,0 +1,99 @@
+package plate
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ljanyst/ghostscad/primitive"
+
+	"github.com/yeldiRium/3d-rack-brackets/internal/cmd/globals"
+	"github.com/yeldiRium/3d-rack-brackets/internal/cmd/render"
+	"github.com/yeldiRium/3d-rack-brackets/internal/ghostscad"
+	"github.com/yeldiRium/3d-rack-brackets/internal/plate"
+)
+
+type PlateCmd struct {
+	Spacing float64 `default:"5" help:"Distance between the parts on a plate in mm." placeholder:"MM"`
+
+	Format string `default:"scad" enum:"scad,stl" group:"output" help:"Format to write the plates in: OpenSCAD code or an STL mesh (${enum})."`
+	ASCII  bool   `group:"output" help:"Write STL meshes as text instead of binary."`
+
+	OutDir string `arg:"" help:"Directory to write the plates to." type:"path"`
+
+	Print render.PrintFlags `embed:""`
+}
+
+func (plateCmd *PlateCmd) Validate() error {
+	if plateCmd.Spacing < 0 {
+		return fmt.Errorf("--spacing must not be negative, got %g", plateCmd.Spacing)
+	}
+	if plateCmd.ASCII && plateCmd.Format != "stl" {
+		return errors.New("--ascii requires --format stl")
+	}
+
+	return nil
+}
+
+// Run arranges the selected print parts on as few build plates as possible
+// and writes every plate to its own file in the output directory, named
+// plate-0, plate-1 and so on.
+func (plateCmd *PlateCmd) Run(globals *globals.Globals) error {
+	renderCmd := plateCmd.Print.RenderCmd()
+	renderCmd.Format = plateCmd.Format
+	renderCmd.ASCII = plateCmd.ASCII
+	assembly, printParts, err := renderCmd.PrintParts()
+	if err != nil {
+		return err
+	}
+	buildVolume := assembly.Config.BuildVolume
+	if buildVolume.X() <= 0 || buildVolume.Y() <= 0 || buildVolume.Z() <= 0 {
+		return errors.New("arranging the parts requires a --build-volume")
+	}
+
+	items := make([]plate.Item, 0, len(printParts))
+	for _, printPart := range printParts {
+		bounds, err := printPart.Bounds()
+		if err != nil {
+			return err
+		}
+		items = append(items, plate.Item{Name: printPart.Name, Bounds: bounds})
+	}
+
+	plates, err := plate.Arrange(items, buildVolume, plateCmd.Spacing)
+	if err != nil {
+		return fmt.Errorf("failed to arrange the parts: %w", err)
+	}
+
+	err = os.MkdirAll(plateCmd.OutDir, 0o755)
+	if err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	for index, arrangedPlate := range plates {
+		name := fmt.Sprintf("plate-%d", index)
+		path := filepath.Join(plateCmd.OutDir, name+"."+plateCmd.Format)
+
+		names := make([]string, 0, len(arrangedPlate.Placements))
+		shape := primitive.NewList()
+		for _, placement := range arrangedPlate.Placements {
+			printPart := printParts[placement.Item]
+			names = append(names, printPart.Name)
+			printShape, err := printPart.Primitive()
+			if err != nil {
+				return err
+			}
+			shape.Add(ghostscad.NewMultMatrix(placement.Transform, printShape))
+		}
+		globals.Logger.Info("writing plate", slog.String("plate", name), slog.String("parts", strings.Join(names, ", ")), slog.String("output", path))
+
+		err = renderCmd.Write(globals, path, name, shape)
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,50 @@
+package render
+
+import (
+	"github.com/yeldiRium/3d-rack-brackets/internal/cmd/output"
+	"github.com/yeldiRium/3d-rack-brackets/internal/design"
+	"github.com/yeldiRium/3d-rack-brackets/internal/shapes/rack"
+)
+
+// PrintFlags are the flags that load the rack and its quality. RenderCmd
+// embeds them, as do the commands that work on the rack's print parts instead
+// of rendering it.
+type PrintFlags struct {
+	Config design.Flag `help:"Load the rack design from a YAML, JSON or TOML file. Flags override its values." placeholder:"FILE" type:"existingfile"`
+
+	Production bool     `group:"quality" help:"Render with a finer resolution."                                            short:"p"`
+	Fa         *float64 `group:"quality" help:"Minimum angle of a circle fragment. Overrides --production."`
+	Fs         *float64 `group:"quality" help:"Minimum size of a circle fragment. Overrides --production."`
+	Fn         *uint16  `group:"quality" help:"Number of fragments of a full circle. Overrides --fa and --fs if non-zero."`
+
+	Parts []string `default:"${parts}" enum:"${parts}" help:"Parts of the rack to work on (${enum})."`
+
+	Rack RackFlags `embed:""`
+}
+
+// RenderCmd returns a command that renders the rack of the flags as OpenSCAD
+// code to stdout. Callers may pick another format before rendering.
+func (flags *PrintFlags) RenderCmd() *RenderCmd {
+	return &RenderCmd{
+		PrintFlags: *flags,
+		Format:     formatSCAD,
+		Mesher:     mesherNative,
+		Output:     output.Stdout,
+	}
+}
+
+// PrintParts validates the command, assembles the rack and returns its
+// selected print parts in print orientation. It sets the fragment settings of
+// the ghostscad package.
+func (render *RenderCmd) PrintParts() (*Assembly, []rack.PrintPart, error) {
+	if err := render.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	assembly, err := render.Assemble()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return assembly, assembly.Rack.PrintParts(render.selectedParts()...), nil
+}
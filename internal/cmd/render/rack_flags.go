@@ -0,0 +1,207 @@
+package render
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/kong"
+	"github.com/go-gl/mathgl/mgl64"
+
+	"github.com/yeldiRium/3d-rack-brackets/internal/shapes/holes"
+	"github.com/yeldiRium/3d-rack-brackets/internal/shapes/rack"
+)
+
+// RackFlags exposes every field of rack.Config as a flag. The defaults are
+// interpolated from rack.DefaultConfig via DefaultVars.
+type RackFlags struct {
+	Units uint8 `default:"${units}" group:"rack" help:"Height of the rack in rack units."`
+
+	Screw       string  `default:"m6"    enum:"${screw_standards}" group:"rack" help:"Screw standard of the screw holes (${enum})."`
+	HoleType    string  `default:"round" enum:"${hole_types}"      group:"rack" help:"Type of the screw holes (${enum})."`
+	ScrewRadius float64 `group:"rack" help:"Radius of the screw holes' bore in mm. Overrides the radius that --screw and --hole-type call for." placeholder:"MM"`
+	Insert      *string `enum:"${inserts}" group:"rack" help:"Heat-set insert of the heat-set-insert holes (${enum}). Defaults to the insert that --screw calls for." placeholder:"SIZE"`
+
+	SpineWidth      float64 `default:"${spine_width}"       group:"rack" help:"Width of the spine in mm."`
+	SpineThickness  float64 `default:"${spine_thickness}"   group:"rack" help:"Thickness of the spine in mm."`
+	SpineInlayWidth float64 `default:"${spine_inlay_width}" group:"rack" help:"Depth of the inlay in the foot that holds the spine in mm."`
+
+	SegmentHeight float64 `default:"${segment_height}" group:"rack" help:"Height of a single rack unit in mm."`
+
+	HoleStandard string    `default:"eia-310" enum:"${hole_standards}" group:"rack" help:"Standard of the hole pattern along the spine (${enum})."`
+	HolePitches  []float64 `group:"rack" help:"Distances between the holes of a rack unit in mm, from bottom to top. Overrides the pitches of --hole-standard." placeholder:"MM"`
+
+	FootLength         float64 `default:"${foot_length}"          group:"rack" help:"Length of the foot in mm."`
+	FootThicknessFront float64 `default:"${foot_thickness_front}" group:"rack" help:"Thickness of the foot at the front in mm."`
+	FootThicknessBack  float64 `default:"${foot_thickness_back}"  group:"rack" help:"Thickness of the foot at the back in mm."`
+	FootSpacerHeight   float64 `default:"${foot_spacer_height}"   group:"rack" help:"Height of the spacer between foot and lowest segment in mm."`
+
+	FootHoles uint8 `default:"${foot_holes}" group:"rack" help:"Number of holes through the foot to attach the rack to a desk."`
+
+	FootScrew    string  `default:"m6"    enum:"${screw_standards}" group:"rack" help:"Screw standard of the holes through the foot (${enum})."`
+	FootHoleType string  `default:"round" enum:"${hole_types}"      group:"rack" help:"Type of the holes through the foot (${enum}). Their fronts face the desk."`
+	FootInsert   *string `enum:"${inserts}" group:"rack" help:"Heat-set insert of the foot's heat-set-insert holes (${enum}). Defaults to the insert that --foot-screw calls for." placeholder:"SIZE"`
+
+	SideBracePadding         float64 `default:"${side_brace_padding}"          group:"rack" help:"Distance between the side brace's outer edges and its attachment to the spine in mm."`
+	SideBraceInnerPadding    float64 `default:"${side_brace_inner_padding}"    group:"rack" help:"Half width of the side brace's inner cutout at the spine in mm."`
+	SideBraceAttachmentDepth float64 `default:"${side_brace_attachment_depth}" group:"rack" help:"Length of the side brace's attachment to the foot in mm."`
+	SideBraceWidth           float64 `default:"${side_brace_width}"            group:"rack" help:"Width of the side brace in mm."`
+
+	SideBraceSides string `default:"${side_brace_sides}" enum:"${sides}" group:"rack" help:"Sides of the spine that are braced (${enum})."`
+
+	BuildVolume []float64 `default:"${build_volume}" group:"rack" help:"Build volume of the printer along x, y and z in mm. Rails that are too long for it are split into pieces and units whose side braces are too long are left unbraced. 0,0,0 disables both." placeholder:"MM"`
+	Joint       string    `default:"${joint}"        enum:"${joints}" group:"rack" help:"Joint between the pieces of a split rail (${enum})."`
+	JointDepth  float64   `default:"${joint_depth}"  group:"rack" help:"Depth of pins and dovetails, or how far splice plates reach past their bolts, in mm."`
+}
+
+// DefaultVars provides the default values for RackFlags.
+func DefaultVars() kong.Vars {
+	config := rack.DefaultConfig()
+	format := func(value float64) string {
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+
+	screwStandards := make([]string, 0, len(holes.Screws))
+	for standard := range holes.Screws {
+		screwStandards = append(screwStandards, standard)
+	}
+	slices.Sort(screwStandards)
+
+	holeTypes := make([]string, 0, len(holes.Types))
+	for _, holeType := range holes.Types {
+		holeTypes = append(holeTypes, string(holeType))
+	}
+
+	holeStandards := make([]string, 0, len(rack.HoleStandards))
+	for standard := range rack.HoleStandards {
+		holeStandards = append(holeStandards, standard)
+	}
+	slices.Sort(holeStandards)
+
+	sides := make([]string, 0, len(rack.AllSides))
+	for _, side := range rack.AllSides {
+		sides = append(sides, string(side))
+	}
+
+	joints := make([]string, 0, len(rack.AllJoints))
+	for _, joint := range rack.AllJoints {
+		joints = append(joints, string(joint))
+	}
+
+	parts := make([]string, 0, len(rack.Parts))
+	for _, part := range rack.Parts {
+		parts = append(parts, string(part))
+	}
+
+	return kong.Vars{
+		"units":                       strconv.FormatUint(uint64(config.Units), 10),
+		"screw_standards":             strings.Join(screwStandards, ","),
+		"hole_types":                  strings.Join(holeTypes, ","),
+		"hole_standards":              strings.Join(holeStandards, ","),
+		"inserts":                     strings.Join(holes.InsertNames(), ","),
+		"parts":                       strings.Join(parts, ","),
+		"sides":                       strings.Join(sides, ","),
+		"spine_width":                 format(config.SpineWidth),
+		"spine_thickness":             format(config.SpineThickness),
+		"spine_inlay_width":           format(config.SpineInlayWidth),
+		"segment_height":              format(config.SegmentHeight),
+		"foot_length":                 format(config.FootLength),
+		"foot_thickness_front":        format(config.FootThicknessFront),
+		"foot_thickness_back":         format(config.FootThicknessBack),
+		"foot_spacer_height":          format(config.FootSpacerHeight),
+		"foot_holes":                  strconv.FormatUint(uint64(config.FootHoles), 10),
+		"side_brace_padding":          format(config.SideBracePadding),
+		"side_brace_inner_padding":    format(config.SideBraceInnerPadding),
+		"side_brace_attachment_depth": format(config.SideBraceAttachmentDepth),
+		"side_brace_width":            format(config.SideBraceWidth),
+		"side_brace_sides":            string(config.SideBraceSides),
+		"build_volume":                format(config.BuildVolume.X()) + "," + format(config.BuildVolume.Y()) + "," + format(config.BuildVolume.Z()),
+		"joints":                      strings.Join(joints, ","),
+		"joint":                       string(config.Joint),
+		"joint_depth":                 format(config.JointDepth),
+	}
+}
+
+// Config converts the flags into a validated rack.Config.
+func (flags *RackFlags) Config() (rack.Config, error) {
+	screw, err := screwWithInsert(flags.Screw, flags.Insert)
+	if err != nil {
+		return rack.Config{}, err
+	}
+	if flags.ScrewRadius != 0 {
+		screw.Radius = flags.ScrewRadius
+		screw.TapDrillRadius = flags.ScrewRadius
+		screw.ClearanceRadius = flags.ScrewRadius
+	}
+
+	footScrew, err := screwWithInsert(flags.FootScrew, flags.FootInsert)
+	if err != nil {
+		return rack.Config{}, err
+	}
+
+	if len(flags.BuildVolume) != 3 {
+		return rack.Config{}, fmt.Errorf("%w: build volume must have 3 dimensions, got %d", rack.ErrInvalidConfig, len(flags.BuildVolume))
+	}
+
+	holePitches := flags.HolePitches
+	if len(holePitches) == 0 {
+		holePitches = slices.Clone(rack.HoleStandards[flags.HoleStandard])
+	}
+
+	config := rack.Config{
+		Units: flags.Units,
+
+		Hole: holes.ProfileFor(holes.Type(flags.HoleType), screw),
+
+		SpineWidth:      flags.SpineWidth,
+		SpineThickness:  flags.SpineThickness,
+		SpineInlayWidth: flags.SpineInlayWidth,
+
+		SegmentHeight: flags.SegmentHeight,
+
+		HolePitches: holePitches,
+
+		FootLength:         flags.FootLength,
+		FootThicknessFront: flags.FootThicknessFront,
+		FootThicknessBack:  flags.FootThicknessBack,
+		FootSpacerHeight:   flags.FootSpacerHeight,
+
+		FootHoles: flags.FootHoles,
+		FootHole:  holes.ProfileFor(holes.Type(flags.FootHoleType), footScrew),
+
+		SideBracePadding:         flags.SideBracePadding,
+		SideBraceInnerPadding:    flags.SideBraceInnerPadding,
+		SideBraceAttachmentDepth: flags.SideBraceAttachmentDepth,
+		SideBraceWidth:           flags.SideBraceWidth,
+
+		SideBraceSides: rack.Sides(flags.SideBraceSides),
+
+		BuildVolume: mgl64.Vec3{flags.BuildVolume[0], flags.BuildVolume[1], flags.BuildVolume[2]},
+
+		Joint:      rack.Joint(flags.Joint),
+		JointDepth: flags.JointDepth,
+	}
+
+	if err := config.Validate(); err != nil {
+		return rack.Config{}, err
+	}
+
+	return config, nil
+}
+
+// screwWithInsert looks up the screw standard and replaces its default insert
+// by the given one, if any.
+func screwWithInsert(standard string, insert *string) (holes.Screw, error) {
+	screw := holes.Screws[standard]
+	if insert == nil {
+		return screw, nil
+	}
+
+	if fits := holes.Inserts[*insert].Screw; fits != standard {
+		return holes.Screw{}, fmt.Errorf("%w: insert %s fits %s screws, not %s", rack.ErrInvalidConfig, *insert, fits, standard)
+	}
+	screw.Insert = *insert
+
+	return screw, nil
+}
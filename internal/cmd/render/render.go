@@ -2,24 +2,174 @@ package render
 
 import (
 	"bufio"
+	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/go-gl/mathgl/mgl64"
 	"github.com/ljanyst/ghostscad/primitive"
 
 	"github.com/yeldiRium/3d-rack-brackets/internal/cmd/globals"
+	"github.com/yeldiRium/3d-rack-brackets/internal/cmd/output"
 	"github.com/yeldiRium/3d-rack-brackets/internal/ghostscad"
+	"github.com/yeldiRium/3d-rack-brackets/internal/mesh"
+	"github.com/yeldiRium/3d-rack-brackets/internal/openscad"
 	"github.com/yeldiRium/3d-rack-brackets/internal/shapes"
 	"github.com/yeldiRium/3d-rack-brackets/internal/shapes/rack"
 )
 
 type RenderCmd struct {
-	Production bool   `short:"p"`
-	Output     string `arg:""    default:"-" type:"path"`
+	PrintFlags `embed:""`
+
+	ShowAnchors bool `help:"Mark the anchors of the emitted parts with highlighted arrows and labels for debugging."`
+
+	Format     string   `default:"scad" enum:"scad,stl,3mf,off,png" group:"output" help:"Format to write: OpenSCAD code, an STL mesh, a 3MF package with every part as its own object in print orientation, or an OFF mesh or PNG image rendered by OpenSCAD (${enum})."`
+	Mesher     string   `default:"native" enum:"native,openscad" group:"output" help:"Whether STL and 3MF files are meshed natively or by OpenSCAD (${enum})."`
+	OpenSCAD   string   `default:"openscad" env:"OPENSCAD" group:"output" help:"Path or name of the OpenSCAD binary." name:"openscad" placeholder:"PATH"`
+	ASCII      bool     `group:"output" help:"Write STL meshes as text instead of binary."`
+	SplitParts bool     `group:"output" help:"Write every part to its own file in --out-dir, in print orientation, next to the assembled rack in rack.<format>."`
+	OutDir     string   `group:"output" help:"Directory to write the parts to with --split-parts." placeholder:"DIR" type:"path"`
+	Material   string   `group:"output" help:"Material to recommend for the parts in 3MF packages, e.g. PETG."`
+	Infill     *float64 `group:"output" help:"Infill to recommend for the parts in 3MF packages in percent." placeholder:"PERCENT"`
+
+	Output string `arg:"" default:"-" type:"path"`
+}
+
+const (
+	formatSCAD = "scad"
+	formatSTL  = "stl"
+	format3MF  = "3mf"
+
+	mesherNative = "native"
+)
+
+// assembledName is the name of the file in --out-dir, without extension, that
+// the assembled rack is written to with --split-parts.
+const assembledName = "rack"
+
+func (render *RenderCmd) Validate() error {
+	if render.meshesNatively() && render.ShowAnchors {
+		return fmt.Errorf("--show-anchors cannot be combined with --format %s and --mesher native, since the anchor labels are text", render.Format)
+	}
+	if render.ASCII && (render.Format != formatSTL || !render.meshesNatively()) {
+		return errors.New("--ascii requires --format stl and --mesher native")
+	}
+	if render.Format == format3MF && render.meshesNatively() && render.SplitParts {
+		return errors.New("--format 3mf writes every part as its own object already, so it cannot be combined with --split-parts")
+	}
+	if render.Infill != nil && (*render.Infill < 0 || *render.Infill > 100) {
+		return fmt.Errorf("--infill must be between 0 and 100, got %g", *render.Infill)
+	}
+	if !render.SplitParts {
+		return nil
+	}
+	if render.OutDir == "" {
+		return errors.New("--split-parts requires --out-dir")
+	}
+	if render.Output != output.Stdout {
+		return fmt.Errorf("--split-parts writes the assembled rack to %s.%s in --out-dir, so it cannot be combined with an output file", assembledName, render.Format)
+	}
+
+	return nil
+}
+
+// Assembly is the rack as it is rendered without --split-parts.
+type Assembly struct {
+	Config rack.Config
+	Rack   *rack.Rack
+
+	// Shape holds the selected parts in place, dropped onto z=0.
+	Shape primitive.Primitive
+
+	// Bounds is the bounding box of the selected parts in Shape, without the
+	// anchor markers.
+	Bounds mesh.Box
+}
+
+// Assemble builds the rack from the flags. It sets the fragment settings of
+// the ghostscad package.
+func (render *RenderCmd) Assemble() (*Assembly, error) {
+	rackConfig, err := render.Rack.Config()
+	if err != nil {
+		return nil, err
+	}
+
+	ghostscad.ResetFragments()
+	if render.Production {
+		ghostscad.SetFa(5)
+		ghostscad.SetFs(0.5)
+	}
+	if render.Fa != nil {
+		ghostscad.SetFa(*render.Fa)
+	}
+	if render.Fs != nil {
+		ghostscad.SetFs(*render.Fs)
+	}
+	if render.Fn != nil {
+		ghostscad.SetFn(*render.Fn)
+	}
+
+	shape := rack.MakeRack(rackConfig)
+	err = shapes.ResolveAnchors(shape.Foot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve anchors: %w", err)
+	}
+
+	selectedParts := shape.Select(render.selectedParts()...)
+	anchoreds := make([]shapes.Anchored, 0, len(selectedParts.Items))
+	for _, part := range selectedParts.Items {
+		if anchored, ok := part.(shapes.Anchored); ok {
+			anchoreds = append(anchoreds, anchored)
+		}
+	}
+	bounds, err := rack.Bounds(anchoreds...)
+	if err != nil {
+		return nil, err
+	}
+	if render.ShowAnchors {
+		selectedParts.Add(shapes.NewAnchorMarkers(anchoreds...))
+	}
+
+	// The rack is dropped onto z=0, so that it stands on the build plate and
+	// the ground of OpenSCAD's preview.
+	drop := 0.0
+	if !bounds.IsEmpty() {
+		drop = -bounds.Min.Z()
+	}
+	dropTransform := mgl64.Translate3D(0, 0, drop)
+
+	return &Assembly{
+		Config: rackConfig,
+		Rack:   shape,
+		Shape:  primitive.NewTranslation(mgl64.Vec3{0, 0, drop}, selectedParts),
+		Bounds: bounds.Transformed(dropTransform),
+	}, nil
+}
+
+func (render *RenderCmd) selectedParts() []rack.Part {
+	parts := make([]rack.Part, 0, len(render.Parts))
+	for _, part := range render.Parts {
+		parts = append(parts, rack.Part(part))
+	}
+
+	return parts
+}
+
+// meshesNatively reports whether the selected format is meshed by the mesh
+// package rather than by OpenSCAD.
+func (render *RenderCmd) meshesNatively() bool {
+	return (render.Format == formatSTL || render.Format == format3MF) && render.Mesher == mesherNative
+}
+
+// writesPrintParts reports whether the print parts are written on their own,
+// in print orientation, rather than only as part of the assembled rack.
+func (render *RenderCmd) writesPrintParts() bool {
+	return render.SplitParts || (render.Format == format3MF && render.meshesNatively())
 }
 
 func (render *RenderCmd) Run(globals *globals.Globals) error {
@@ -29,50 +179,168 @@ func (render *RenderCmd) Run(globals *globals.Globals) error {
 		globals.Logger.Debug("done rendering", slog.Duration("elapsed", time.Since(startTime)))
 	}()
 
-	if render.Production {
-		ghostscad.SetFa(5)
-		ghostscad.SetFs(0.5)
+	assembly, printParts, err := render.PrintParts()
+	if err != nil {
+		return err
+	}
+	err = render.report(globals.Logger, assembly, printParts)
+	if err != nil {
+		return err
 	}
 
-	output, err := render.ChooseOutput(globals.Stdout)
+	if render.Format == format3MF && render.meshesNatively() {
+		return render.write3MF(render.Output, globals.Stdout, printParts)
+	}
+	if !render.SplitParts {
+		return render.Write(globals, render.Output, assembledName, assembly.Shape)
+	}
+
+	err = os.MkdirAll(render.OutDir, 0o755)
+	if err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	err = render.Write(globals, filepath.Join(render.OutDir, assembledName+"."+render.Format), assembledName, assembly.Shape)
+	if err != nil {
+		return err
+	}
+	for _, printPart := range printParts {
+		path := filepath.Join(render.OutDir, printPart.Name+"."+render.Format)
+		globals.Logger.Debug("writing part", slog.String("part", printPart.Name), slog.String("output", path))
+
+		shape, err := printPart.Primitive()
+		if err != nil {
+			return err
+		}
+		err = render.Write(globals, path, printPart.Name, shape)
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %w", printPart.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// report logs the size of the assembled rack and warns about print parts that
+// do not fit the build volume in their print orientation. The print parts are
+// only measured if they are written on their own, see writesPrintParts.
+func (render *RenderCmd) report(logger *slog.Logger, assembly *Assembly, printParts []rack.PrintPart) error {
+	size := assembly.Bounds.Size()
+	logger.Info("assembled the rack",
+		slog.String("footprint", fmt.Sprintf("%.1f x %.1f mm", size.X(), size.Y())),
+		slog.String("height", fmt.Sprintf("%.1f mm", size.Z())),
+	)
+
+	buildVolume := assembly.Config.BuildVolume
+	if buildVolume == (mgl64.Vec3{}) || !render.writesPrintParts() {
+		return nil
+	}
+	for _, printPart := range printParts {
+		box, err := printPart.Bounds()
+		if err != nil {
+			return err
+		}
+		if !box.Fits(buildVolume) {
+			partSize := box.Size()
+			logger.Warn("part does not fit the build volume",
+				slog.String("part", printPart.Name),
+				slog.String("size", fmt.Sprintf("%.1f x %.1f x %.1f mm", partSize.X(), partSize.Y(), partSize.Z())),
+				slog.String("buildVolume", fmt.Sprintf("%g x %g x %g mm", buildVolume.X(), buildVolume.Y(), buildVolume.Z())),
+			)
+		}
+	}
+
+	return nil
+}
+
+// Write writes shape to path in the selected format, see output.Open. STL
+// meshes are named after name.
+func (render *RenderCmd) Write(globals *globals.Globals, path string, name string, shape primitive.Primitive) error {
+	switch {
+	case render.Format == formatSCAD:
+		return writeSCAD(path, globals.Stdout, shape)
+	case render.Format == formatSTL && render.meshesNatively():
+		return render.writeSTL(path, globals.Stdout, name, shape)
+	default:
+		return render.export(globals, path, name, shape)
+	}
+}
+
+// writeSTL meshes shape natively and writes it to path as an STL file.
+func (render *RenderCmd) writeSTL(path string, stdout io.Writer, name string, shape primitive.Primitive) error {
+	mesh, err := mesh.FromPrimitive(shape)
+	if err != nil {
+		return fmt.Errorf("failed to mesh %s: %w", name, err)
+	}
+
+	outputFile, err := output.Open(path, stdout)
 	if err != nil {
 		return fmt.Errorf("failed to open output stream: %w", err)
 	}
-	bufferedOutput := bufio.NewWriter(output)
+	defer func() { _ = outputFile.Close() }()
 
-	segmentCount := 3
-	shape := rack.MakeRack(uint8(segmentCount))
-	err = shapes.ResolveAnchors(shape.Foot)
+	if render.ASCII {
+		err = mesh.WriteASCIISTL(outputFile, name)
+	} else {
+		err = mesh.WriteSTL(outputFile, name)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to resolve anchors: %w", err)
+		return err
 	}
 
-	orientedShape := primitive.NewRotation(mgl64.Vec3{0, 0, 0}, shape)
-	translatedShape := primitive.NewTranslation(mgl64.Vec3{0, 0, float64(rack.RackFootThicknessFront)}, orientedShape)
+	return outputFile.Close()
+}
 
-	ghostscad.RenderGlobals(bufferedOutput)
-	translatedShape.Render(bufferedOutput)
+// export has OpenSCAD render shape and writes the result to path. Nothing is
+// written if OpenSCAD fails.
+func (render *RenderCmd) export(globals *globals.Globals, path string, name string, shape primitive.Primitive) error {
+	var source, result bytes.Buffer
+	if err := renderSCAD(&source, shape); err != nil {
+		return err
+	}
+
+	runner := &openscad.Runner{
+		Binary: render.OpenSCAD,
+		Logger: globals.Logger.With(slog.String("part", name)),
+	}
+	if err := runner.Export(globals.Context, &source, render.Format, &result); err != nil {
+		return err
+	}
 
-	err = bufferedOutput.Flush()
+	outputFile, err := output.Open(path, globals.Stdout)
 	if err != nil {
+		return fmt.Errorf("failed to open output stream: %w", err)
+	}
+	defer func() { _ = outputFile.Close() }()
+
+	if _, err := result.WriteTo(outputFile); err != nil {
 		return err
 	}
 
-	return nil
+	return outputFile.Close()
 }
 
-func (render *RenderCmd) ChooseOutput(stdout io.Writer) (io.Writer, error) {
-	var output io.Writer
-	if render.Output == "-" {
-		output = stdout
-	} else {
-		file, err := os.Create(render.Output)
-		if err != nil {
-			return nil, fmt.Errorf("failed to open output file: %w", err)
-		}
+// writeSCAD writes the OpenSCAD code of shape to path, see output.Open.
+func writeSCAD(path string, stdout io.Writer, shape primitive.Primitive) error {
+	outputFile, err := output.Open(path, stdout)
+	if err != nil {
+		return fmt.Errorf("failed to open output stream: %w", err)
+	}
+	defer func() { _ = outputFile.Close() }()
 
-		output = file
+	err = renderSCAD(outputFile, shape)
+	if err != nil {
+		return err
 	}
 
-	return output, nil
+	return outputFile.Close()
+}
+
+// renderSCAD writes the OpenSCAD code of shape, including the globals.
+func renderSCAD(writer io.Writer, shape primitive.Primitive) error {
+	bufferedOutput := bufio.NewWriter(writer)
+
+	ghostscad.RenderGlobals(bufferedOutput)
+	shape.Render(bufferedOutput)
+
+	return bufferedOutput.Flush()
 }
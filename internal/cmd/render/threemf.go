@@ -0,0 +1,81 @@
+package render
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/go-gl/mathgl/mgl64"
+
+	"github.com/yeldiRium/3d-rack-brackets/internal/cmd/output"
+	"github.com/yeldiRium/3d-rack-brackets/internal/mesh"
+	"github.com/yeldiRium/3d-rack-brackets/internal/shapes/rack"
+)
+
+// plateSpacing is the gap between the parts that are laid out next to each
+// other in 3MF packages, in mm.
+const plateSpacing = 5
+
+// write3MF writes the parts to path as a 3MF package, see output.Open. Every
+// part is meshed in its own frame and placed on the build plate in its print
+// orientation, next to the previous part.
+func (render *RenderCmd) write3MF(path string, stdout io.Writer, printParts []rack.PrintPart) error {
+	objects := make([]mesh.Object, 0, len(printParts))
+	nextX := 0.0
+	for _, printPart := range printParts {
+		orientation := printPart.Orientation
+		printPart.Orientation = mgl64.Ident4()
+
+		shape, err := printPart.Primitive()
+		if err != nil {
+			return err
+		}
+		partMesh, err := mesh.FromPrimitive(shape)
+		if err != nil {
+			return fmt.Errorf("failed to mesh %s: %w", printPart.Name, err)
+		}
+
+		bounds := partMesh.Transformed(orientation).Bounds()
+		placement := mgl64.Translate3D(nextX-bounds.Min.X(), -bounds.Min.Y(), -bounds.Min.Z()).Mul4(orientation)
+		nextX += bounds.Size().X() + plateSpacing
+
+		objects = append(objects, mesh.Object{
+			Name:      printPart.Name,
+			Mesh:      partMesh,
+			Transform: placement,
+			Metadata:  render.partMetadata(printPart),
+		})
+	}
+
+	outputFile, err := output.Open(path, stdout)
+	if err != nil {
+		return fmt.Errorf("failed to open output stream: %w", err)
+	}
+	defer func() { _ = outputFile.Close() }()
+
+	err = mesh.Write3MF(outputFile, objects, map[string]string{
+		"Title":       assembledName,
+		"Application": "3d-rack-brackets",
+	})
+	if err != nil {
+		return err
+	}
+
+	return outputFile.Close()
+}
+
+// partMetadata returns the metadata of a part in 3MF packages: the kind of the
+// part and the recommendations for printing it.
+func (render *RenderCmd) partMetadata(printPart rack.PrintPart) map[string]string {
+	metadata := map[string]string{
+		"rack:kind": string(printPart.Kind),
+	}
+	if render.Material != "" {
+		metadata["rack:material"] = render.Material
+	}
+	if render.Infill != nil {
+		metadata["rack:infill"] = strconv.FormatFloat(*render.Infill, 'f', -1, 64) + "%"
+	}
+
+	return metadata
+}
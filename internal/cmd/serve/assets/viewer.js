@@ -0,0 +1,277 @@
+// A small WebGL viewer for the rack's STL mesh. It is written without any
+// libraries, so that the preview works offline and nothing has to be bundled.
+'use strict';
+
+const form = document.getElementById('parameters');
+const statusLine = document.getElementById('status');
+const canvas = document.getElementById('viewer');
+const gl = canvas.getContext('webgl', { antialias: true });
+
+const vertexShaderSource = `
+attribute vec3 position;
+attribute vec3 normal;
+uniform mat4 projection;
+uniform mat4 view;
+varying vec3 worldNormal;
+
+void main() {
+  worldNormal = normal;
+  gl_Position = projection * view * vec4(position, 1.0);
+}
+`;
+
+const fragmentShaderSource = `
+precision mediump float;
+varying vec3 worldNormal;
+
+void main() {
+  vec3 normal = normalize(worldNormal);
+  float key = max(dot(normal, normalize(vec3(0.4, -0.6, 0.7))), 0.0);
+  float fill = max(dot(normal, normalize(vec3(-0.5, 0.7, 0.2))), 0.0);
+  vec3 color = vec3(0.95, 0.62, 0.2) * (0.25 + 0.65 * key + 0.25 * fill);
+  gl_FragColor = vec4(color, 1.0);
+}
+`;
+
+function compileShader(type, source) {
+  const shader = gl.createShader(type);
+  gl.shaderSource(shader, source);
+  gl.compileShader(shader);
+  if (!gl.getShaderParameter(shader, gl.COMPILE_STATUS)) {
+    throw new Error(gl.getShaderInfoLog(shader));
+  }
+
+  return shader;
+}
+
+function createProgram() {
+  const program = gl.createProgram();
+  gl.attachShader(program, compileShader(gl.VERTEX_SHADER, vertexShaderSource));
+  gl.attachShader(program, compileShader(gl.FRAGMENT_SHADER, fragmentShaderSource));
+  gl.linkProgram(program);
+  if (!gl.getProgramParameter(program, gl.LINK_STATUS)) {
+    throw new Error(gl.getProgramInfoLog(program));
+  }
+
+  return program;
+}
+
+// parseSTL reads a binary STL file into flat arrays of vertex positions and
+// normals, and the bounding box of the mesh.
+function parseSTL(buffer) {
+  const data = new DataView(buffer);
+  const count = data.getUint32(80, true);
+  const positions = new Float32Array(count * 9);
+  const normals = new Float32Array(count * 9);
+  const lower = [Infinity, Infinity, Infinity];
+  const upper = [-Infinity, -Infinity, -Infinity];
+
+  for (let triangle = 0; triangle < count; triangle++) {
+    const offset = 84 + triangle * 50;
+    for (let vertex = 0; vertex < 3; vertex++) {
+      for (let axis = 0; axis < 3; axis++) {
+        const index = triangle * 9 + vertex * 3 + axis;
+        const value = data.getFloat32(offset + 12 + vertex * 12 + axis * 4, true);
+        positions[index] = value;
+        normals[index] = data.getFloat32(offset + axis * 4, true);
+        lower[axis] = Math.min(lower[axis], value);
+        upper[axis] = Math.max(upper[axis], value);
+      }
+    }
+  }
+
+  return { positions, normals, count: count * 3, lower, upper };
+}
+
+function perspective(fieldOfView, aspect, near, far) {
+  const f = 1 / Math.tan(fieldOfView / 2);
+  const range = 1 / (near - far);
+
+  return new Float32Array([
+    f / aspect, 0, 0, 0,
+    0, f, 0, 0,
+    0, 0, (near + far) * range, -1,
+    0, 0, 2 * near * far * range, 0,
+  ]);
+}
+
+function subtract(a, b) {
+  return [a[0] - b[0], a[1] - b[1], a[2] - b[2]];
+}
+
+function cross(a, b) {
+  return [a[1] * b[2] - a[2] * b[1], a[2] * b[0] - a[0] * b[2], a[0] * b[1] - a[1] * b[0]];
+}
+
+function dot(a, b) {
+  return a[0] * b[0] + a[1] * b[1] + a[2] * b[2];
+}
+
+function normalize(a) {
+  const length = Math.hypot(a[0], a[1], a[2]);
+
+  return [a[0] / length, a[1] / length, a[2] / length];
+}
+
+function lookAt(eye, center, up) {
+  const z = normalize(subtract(eye, center));
+  const x = normalize(cross(up, z));
+  const y = cross(z, x);
+
+  return new Float32Array([
+    x[0], y[0], z[0], 0,
+    x[1], y[1], z[1], 0,
+    x[2], y[2], z[2], 0,
+    -dot(x, eye), -dot(y, eye), -dot(z, eye), 1,
+  ]);
+}
+
+const program = createProgram();
+const attributes = {
+  position: gl.getAttribLocation(program, 'position'),
+  normal: gl.getAttribLocation(program, 'normal'),
+};
+const uniforms = {
+  projection: gl.getUniformLocation(program, 'projection'),
+  view: gl.getUniformLocation(program, 'view'),
+};
+const buffers = {
+  position: gl.createBuffer(),
+  normal: gl.createBuffer(),
+};
+
+// The camera orbits the center of the rack. The rack's z axis points up.
+const camera = { azimuth: -Math.PI / 3, elevation: Math.PI / 8, distance: 0, center: [0, 0, 0] };
+let model = null;
+
+function draw() {
+  const width = canvas.clientWidth * window.devicePixelRatio;
+  const height = canvas.clientHeight * window.devicePixelRatio;
+  if (canvas.width !== width || canvas.height !== height) {
+    canvas.width = width;
+    canvas.height = height;
+  }
+
+  gl.viewport(0, 0, canvas.width, canvas.height);
+  gl.clearColor(0.125, 0.141, 0.165, 1);
+  gl.clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT);
+  if (model === null) {
+    return;
+  }
+
+  const eye = [
+    camera.center[0] + camera.distance * Math.cos(camera.elevation) * Math.cos(camera.azimuth),
+    camera.center[1] + camera.distance * Math.cos(camera.elevation) * Math.sin(camera.azimuth),
+    camera.center[2] + camera.distance * Math.sin(camera.elevation),
+  ];
+
+  gl.enable(gl.DEPTH_TEST);
+  gl.useProgram(program);
+  gl.uniformMatrix4fv(uniforms.projection, false, perspective(Math.PI / 4, canvas.width / canvas.height, camera.distance / 100, camera.distance * 10));
+  gl.uniformMatrix4fv(uniforms.view, false, lookAt(eye, camera.center, [0, 0, 1]));
+
+  gl.bindBuffer(gl.ARRAY_BUFFER, buffers.position);
+  gl.enableVertexAttribArray(attributes.position);
+  gl.vertexAttribPointer(attributes.position, 3, gl.FLOAT, false, 0, 0);
+  gl.bindBuffer(gl.ARRAY_BUFFER, buffers.normal);
+  gl.enableVertexAttribArray(attributes.normal);
+  gl.vertexAttribPointer(attributes.normal, 3, gl.FLOAT, false, 0, 0);
+
+  gl.drawArrays(gl.TRIANGLES, 0, model.count);
+}
+
+function show(mesh) {
+  gl.bindBuffer(gl.ARRAY_BUFFER, buffers.position);
+  gl.bufferData(gl.ARRAY_BUFFER, mesh.positions, gl.STATIC_DRAW);
+  gl.bindBuffer(gl.ARRAY_BUFFER, buffers.normal);
+  gl.bufferData(gl.ARRAY_BUFFER, mesh.normals, gl.STATIC_DRAW);
+
+  // The camera is only fitted to the first rack, so that it stays put while
+  // the parameters are tweaked.
+  if (model === null) {
+    const size = subtract(mesh.upper, mesh.lower);
+    camera.center = mesh.lower.map((lower, axis) => lower + size[axis] / 2);
+    camera.distance = 1.5 * Math.hypot(size[0], size[1], size[2]);
+  }
+  model = mesh;
+  draw();
+}
+
+let dragging = null;
+canvas.addEventListener('pointerdown', (event) => {
+  dragging = { x: event.clientX, y: event.clientY };
+  canvas.setPointerCapture(event.pointerId);
+});
+canvas.addEventListener('pointerup', () => {
+  dragging = null;
+});
+canvas.addEventListener('pointermove', (event) => {
+  if (dragging === null) {
+    return;
+  }
+  camera.azimuth -= (event.clientX - dragging.x) * 0.01;
+  camera.elevation = Math.max(-1.5, Math.min(1.5, camera.elevation + (event.clientY - dragging.y) * 0.01));
+  dragging = { x: event.clientX, y: event.clientY };
+  draw();
+});
+canvas.addEventListener('wheel', (event) => {
+  event.preventDefault();
+  camera.distance *= Math.exp(event.deltaY * 0.001);
+  draw();
+}, { passive: false });
+window.addEventListener('resize', draw);
+
+function setStatus(message, isError) {
+  statusLine.textContent = message;
+  statusLine.classList.toggle('error', isError);
+}
+
+let pending = null;
+async function reload() {
+  if (pending !== null) {
+    pending.abort();
+  }
+  pending = new AbortController();
+
+  setStatus('Rendering…', false);
+  const startTime = performance.now();
+  try {
+    const response = await fetch('rack.stl?' + new URLSearchParams(new FormData(form)), { signal: pending.signal });
+    if (!response.ok) {
+      setStatus(await response.text(), true);
+
+      return;
+    }
+    show(parseSTL(await response.arrayBuffer()));
+    setStatus(`Rendered in ${Math.round(performance.now() - startTime)} ms.`, false);
+  } catch (error) {
+    if (error.name !== 'AbortError') {
+      setStatus(error.message, true);
+    }
+  }
+}
+
+let reloadTimer = null;
+form.addEventListener('input', () => {
+  clearTimeout(reloadTimer);
+  reloadTimer = setTimeout(reload, 300);
+});
+form.addEventListener('submit', (event) => {
+  event.preventDefault();
+});
+
+async function start() {
+  const response = await fetch('parameters');
+  const parameters = await response.json();
+
+  for (const holeType of parameters.holeTypes) {
+    form.elements['hole-type'].add(new Option(holeType, holeType));
+  }
+  form.elements.units.value = parameters.units;
+  form.elements['foot-length'].value = parameters.footLength;
+  form.elements['hole-type'].value = parameters.holeType;
+
+  await reload();
+}
+
+start();
@@ -0,0 +1,174 @@
+package serve
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/yeldiRium/3d-rack-brackets/internal/cmd/globals"
+	"github.com/yeldiRium/3d-rack-brackets/internal/cmd/render"
+	"github.com/yeldiRium/3d-rack-brackets/internal/mesh"
+	"github.com/yeldiRium/3d-rack-brackets/internal/shapes/holes"
+)
+
+//go:embed assets
+var assets embed.FS
+
+type ServeCmd struct {
+	Listen string `default:"localhost:8080" help:"Address to serve the preview on."`
+
+	Print render.PrintFlags `embed:""`
+}
+
+// shutdownTimeout is how long requests may take to finish when the server is
+// stopped.
+const shutdownTimeout = 5 * time.Second
+
+// Run serves the preview until the program is interrupted.
+func (serve *ServeCmd) Run(globals *globals.Globals) error {
+	listener, err := net.Listen("tcp", serve.Listen)
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+
+	server := &http.Server{
+		Handler:           serve.handler(globals.Logger),
+		ReadHeaderTimeout: shutdownTimeout,
+	}
+	go func() {
+		<-globals.Context.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		_ = server.Shutdown(ctx)
+	}()
+
+	globals.Logger.Info("serving the preview", slog.String("url", "http://"+listener.Addr().String()))
+	err = server.Serve(listener)
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+
+	return err
+}
+
+// parameters are the rack parameters that the preview's form controls.
+type parameters struct {
+	Units      uint8    `json:"units"`
+	FootLength float64  `json:"footLength"`
+	HoleType   string   `json:"holeType"`
+	HoleTypes  []string `json:"holeTypes"`
+}
+
+func (serve *ServeCmd) handler(logger *slog.Logger) http.Handler {
+	static, err := fs.Sub(assets, "assets")
+	if err != nil {
+		panic("the assets are embedded. this should not happen")
+	}
+
+	// Rendering sets the fragment settings of the ghostscad package, so only
+	// one rack is rendered at a time.
+	var renderLock sync.Mutex
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /", http.FileServerFS(static))
+	mux.HandleFunc("GET /parameters", func(writer http.ResponseWriter, _ *http.Request) {
+		holeTypes := make([]string, 0, len(holes.Types))
+		for _, holeType := range holes.Types {
+			holeTypes = append(holeTypes, string(holeType))
+		}
+
+		writer.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(writer).Encode(parameters{
+			Units:      serve.Print.Rack.Units,
+			FootLength: serve.Print.Rack.FootLength,
+			HoleType:   serve.Print.Rack.HoleType,
+			HoleTypes:  holeTypes,
+		})
+	})
+	mux.HandleFunc("GET /rack.stl", func(writer http.ResponseWriter, request *http.Request) {
+		renderCmd, err := serve.renderCmd(request)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		startTime := time.Now()
+		renderLock.Lock()
+		stl, err := renderSTL(renderCmd)
+		renderLock.Unlock()
+		if err != nil {
+			logger.Error("failed to render", slog.Any("error", err))
+			http.Error(writer, err.Error(), http.StatusUnprocessableEntity)
+
+			return
+		}
+		logger.Info("rendered", slog.String("query", request.URL.RawQuery), slog.Duration("elapsed", time.Since(startTime)))
+
+		writer.Header().Set("Content-Type", "model/stl")
+		_, _ = writer.Write(stl)
+	})
+
+	return mux
+}
+
+// renderCmd returns the render command for the flags, overridden by the
+// parameters of the request.
+func (serve *ServeCmd) renderCmd(request *http.Request) (*render.RenderCmd, error) {
+	renderCmd := serve.Print.RenderCmd()
+
+	query := request.URL.Query()
+	if value := query.Get("units"); value != "" {
+		units, err := strconv.ParseUint(value, 10, 8)
+		if err != nil {
+			return nil, fmt.Errorf("units must be a number between 1 and 255, got %q", value)
+		}
+		renderCmd.Rack.Units = uint8(units)
+	}
+	if value := query.Get("foot-length"); value != "" {
+		footLength, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("foot length must be a number, got %q", value)
+		}
+		renderCmd.Rack.FootLength = footLength
+	}
+	if value := query.Get("hole-type"); value != "" {
+		if !slices.Contains(holes.Types, holes.Type(value)) {
+			return nil, fmt.Errorf("unknown hole type %q", value)
+		}
+		renderCmd.Rack.HoleType = value
+	}
+
+	return renderCmd, nil
+}
+
+// renderSTL assembles the rack and meshes it into a binary STL file.
+func renderSTL(renderCmd *render.RenderCmd) ([]byte, error) {
+	assembly, err := renderCmd.Assemble()
+	if err != nil {
+		return nil, err
+	}
+
+	rackMesh, err := mesh.FromPrimitive(assembly.Shape)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mesh the rack: %w", err)
+	}
+
+	var stl bytes.Buffer
+	if err := rackMesh.WriteSTL(&stl, "rack"); err != nil {
+		return nil, err
+	}
+
+	return stl.Bytes(), nil
+}
@@ -0,0 +1,17 @@
+//go:build !windows
+
+package watch
+
+import (
+	"os"
+	"syscall"
+)
+
+// executableSuffix is the file name suffix of executables.
+const executableSuffix = ""
+
+// replaceProcess replaces the running process with binary, which gets the
+// arguments and environment of this program. It only returns if that fails.
+func replaceProcess(binary string) error {
+	return syscall.Exec(binary, os.Args, os.Environ()) //nolint:gosec // restarts this program with its own arguments
+}
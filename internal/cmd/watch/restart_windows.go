@@ -0,0 +1,33 @@
+//go:build windows
+
+package watch
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+// executableSuffix is the file name suffix of executables.
+const executableSuffix = ".exe"
+
+// replaceProcess runs binary with the arguments of this program and exits
+// with its exit code once it ends, since Windows cannot replace a running
+// process. It only returns if binary cannot be started.
+func replaceProcess(binary string) error {
+	command := exec.Command(binary, os.Args[1:]...) //nolint:gosec // restarts this program with its own arguments
+	command.Stdin = os.Stdin
+	command.Stdout = os.Stdout
+	command.Stderr = os.Stderr
+	if err := command.Start(); err != nil {
+		return err
+	}
+
+	var exitError *exec.ExitError
+	if err := command.Wait(); errors.As(err, &exitError) {
+		os.Exit(exitError.ExitCode())
+	}
+	os.Exit(0)
+
+	return nil
+}
@@ -0,0 +1,205 @@
+package watch
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/kong"
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/yeldiRium/3d-rack-brackets/internal/cmd/globals"
+	"github.com/yeldiRium/3d-rack-brackets/internal/cmd/output"
+	"github.com/yeldiRium/3d-rack-brackets/internal/cmd/render"
+)
+
+type WatchCmd struct {
+	Debounce time.Duration `default:"200ms" group:"watch" help:"Time to wait for further changes before rendering again."`
+	Source   string        `group:"watch" help:"Go module of this program. Changes to its Go files rebuild the program and restart watching with the same flags." placeholder:"DIR" type:"existingdir"`
+
+	Render render.RenderCmd `embed:""`
+}
+
+func (watch *WatchCmd) Validate() error {
+	if err := watch.Render.Validate(); err != nil {
+		return err
+	}
+	if watch.Render.Config == "" && watch.Source == "" {
+		return errors.New("nothing to watch, give a design file with --config or the program's sources with --source")
+	}
+	if watch.Render.Output == output.Stdout && !watch.Render.SplitParts {
+		return errors.New("watch renders the rack on every change, so it needs an output file")
+	}
+
+	return nil
+}
+
+// Run renders the rack and renders it again whenever the design file changes,
+// until it is interrupted. Failures are logged and do not stop watching, so
+// that a half-edited design does not end the session.
+func (watch *WatchCmd) Run(globals *globals.Globals, kongContext *kong.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to start watching: %w", err)
+	}
+	defer func() { _ = watcher.Close() }()
+
+	if watch.Render.Config != "" {
+		// Editors often replace files instead of writing them, so the directory
+		// is watched rather than the file.
+		if err := watcher.Add(filepath.Dir(string(watch.Render.Config))); err != nil {
+			return fmt.Errorf("failed to watch the design file: %w", err)
+		}
+	}
+	if watch.Source != "" {
+		if err := addDirectories(watcher, watch.Source); err != nil {
+			return fmt.Errorf("failed to watch the sources: %w", err)
+		}
+	}
+
+	globals.Logger.Info("watching for changes", slog.String("config", string(watch.Render.Config)), slog.String("source", watch.Source))
+	watch.render(globals, &watch.Render)
+
+	var debounce <-chan time.Time
+	sourceChanged := false
+	for {
+		select {
+		case <-globals.Context.Done():
+			return nil
+		case err := <-watcher.Errors:
+			globals.Logger.Error("failed to watch for changes", slog.Any("error", err))
+		case event := <-watcher.Events:
+			if event.Has(fsnotify.Create) {
+				watch.addCreatedDirectory(watcher, event.Name, globals.Logger)
+			}
+			switch {
+			case watch.isSource(event.Name):
+				sourceChanged = true
+			case !watch.isConfig(event.Name):
+				continue
+			}
+			globals.Logger.Debug("change detected", slog.String("path", event.Name), slog.String("operation", event.Op.String()))
+			debounce = time.After(watch.Debounce)
+		case <-debounce:
+			debounce = nil
+			if sourceChanged {
+				sourceChanged = false
+				err := watch.restart(globals)
+				globals.Logger.Error("failed to rebuild", slog.Any("error", err))
+
+				continue
+			}
+
+			renderCmd, err := reload(kongContext)
+			if err != nil {
+				globals.Logger.Error("failed to load the design", slog.Any("error", err))
+
+				continue
+			}
+			watch.render(globals, renderCmd)
+		}
+	}
+}
+
+// render renders the rack once and logs how long it took.
+func (watch *WatchCmd) render(globals *globals.Globals, renderCmd *render.RenderCmd) {
+	startTime := time.Now()
+	if err := renderCmd.Run(globals); err != nil {
+		globals.Logger.Error("failed to render", slog.Any("error", err), slog.Duration("elapsed", time.Since(startTime)))
+
+		return
+	}
+	globals.Logger.Info("rendered", slog.String("output", renderCmd.Output), slog.Duration("elapsed", time.Since(startTime)))
+}
+
+func (watch *WatchCmd) isConfig(path string) bool {
+	return watch.Render.Config != "" && filepath.Clean(path) == filepath.Clean(string(watch.Render.Config))
+}
+
+func (watch *WatchCmd) isSource(path string) bool {
+	return watch.Source != "" && strings.HasSuffix(path, ".go") && !strings.HasSuffix(path, "_test.go")
+}
+
+// reload parses the command line again, so that the design file is loaded
+// again and layered below the flags like before.
+func reload(kongContext *kong.Context) (*render.RenderCmd, error) {
+	model := reflect.New(kongContext.Model.Target.Type())
+	parser, err := kong.New(model.Interface(), render.DefaultVars())
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := parser.Parse(kongContext.Args)
+	if err != nil {
+		return nil, err
+	}
+
+	watch, ok := parsed.Selected().Target.Addr().Interface().(*WatchCmd)
+	if !ok {
+		panic("reloaded a different command than watch. this should not happen")
+	}
+
+	return &watch.Render, nil
+}
+
+// restart builds the program from the sources and runs it in place of the
+// running process, see replaceProcess. It only returns if that fails.
+func (watch *WatchCmd) restart(globals *globals.Globals) error {
+	binary := filepath.Join(os.TempDir(), fmt.Sprintf("3d-rack-brackets-watch-%d%s", os.Getpid(), executableSuffix))
+
+	globals.Logger.Info("rebuilding", slog.String("source", watch.Source))
+	build := exec.CommandContext(globals.Context, "go", "build", "-o", binary, ".")
+	build.Dir = watch.Source
+	if output, err := build.CombinedOutput(); err != nil {
+		return fmt.Errorf("%w\n%s", err, output)
+	}
+
+	if globals.Cleanup != nil {
+		globals.Cleanup()
+	}
+
+	return replaceProcess(binary)
+}
+
+// addCreatedDirectory watches path and every directory below it if it is a
+// directory that was created in the sources, since watching root does not
+// cover directories created later on.
+func (watch *WatchCmd) addCreatedDirectory(watcher *fsnotify.Watcher, path string, logger *slog.Logger) {
+	if watch.Source == "" || strings.HasPrefix(filepath.Base(path), ".") {
+		return
+	}
+	relative, err := filepath.Rel(watch.Source, path)
+	if err != nil || strings.HasPrefix(relative, "..") {
+		return
+	}
+	info, err := os.Stat(path)
+	if err != nil || !info.IsDir() {
+		return
+	}
+
+	if err := addDirectories(watcher, path); err != nil {
+		logger.Error("failed to watch the sources", slog.String("path", path), slog.Any("error", err))
+	}
+}
+
+// addDirectories watches root and every directory below it, except hidden
+// ones.
+func addDirectories(watcher *fsnotify.Watcher, root string) error {
+	return filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || !entry.IsDir() {
+			return err
+		}
+		if path != root && strings.HasPrefix(entry.Name(), ".") {
+			return filepath.SkipDir
+		}
+
+		return watcher.Add(path)
+	})
+}
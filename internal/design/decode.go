@@ -0,0 +1,122 @@
+package design
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/pelletier/go-toml/v2/unstable"
+	"gopkg.in/yaml.v3"
+)
+
+// decodeYAML decodes YAML documents. Since JSON is a subset of YAML, it is used
+// for JSON documents as well, which gives us positions for JSON for free.
+func decodeYAML(file string, data []byte) (map[string]any, map[string]Position, error) {
+	values := map[string]any{}
+	positions := map[string]Position{}
+
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", file, err)
+	}
+	if len(document.Content) == 0 {
+		return values, positions, nil
+	}
+
+	root := document.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, nil, &SchemaError{
+			File:     file,
+			Position: Position{Line: root.Line, Column: root.Column},
+			Message:  "design must be a mapping",
+		}
+	}
+	if err := root.Decode(&values); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", file, err)
+	}
+
+	var walk func(prefix string, node *yaml.Node)
+	walk = func(prefix string, node *yaml.Node) {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			path := joinPath(prefix, key.Value)
+			positions[path] = Position{Line: key.Line, Column: key.Column}
+			if value.Kind == yaml.MappingNode {
+				walk(path, value)
+			}
+		}
+	}
+	walk("", root)
+
+	return values, positions, nil
+}
+
+func decodeTOML(file string, data []byte) (map[string]any, map[string]Position, error) {
+	values := map[string]any{}
+	positions := map[string]Position{}
+
+	if err := toml.Unmarshal(data, &values); err != nil {
+		var decodeError *toml.DecodeError
+		if errors.As(err, &decodeError) {
+			line, column := decodeError.Position()
+
+			return nil, nil, fmt.Errorf("%s:%d:%d: %w", file, line, column, err)
+		}
+
+		return nil, nil, fmt.Errorf("%s: %w", file, err)
+	}
+
+	var parser unstable.Parser
+	parser.Reset(data)
+
+	record := func(prefix string, keys unstable.Iterator) string {
+		path := prefix
+		for keys.Next() {
+			key := keys.Node()
+			path = joinPath(path, string(key.Data))
+			if _, ok := positions[path]; !ok {
+				start := parser.Shape(key.Raw).Start
+				positions[path] = Position{Line: start.Line, Column: start.Column}
+			}
+		}
+
+		return path
+	}
+
+	var recordKeyValue func(prefix string, keyValue *unstable.Node)
+	recordKeyValue = func(prefix string, keyValue *unstable.Node) {
+		path := record(prefix, keyValue.Key())
+		if value := keyValue.Value(); value.Kind == unstable.InlineTable {
+			children := value.Children()
+			for children.Next() {
+				recordKeyValue(path, children.Node())
+			}
+		}
+	}
+
+	table := ""
+	for parser.NextExpression() {
+		expression := parser.Expression()
+		switch expression.Kind {
+		case unstable.Table, unstable.ArrayTable:
+			table = record("", expression.Key())
+		case unstable.KeyValue:
+			recordKeyValue(table, expression)
+		default:
+		}
+	}
+	if err := parser.Error(); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", file, err)
+	}
+
+	return values, positions, nil
+}
+
+func joinPath(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+
+	return strings.Join([]string{prefix, key}, ".")
+}
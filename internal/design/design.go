@@ -0,0 +1,150 @@
+// Package design loads rack designs from YAML, JSON or TOML files.
+//
+// A design file describes a rack variant, e.g.:
+//
+//	units: 6
+//	screw:
+//	  standard: m5
+//	dimensions:
+//	  foot:
+//	    length: 200
+//	quality:
+//	  production: true
+//	parts: [segments, braces]
+//
+// Every value in a design file corresponds to a flag of the render command. A
+// loaded Design is a kong.Resolver, so flags given on the command line take
+// precedence over the design, which in turn takes precedence over the flags'
+// defaults.
+package design
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/alecthomas/kong"
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported design file format")
+)
+
+// Position is a location in a design file.
+type Position struct {
+	Line   int
+	Column int
+}
+
+func (position Position) before(other Position) bool {
+	if position.Line != other.Line {
+		return position.Line < other.Line
+	}
+
+	return position.Column < other.Column
+}
+
+// SchemaError describes a value in a design file that violates the schema.
+type SchemaError struct {
+	File     string
+	Path     string
+	Position Position
+	Message  string
+}
+
+func (err *SchemaError) Error() string {
+	if err.Path == "" {
+		return fmt.Sprintf("%s:%d:%d: %s", err.File, err.Position.Line, err.Position.Column, err.Message)
+	}
+
+	return fmt.Sprintf("%s:%d:%d: %s: %s", err.File, err.Position.Line, err.Position.Column, err.Path, err.Message)
+}
+
+// Design is a validated design file. Its values are stored the way they would
+// be given on the command line, keyed by flag name.
+type Design struct {
+	File  string
+	flags map[string]string
+}
+
+// Load reads and validates the design file at path. The format is chosen by
+// the file extension.
+func Load(path string) (*Design, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read design file: %w", err)
+	}
+
+	return Parse(path, data)
+}
+
+// Parse validates a design. The format is chosen by the extension of file.
+func Parse(file string, data []byte) (*Design, error) {
+	var values map[string]any
+	var positions map[string]Position
+	var err error
+
+	switch filepath.Ext(file) {
+	case ".yaml", ".yml", ".json":
+		values, positions, err = decodeYAML(file, data)
+	case ".toml":
+		values, positions, err = decodeTOML(file, data)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, file)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	flags, schemaErrors := validate(file, values, positions)
+	if len(schemaErrors) > 0 {
+		errs := make([]error, 0, len(schemaErrors))
+		for _, schemaError := range schemaErrors {
+			errs = append(errs, schemaError)
+		}
+
+		return nil, errors.Join(errs...)
+	}
+
+	return &Design{
+		File:  file,
+		flags: flags,
+	}, nil
+}
+
+// Validate implements kong.Resolver. The design was already validated against
+// the schema when it was loaded.
+func (design *Design) Validate(_ *kong.Application) error {
+	return nil
+}
+
+// Resolve implements kong.Resolver.
+func (design *Design) Resolve(_ *kong.Context, _ *kong.Path, flag *kong.Flag) (any, error) {
+	value, ok := design.flags[flag.Name]
+	if !ok {
+		return nil, nil //nolint:nilnil // kong expects nil for unresolved flags
+	}
+
+	return value, nil
+}
+
+// Flag is a flag that loads a design file and layers it below the flags given
+// on the command line.
+type Flag string
+
+// BeforeResolve adds the design as a resolver.
+func (flag Flag) BeforeResolve(ctx *kong.Context, trace *kong.Path) error {
+	path, ok := ctx.FlagValue(trace.Flag).(Flag)
+	if !ok {
+		panic("design flag has unexpected type. this should not happen")
+	}
+
+	design, err := Load(string(path))
+	if err != nil {
+		return err
+	}
+	ctx.AddResolver(design)
+
+	return nil
+}
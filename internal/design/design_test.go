@@ -0,0 +1,156 @@
+package design
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alecthomas/kong"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	t.Run("maps the values of a YAML design to flags.", func(t *testing.T) {
+		t.Parallel()
+
+		design, err := Parse("studio.yaml", []byte(`
+units: 6
+screw:
+  standard: m5
+dimensions:
+  foot:
+    length: 200.5
+quality:
+  production: true
+parts: [segments, foot]
+`))
+		require.NoError(t, err)
+
+		assert.Equal(t, map[string]string{
+			"units":       "6",
+			"screw":       "m5",
+			"foot-length": "200.5",
+			"production":  "true",
+			"parts":       "segments,foot",
+		}, design.flags)
+	})
+
+	t.Run("maps the values of a JSON design to flags.", func(t *testing.T) {
+		t.Parallel()
+
+		design, err := Parse("studio.json", []byte(`{"units": 2, "dimensions": {"spine": {"width": 12}}, "holes": {"pitches": [20, 24.45]}}`))
+		require.NoError(t, err)
+
+		assert.Equal(t, map[string]string{
+			"units":        "2",
+			"spine-width":  "12",
+			"hole-pitches": "20,24.45",
+		}, design.flags)
+	})
+
+	t.Run("maps the values of a TOML design to flags.", func(t *testing.T) {
+		t.Parallel()
+
+		design, err := Parse("studio.toml", []byte(`
+units = 4
+quality = { fn = 32 }
+side-braces = "right"
+
+[dimensions.side-brace]
+width = 4
+`))
+		require.NoError(t, err)
+
+		assert.Equal(t, map[string]string{
+			"units":            "4",
+			"fn":               "32",
+			"side-brace-sides": "right",
+			"side-brace-width": "4",
+		}, design.flags)
+	})
+
+	t.Run("reports every schema violation with its position, in order.", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := Parse("studio.yaml", []byte(`
+units: 0
+dimensions:
+  foot:
+    length: long
+    color: red
+holes:
+  pitches: [30, -14.45]
+parts: [segments, wheels]
+`))
+
+		require.EqualError(t, err, `studio.yaml:2:1: units: must be at least 1, got 0
+studio.yaml:5:5: dimensions.foot.length: must be a number, got long
+studio.yaml:6:5: dimensions.foot.color: unknown key
+studio.yaml:8:3: holes.pitches: must be greater than 0, got -14.45
+studio.yaml:9:1: parts: must be one of segments, braces, foot, got "wheels"`)
+	})
+
+	t.Run("reports positions of keys in TOML inline tables.", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := Parse("studio.toml", []byte(`units = 3
+dimensions = { foot = { length = -1 } }
+`))
+
+		var schemaError *SchemaError
+		require.ErrorAs(t, err, &schemaError)
+		assert.Equal(t, "dimensions.foot.length", schemaError.Path)
+		assert.Equal(t, Position{Line: 2, Column: 25}, schemaError.Position)
+	})
+
+	t.Run("rejects tables where values are expected and vice versa.", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := Parse("studio.yaml", []byte(`
+units:
+  count: 3
+dimensions: 5
+`))
+
+		require.EqualError(t, err, `studio.yaml:2:1: units: must be an integer, got map[count:3]
+studio.yaml:4:1: dimensions: must be a table`)
+	})
+
+	t.Run("rejects unknown file formats.", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := Parse("studio.ini", []byte(``))
+		require.ErrorIs(t, err, ErrUnsupportedFormat)
+	})
+}
+
+func TestFlag(t *testing.T) {
+	t.Parallel()
+
+	t.Run("layers flags over the design over the defaults.", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "studio.yaml")
+		err := os.WriteFile(path, []byte("units: 6\ndimensions: {foot: {length: 200}}\n"), 0o600)
+		require.NoError(t, err)
+
+		var cli struct {
+			Config     Flag    `type:"existingfile"`
+			Units      uint8   `default:"3"`
+			FootLength float64 `default:"170"`
+			SpineWidth float64 `default:"15"`
+		}
+		parser, err := kong.New(&cli)
+		require.NoError(t, err)
+
+		_, err = parser.Parse([]string{"--config", path, "--units", "8"})
+		require.NoError(t, err)
+
+		assert.Equal(t, uint8(8), cli.Units)
+		assert.InDelta(t, 200.0, cli.FootLength, 0)
+		assert.InDelta(t, 15.0, cli.SpineWidth, 0)
+	})
+}
@@ -0,0 +1,366 @@
+package design
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/yeldiRium/3d-rack-brackets/internal/shapes/holes"
+	"github.com/yeldiRium/3d-rack-brackets/internal/shapes/rack"
+)
+
+type kind int
+
+const (
+	kindBool kind = iota
+	kindInteger
+	kindNumber
+	kindString
+	kindStringList
+	kindNumberList
+)
+
+func (k kind) String() string {
+	switch k {
+	case kindBool:
+		return "a boolean"
+	case kindInteger:
+		return "an integer"
+	case kindNumber:
+		return "a number"
+	case kindString:
+		return "a string"
+	case kindStringList:
+		return "a list of strings"
+	case kindNumberList:
+		return "a list of numbers"
+	default:
+		return "unknown"
+	}
+}
+
+// field describes a single value of a design file and the render flag it is
+// resolved for.
+type field struct {
+	// path is the dotted path of the value in the design file.
+	path string
+
+	// flag is the name of the flag this value provides a default for.
+	flag string
+
+	kind kind
+
+	// min and max bound numbers and integers, inclusive.
+	min, max float64
+
+	// exclusiveMin makes min an exclusive bound, e.g. for lengths that must be
+	// strictly positive.
+	exclusiveMin bool
+
+	// enum restricts strings and the elements of string lists.
+	enum []string
+}
+
+func length(path, flag string) field {
+	return field{path: path, flag: flag, kind: kindNumber, min: 0, max: math.Inf(1), exclusiveMin: true}
+}
+
+func offset(path, flag string) field {
+	return field{path: path, flag: flag, kind: kindNumber, min: 0, max: math.Inf(1)}
+}
+
+// schema lists every value a design file may contain.
+var schema = []field{
+	{path: "units", flag: "units", kind: kindInteger, min: 1, max: math.MaxUint8},
+
+	{path: "screw.standard", flag: "screw", kind: kindString, enum: screwStandards()},
+	length("screw.radius", "screw-radius"),
+
+	{path: "holes.type", flag: "hole-type", kind: kindString, enum: holeTypes()},
+	{path: "holes.insert", flag: "insert", kind: kindString, enum: holes.InsertNames()},
+	{path: "holes.standard", flag: "hole-standard", kind: kindString, enum: holeStandards()},
+	{path: "holes.pitches", flag: "hole-pitches", kind: kindNumberList, min: 0, max: math.Inf(1), exclusiveMin: true},
+
+	length("dimensions.spine.width", "spine-width"),
+	length("dimensions.spine.thickness", "spine-thickness"),
+	offset("dimensions.spine.inlay-width", "spine-inlay-width"),
+	length("dimensions.segment.height", "segment-height"),
+	length("dimensions.foot.length", "foot-length"),
+	length("dimensions.foot.thickness-front", "foot-thickness-front"),
+	length("dimensions.foot.thickness-back", "foot-thickness-back"),
+	offset("dimensions.foot.spacer-height", "foot-spacer-height"),
+	length("dimensions.side-brace.padding", "side-brace-padding"),
+	length("dimensions.side-brace.inner-padding", "side-brace-inner-padding"),
+	length("dimensions.side-brace.attachment-depth", "side-brace-attachment-depth"),
+	length("dimensions.side-brace.width", "side-brace-width"),
+
+	{path: "foot-holes.count", flag: "foot-holes", kind: kindInteger, min: 0, max: math.MaxUint8},
+	{path: "foot-holes.screw", flag: "foot-screw", kind: kindString, enum: screwStandards()},
+	{path: "foot-holes.type", flag: "foot-hole-type", kind: kindString, enum: holeTypes()},
+	{path: "foot-holes.insert", flag: "foot-insert", kind: kindString, enum: holes.InsertNames()},
+
+	{path: "side-braces", flag: "side-brace-sides", kind: kindString, enum: sides()},
+
+	{path: "printer.build-volume", flag: "build-volume", kind: kindNumberList, min: 0, max: math.Inf(1)},
+	{path: "printer.material", flag: "material", kind: kindString},
+	{path: "printer.infill", flag: "infill", kind: kindNumber, min: 0, max: 100},
+	length("printer.filament-diameter", "filament-diameter"),
+	length("printer.nozzle", "nozzle"),
+	{path: "joints.type", flag: "joint", kind: kindString, enum: joints()},
+	length("joints.depth", "joint-depth"),
+
+	{path: "quality.production", flag: "production", kind: kindBool},
+	{path: "quality.fa", flag: "fa", kind: kindNumber, min: 0.01, max: 360},
+	{path: "quality.fs", flag: "fs", kind: kindNumber, min: 0.01, max: math.Inf(1)},
+	{path: "quality.fn", flag: "fn", kind: kindInteger, min: 0, max: math.MaxUint16},
+
+	{path: "parts", flag: "parts", kind: kindStringList, enum: partNames()},
+}
+
+func screwStandards() []string {
+	standards := make([]string, 0, len(holes.Screws))
+	for standard := range holes.Screws {
+		standards = append(standards, standard)
+	}
+	sort.Strings(standards)
+
+	return standards
+}
+
+func holeTypes() []string {
+	types := make([]string, 0, len(holes.Types))
+	for _, holeType := range holes.Types {
+		types = append(types, string(holeType))
+	}
+
+	return types
+}
+
+func holeStandards() []string {
+	standards := make([]string, 0, len(rack.HoleStandards))
+	for standard := range rack.HoleStandards {
+		standards = append(standards, standard)
+	}
+	sort.Strings(standards)
+
+	return standards
+}
+
+func sides() []string {
+	names := make([]string, 0, len(rack.AllSides))
+	for _, side := range rack.AllSides {
+		names = append(names, string(side))
+	}
+
+	return names
+}
+
+func joints() []string {
+	names := make([]string, 0, len(rack.AllJoints))
+	for _, joint := range rack.AllJoints {
+		names = append(names, string(joint))
+	}
+
+	return names
+}
+
+func partNames() []string {
+	names := make([]string, 0, len(rack.Parts))
+	for _, part := range rack.Parts {
+		names = append(names, string(part))
+	}
+
+	return names
+}
+
+// lookupField returns the field at the given path. If path is a table that
+// contains fields, isTable is true instead.
+func lookupField(path string) (field *field, isTable bool) {
+	for i := range schema {
+		if schema[i].path == path {
+			return &schema[i], false
+		}
+		if strings.HasPrefix(schema[i].path, path+".") {
+			isTable = true
+		}
+	}
+
+	return nil, isTable
+}
+
+// validate walks the decoded values and checks them against the schema. It
+// returns the valid values formatted as flag values, keyed by flag name, and
+// an error for every violation.
+func validate(file string, values map[string]any, positions map[string]Position) (map[string]string, []*SchemaError) {
+	flags := map[string]string{}
+	var errs []*SchemaError
+
+	var walk func(prefix string, table map[string]any)
+	walk = func(prefix string, table map[string]any) {
+		for key, value := range table {
+			path := joinPath(prefix, key)
+			fail := func(format string, args ...any) {
+				errs = append(errs, &SchemaError{
+					File:     file,
+					Path:     path,
+					Position: positions[path],
+					Message:  fmt.Sprintf(format, args...),
+				})
+			}
+
+			field, isTable := lookupField(path)
+			switch {
+			case isTable:
+				nested, ok := value.(map[string]any)
+				if !ok {
+					fail("must be a table")
+
+					continue
+				}
+				walk(path, nested)
+			case field == nil:
+				fail("unknown key")
+			default:
+				flagValue, err := field.format(value)
+				if err != nil {
+					fail("%s", err)
+
+					continue
+				}
+				flags[field.flag] = flagValue
+			}
+		}
+	}
+	walk("", values)
+
+	sort.SliceStable(errs, func(i, j int) bool {
+		return errs[i].Position.before(errs[j].Position)
+	})
+
+	return flags, errs
+}
+
+// format checks value against the field and formats it the way it would be
+// given on the command line.
+func (field *field) format(value any) (string, error) {
+	switch field.kind {
+	case kindBool:
+		boolean, ok := value.(bool)
+		if !ok {
+			return "", field.typeError(value)
+		}
+
+		return strconv.FormatBool(boolean), nil
+	case kindInteger:
+		number, ok := asNumber(value)
+		if !ok || number != math.Trunc(number) {
+			return "", field.typeError(value)
+		}
+		if err := field.checkBounds(number); err != nil {
+			return "", err
+		}
+
+		return strconv.FormatFloat(number, 'f', -1, 64), nil
+	case kindNumber:
+		number, ok := asNumber(value)
+		if !ok {
+			return "", field.typeError(value)
+		}
+		if err := field.checkBounds(number); err != nil {
+			return "", err
+		}
+
+		return strconv.FormatFloat(number, 'f', -1, 64), nil
+	case kindString:
+		str, ok := value.(string)
+		if !ok {
+			return "", field.typeError(value)
+		}
+		if err := field.checkEnum(str); err != nil {
+			return "", err
+		}
+
+		return str, nil
+	case kindStringList:
+		list, ok := value.([]any)
+		if !ok {
+			return "", field.typeError(value)
+		}
+		items := make([]string, 0, len(list))
+		for _, item := range list {
+			str, ok := item.(string)
+			if !ok {
+				return "", field.typeError(value)
+			}
+			if err := field.checkEnum(str); err != nil {
+				return "", err
+			}
+			items = append(items, str)
+		}
+
+		return strings.Join(items, ","), nil
+	case kindNumberList:
+		list, ok := value.([]any)
+		if !ok {
+			return "", field.typeError(value)
+		}
+		items := make([]string, 0, len(list))
+		for _, item := range list {
+			number, ok := asNumber(item)
+			if !ok {
+				return "", field.typeError(value)
+			}
+			if err := field.checkBounds(number); err != nil {
+				return "", err
+			}
+			items = append(items, strconv.FormatFloat(number, 'f', -1, 64))
+		}
+
+		return strings.Join(items, ","), nil
+	default:
+		panic("unknown field kind. this should not happen")
+	}
+}
+
+func (field *field) typeError(value any) error {
+	return fmt.Errorf("must be %s, got %v", field.kind, value)
+}
+
+func (field *field) checkBounds(number float64) error {
+	switch {
+	case field.exclusiveMin && number <= field.min:
+		return fmt.Errorf("must be greater than %g, got %g", field.min, number)
+	case number < field.min:
+		return fmt.Errorf("must be at least %g, got %g", field.min, number)
+	case number > field.max:
+		return fmt.Errorf("must be at most %g, got %g", field.max, number)
+	default:
+		return nil
+	}
+}
+
+func (field *field) checkEnum(str string) error {
+	if len(field.enum) > 0 && !slices.Contains(field.enum, str) {
+		return fmt.Errorf("must be one of %s, got %q", strings.Join(field.enum, ", "), str)
+	}
+
+	return nil
+}
+
+func asNumber(value any) (float64, bool) {
+	switch number := value.(type) {
+	case int:
+		return float64(number), true
+	case int64:
+		return float64(number), true
+	case uint64:
+		return float64(number), true
+	case float64:
+		return number, true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,103 @@
+// Package filament estimates how much filament printing a part takes.
+package filament
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+var ErrUnknownMaterial = errors.New("unknown material")
+
+// Material is a kind of filament.
+type Material struct {
+	Name string
+
+	// Density is the density of the material in g/cm³.
+	Density float64
+}
+
+// Materials lists the materials whose density is known, by name.
+var Materials = []Material{
+	{Name: "PLA", Density: 1.24},
+	{Name: "PETG", Density: 1.27},
+	{Name: "ABS", Density: 1.04},
+}
+
+// MaterialNames returns the names of Materials.
+func MaterialNames() []string {
+	names := make([]string, 0, len(Materials))
+	for _, material := range Materials {
+		names = append(names, material.Name)
+	}
+
+	return names
+}
+
+// LookupMaterial returns the material with the given name, ignoring case.
+func LookupMaterial(name string) (Material, error) {
+	index := slices.IndexFunc(Materials, func(material Material) bool {
+		return strings.EqualFold(material.Name, name)
+	})
+	if index < 0 {
+		return Material{}, fmt.Errorf("%w %q, expected one of %s", ErrUnknownMaterial, name, strings.Join(MaterialNames(), ", "))
+	}
+
+	return Materials[index], nil
+}
+
+// Settings describe the filament and how a part is sliced.
+type Settings struct {
+	// Diameter is the diameter of the filament in mm.
+	Diameter float64
+
+	// Density is the density of the filament in g/cm³.
+	Density float64
+
+	// Infill is how much of a part's interior is filled, in percent.
+	Infill float64
+
+	// ShellThickness is how thick the perimeters and the top and bottom layers
+	// are in mm. They are printed solid, the rest of the part with Infill.
+	ShellThickness float64
+}
+
+// Estimate is how much filament a part takes.
+type Estimate struct {
+	// Volume is the volume of the printed plastic in cm³.
+	Volume float64 `json:"volume"`
+
+	// Length is the length of the filament in m.
+	Length float64 `json:"length"`
+
+	// Mass is the mass of the filament in g.
+	Mass float64 `json:"mass"`
+}
+
+// Add returns the sum of both estimates.
+func (estimate Estimate) Add(other Estimate) Estimate {
+	return Estimate{
+		Volume: estimate.Volume + other.Volume,
+		Length: estimate.Length + other.Length,
+		Mass:   estimate.Mass + other.Mass,
+	}
+}
+
+// Estimate estimates the filament for a part with the given volume in mm³ and
+// surface area in mm². The shell is approximated as the surface times the
+// shell thickness, so thin parts are printed solid.
+func (settings Settings) Estimate(volume, area float64) Estimate {
+	shell := min(area*settings.ShellThickness, volume)
+	printed := shell + (volume-shell)*settings.Infill/100
+
+	crossSection := math.Pi * settings.Diameter * settings.Diameter / 4
+
+	// There are 1000 mm³ in a cm³ and 1000 mm in a m.
+	return Estimate{
+		Volume: printed / 1000,
+		Length: printed / crossSection / 1000,
+		Mass:   printed / 1000 * settings.Density,
+	}
+}
@@ -0,0 +1,68 @@
+package filament
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupMaterial(t *testing.T) {
+	t.Parallel()
+
+	t.Run("finds materials regardless of case.", func(t *testing.T) {
+		t.Parallel()
+
+		material, err := LookupMaterial("petg")
+		require.NoError(t, err)
+
+		assert.Equal(t, "PETG", material.Name)
+	})
+
+	t.Run("rejects unknown materials.", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := LookupMaterial("wood")
+
+		require.ErrorIs(t, err, ErrUnknownMaterial)
+		assert.ErrorContains(t, err, "PLA, PETG, ABS")
+	})
+}
+
+func TestSettingsEstimate(t *testing.T) {
+	t.Parallel()
+
+	t.Run("converts solid parts into filament.", func(t *testing.T) {
+		t.Parallel()
+
+		settings := Settings{Diameter: 1.75, Density: 1.24, Infill: 100, ShellThickness: 0.8}
+
+		estimate := settings.Estimate(10000, 2400)
+
+		assert.InDelta(t, 10, estimate.Volume, 1e-9)
+		assert.InDelta(t, 10000/(math.Pi*1.75*1.75/4)/1000, estimate.Length, 1e-9)
+		assert.InDelta(t, 12.4, estimate.Mass, 1e-9)
+	})
+
+	t.Run("fills the interior with infill.", func(t *testing.T) {
+		t.Parallel()
+
+		settings := Settings{Diameter: 1.75, Density: 1, Infill: 20, ShellThickness: 1}
+
+		// A 20 mm cube has a shell of 2400 mm³ and an interior of 5600 mm³.
+		estimate := settings.Estimate(8000, 2400)
+
+		assert.InDelta(t, 2.4+5.6*0.2, estimate.Volume, 1e-9)
+	})
+
+	t.Run("prints thin parts solid.", func(t *testing.T) {
+		t.Parallel()
+
+		settings := Settings{Diameter: 1.75, Density: 1, Infill: 0, ShellThickness: 2}
+
+		estimate := settings.Estimate(300, 700)
+
+		assert.InDelta(t, 0.3, estimate.Volume, 1e-9)
+	})
+}
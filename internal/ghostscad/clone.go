@@ -1,7 +0,0 @@
-package ghostscad
-
-import "github.com/ljanyst/ghostscad/primitive"
-
-func CloneTransform(transform *primitive.Transform) *primitive.Transform {
-	return transform.Inverse().Inverse()
-}
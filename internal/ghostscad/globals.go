@@ -40,6 +40,14 @@ func SetFn(val uint16) {
 	fn = val
 }
 
+// Reset the fragment settings to their defaults, e.g. before rendering another
+// model in the same process.
+func ResetFragments() {
+	fa = 12.0
+	fs = 2.0
+	fn = 0
+}
+
 // Import SCAD files and fonts.
 func Use(file string) {
 	uses = append(uses, file)
@@ -53,3 +61,8 @@ func RenderGlobals(w *bufio.Writer) {
 		_, _ = fmt.Fprintf(w, "use <%s>;\n", use)
 	}
 }
+
+// Fragments returns the fragment settings that RenderGlobals renders.
+func Fragments() (float64, float64, uint16) {
+	return fa, fs, fn
+}
@@ -0,0 +1,89 @@
+package ghostscad
+
+import (
+	"bufio"
+	"strconv"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/ljanyst/ghostscad/primitive"
+)
+
+// MultMatrix applies an affine transformation matrix to its items. Unlike
+// chains of primitive.Transform it does not need to decompose rotations into
+// euler angles, so it renders arbitrary transformations without losing
+// precision.
+type MultMatrix struct {
+	primitive.ParentImpl
+
+	Matrix mgl64.Mat4
+	Items  *primitive.List
+
+	prefix string
+}
+
+func NewMultMatrix(matrix mgl64.Mat4, items ...primitive.Primitive) *MultMatrix {
+	multMatrix := &MultMatrix{
+		Matrix: matrix,
+		Items:  primitive.NewList(),
+	}
+	multMatrix.Items.SetParent(multMatrix)
+	multMatrix.Items.Add(items...)
+
+	return multMatrix
+}
+
+func (multMatrix *MultMatrix) Add(items ...primitive.Primitive) *MultMatrix {
+	multMatrix.Items.Add(items...)
+
+	return multMatrix
+}
+
+func (multMatrix *MultMatrix) Disable() primitive.Primitive { //nolint:ireturn
+	multMatrix.prefix = "*"
+
+	return multMatrix
+}
+
+func (multMatrix *MultMatrix) ShowOnly() primitive.Primitive { //nolint:ireturn
+	multMatrix.prefix = "!"
+
+	return multMatrix
+}
+
+func (multMatrix *MultMatrix) Highlight() primitive.Primitive { //nolint:ireturn
+	multMatrix.prefix = "#"
+
+	return multMatrix
+}
+
+func (multMatrix *MultMatrix) Transparent() primitive.Primitive { //nolint:ireturn
+	multMatrix.prefix = "%"
+
+	return multMatrix
+}
+
+func (multMatrix *MultMatrix) Prefix() string {
+	return multMatrix.prefix
+}
+
+func (multMatrix *MultMatrix) Render(w *bufio.Writer) {
+	_, _ = w.WriteString(multMatrix.Prefix())
+	_, _ = w.WriteString("multmatrix([")
+	for row := range 4 {
+		if row > 0 {
+			_, _ = w.WriteString(", ")
+		}
+		_, _ = w.WriteString("[")
+		for column := range 4 {
+			if column > 0 {
+				_, _ = w.WriteString(", ")
+			}
+			// The shortest representation that parses back to the same number,
+			// since %f would round the matrix to six decimals.
+			_, _ = w.WriteString(strconv.FormatFloat(multMatrix.Matrix.At(row, column), 'g', -1, 64))
+		}
+		_, _ = w.WriteString("]")
+	}
+	_, _ = w.WriteString("]) ")
+	multMatrix.Items.Render(w)
+}
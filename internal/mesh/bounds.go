@@ -0,0 +1,194 @@
+package mesh
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/ljanyst/ghostscad/primitive"
+)
+
+// Box is an axis-aligned bounding box. A box whose Min exceeds its Max along
+// any axis is empty.
+type Box struct {
+	Min, Max mgl64.Vec3
+}
+
+// EmptyBox returns a box that contains nothing and is the identity of Union.
+func EmptyBox() Box {
+	return Box{
+		Min: mgl64.Vec3{math.Inf(1), math.Inf(1), math.Inf(1)},
+		Max: mgl64.Vec3{math.Inf(-1), math.Inf(-1), math.Inf(-1)},
+	}
+}
+
+// IsEmpty reports whether the box contains nothing.
+func (box Box) IsEmpty() bool {
+	return box.Min.X() > box.Max.X() || box.Min.Y() > box.Max.Y() || box.Min.Z() > box.Max.Z()
+}
+
+// Size returns the extent of the box along each axis, which is zero for empty
+// boxes.
+func (box Box) Size() mgl64.Vec3 {
+	if box.IsEmpty() {
+		return mgl64.Vec3{}
+	}
+
+	return box.Max.Sub(box.Min)
+}
+
+// Extend returns the smallest box that contains the box and point.
+func (box Box) Extend(point mgl64.Vec3) Box {
+	for axis := range 3 {
+		box.Min[axis] = min(box.Min[axis], point[axis])
+		box.Max[axis] = max(box.Max[axis], point[axis])
+	}
+
+	return box
+}
+
+// Union returns the smallest box that contains both boxes.
+func (box Box) Union(other Box) Box {
+	if other.IsEmpty() {
+		return box
+	}
+
+	return box.Extend(other.Min).Extend(other.Max)
+}
+
+// Intersection returns the box that both boxes contain.
+func (box Box) Intersection(other Box) Box {
+	for axis := range 3 {
+		box.Min[axis] = max(box.Min[axis], other.Min[axis])
+		box.Max[axis] = min(box.Max[axis], other.Max[axis])
+	}
+	if box.IsEmpty() {
+		return EmptyBox()
+	}
+
+	return box
+}
+
+// Transformed returns the bounding box of the box's corners after applying
+// transform, which contains the transformed box.
+func (box Box) Transformed(transform mgl64.Mat4) Box {
+	if box.IsEmpty() {
+		return box
+	}
+
+	result := EmptyBox()
+	for corner := range 8 {
+		point := box.Min
+		for axis := range 3 {
+			if corner&(1<<axis) != 0 {
+				point[axis] = box.Max[axis]
+			}
+		}
+		result = result.Extend(mgl64.TransformCoordinate(point, transform))
+	}
+
+	return result
+}
+
+// Fits reports whether the box fits into a volume of the given size without
+// being rotated.
+func (box Box) Fits(volume mgl64.Vec3) bool {
+	size := box.Size()
+
+	return size.X() <= volume.X() && size.Y() <= volume.Y() && size.Z() <= volume.Z()
+}
+
+// Bounds returns the bounding box of a primitive that is placed by transform,
+// see FromPrimitive for what is understood. Only differences and
+// intersections are evaluated into solids, the boxes of everything else are
+// combined directly, so it is faster than measuring the primitive's mesh.
+func Bounds(item primitive.Primitive, transform mgl64.Mat4) (Box, error) {
+	root, err := statementOf(item)
+	if err != nil {
+		return Box{}, err
+	}
+
+	evaluator := &evaluator{quality: globalQuality()}
+
+	return evaluator.statementBounds(root, transform, nil)
+}
+
+// bounds returns the bounding box of a block, whose shapes are placed by
+// transform.
+func (evaluator *evaluator) bounds(statements []*statement, transform mgl64.Mat4, extrusion *extrusion) (Box, error) {
+	result := EmptyBox()
+	for _, statement := range statements {
+		box, err := evaluator.statementBounds(statement, transform, extrusion)
+		if err != nil {
+			return Box{}, err
+		}
+		result = result.Union(box)
+	}
+
+	return result, nil
+}
+
+func (evaluator *evaluator) statementBounds(statement *statement, transform mgl64.Mat4, extrusion *extrusion) (Box, error) {
+	switch statement.modifier {
+	case '%', '*':
+		return EmptyBox(), nil
+	case '!':
+		return Box{}, evaluator.fail(statement, "the root modifier %q", statement.modifier)
+	}
+
+	if statement.isAssignment() {
+		return EmptyBox(), evaluator.assign(statement)
+	}
+
+	arguments, err := newArguments(statement)
+	if err != nil {
+		return Box{}, err
+	}
+
+	switch statement.name {
+	case "", "union":
+		return evaluator.bounds(statement.children, transform, extrusion)
+	case "difference", "intersection":
+		// The box of a difference or intersection depends on how its
+		// operands overlap, so it is taken from the evaluated solid.
+		solid, err := evaluator.boolean(statement, extrusion)
+		if err != nil {
+			return Box{}, err
+		}
+
+		return solidBounds(solid, transform), nil
+	case "translate", "rotate", "mirror", "multmatrix", "scale":
+		childTransform, err := arguments.transform(statement.name)
+		if err != nil {
+			return Box{}, err
+		}
+
+		return evaluator.bounds(statement.children, transform.Mul4(childTransform), extrusion)
+	case "linear_extrude":
+		inner, err := evaluator.linearExtrusion(statement, arguments, extrusion)
+		if err != nil {
+			return Box{}, err
+		}
+
+		return evaluator.bounds(statement.children, transform, inner)
+	default:
+		solid, err := evaluator.leaf(statement, arguments, extrusion)
+		if err != nil {
+			return Box{}, err
+		}
+
+		return solidBounds(solid, transform), nil
+	}
+}
+
+// solidBounds returns the bounding box of the solid's vertices after applying
+// transform.
+func solidBounds(solid solid, transform mgl64.Mat4) Box {
+	result := EmptyBox()
+	for _, polygon := range solid {
+		for _, vertex := range polygon.vertices {
+			result = result.Extend(mgl64.TransformCoordinate(vertex, transform))
+		}
+	}
+
+	return result
+}
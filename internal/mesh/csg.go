@@ -0,0 +1,362 @@
+package mesh
+
+import (
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+// planeEpsilon is the distance in mm within which points count as lying on a
+// plane.
+const planeEpsilon = 1e-5
+
+// parallelEpsilon is the length below which cross products count as zero, so
+// that their factors are parallel.
+const parallelEpsilon = 1e-12
+
+type csgPlane struct {
+	normal   mgl64.Vec3
+	distance float64
+}
+
+// planeFromPoints returns the plane through a, b and c, whose normal points to
+// where a, b and c turn counter-clockwise. ok is false for collinear points.
+func planeFromPoints(a, b, c mgl64.Vec3) (result csgPlane, ok bool) {
+	normal := b.Sub(a).Cross(c.Sub(a))
+	length := normal.Len()
+	if length < parallelEpsilon {
+		return csgPlane{}, false
+	}
+	normal = normal.Mul(1 / length)
+
+	return csgPlane{normal: normal, distance: normal.Dot(a)}, true
+}
+
+func (plane csgPlane) flipped() csgPlane {
+	return csgPlane{normal: plane.normal.Mul(-1), distance: -plane.distance}
+}
+
+// polygon is a convex, planar polygon whose vertices are ordered
+// counter-clockwise when seen from the front.
+type csgPolygon struct {
+	vertices []mgl64.Vec3
+	plane    csgPlane
+}
+
+// newPolygon creates a polygon from its vertices. ok is false for degenerate
+// polygons, which have no area.
+func newPolygon(vertices []mgl64.Vec3) (result csgPolygon, ok bool) {
+	if len(vertices) < 3 {
+		return csgPolygon{}, false
+	}
+	// The first three vertices may be collinear in a valid polygon, so the
+	// plane is taken from the largest triangle of the fan.
+	var best csgPlane
+	bestArea := 0.0
+	for i := 2; i < len(vertices); i++ {
+		area := vertices[i-1].Sub(vertices[0]).Cross(vertices[i].Sub(vertices[0])).Len()
+		if area > bestArea {
+			bestArea = area
+			best, ok = planeFromPoints(vertices[0], vertices[i-1], vertices[i])
+		}
+	}
+	if !ok {
+		return csgPolygon{}, false
+	}
+
+	return csgPolygon{vertices: vertices, plane: best}, true
+}
+
+func (polygon csgPolygon) flipped() csgPolygon {
+	vertices := make([]mgl64.Vec3, len(polygon.vertices))
+	for i, vertex := range polygon.vertices {
+		vertices[len(vertices)-1-i] = vertex
+	}
+
+	return csgPolygon{vertices: vertices, plane: polygon.plane.flipped()}
+}
+
+const (
+	coplanar = 0
+	front    = 1
+	back     = 2
+	spanning = front | back
+)
+
+// split sorts polygon into the given lists by its position relative to plane.
+// Polygons that span the plane are split in two.
+func (plane csgPlane) split(polygon csgPolygon, coplanarFront, coplanarBack, fronts, backs *[]csgPolygon) {
+	polygonType := 0
+	types := make([]int, len(polygon.vertices))
+	for i, vertex := range polygon.vertices {
+		t := plane.normal.Dot(vertex) - plane.distance
+		switch {
+		case t < -planeEpsilon:
+			types[i] = back
+		case t > planeEpsilon:
+			types[i] = front
+		default:
+			types[i] = coplanar
+		}
+		polygonType |= types[i]
+	}
+
+	switch polygonType {
+	case coplanar:
+		if plane.normal.Dot(polygon.plane.normal) > 0 {
+			*coplanarFront = append(*coplanarFront, polygon)
+		} else {
+			*coplanarBack = append(*coplanarBack, polygon)
+		}
+	case front:
+		*fronts = append(*fronts, polygon)
+	case back:
+		*backs = append(*backs, polygon)
+	case spanning:
+		var frontVertices, backVertices []mgl64.Vec3
+		for i, vertex := range polygon.vertices {
+			j := (i + 1) % len(polygon.vertices)
+			next := polygon.vertices[j]
+			if types[i] != back {
+				frontVertices = append(frontVertices, vertex)
+			}
+			if types[i] != front {
+				backVertices = append(backVertices, vertex)
+			}
+			if types[i]|types[j] == spanning {
+				t := (plane.distance - plane.normal.Dot(vertex)) / plane.normal.Dot(next.Sub(vertex))
+				intersection := vertex.Add(next.Sub(vertex).Mul(t))
+				frontVertices = append(frontVertices, intersection)
+				backVertices = append(backVertices, intersection)
+			}
+		}
+		if len(frontVertices) >= 3 {
+			*fronts = append(*fronts, csgPolygon{vertices: frontVertices, plane: polygon.plane})
+		}
+		if len(backVertices) >= 3 {
+			*backs = append(*backs, csgPolygon{vertices: backVertices, plane: polygon.plane})
+		}
+	}
+}
+
+// bspNode is a node of a binary space partitioning tree, which is how the
+// boolean operations are implemented. See Laidlaw, Trumbore and Hughes,
+// "Constructive Solid Geometry for Polyhedral Objects" and the csg.js library
+// by Evan Wallace, which this is a port of.
+type bspNode struct {
+	plane    *csgPlane
+	front    *bspNode
+	back     *bspNode
+	polygons []csgPolygon
+}
+
+func newBSPNode(polygons []csgPolygon) *bspNode {
+	node := &bspNode{}
+	node.build(polygons)
+
+	return node
+}
+
+// invert turns the solid inside out.
+func (node *bspNode) invert() {
+	for i, polygon := range node.polygons {
+		node.polygons[i] = polygon.flipped()
+	}
+	if node.plane != nil {
+		flipped := node.plane.flipped()
+		node.plane = &flipped
+	}
+	if node.front != nil {
+		node.front.invert()
+	}
+	if node.back != nil {
+		node.back.invert()
+	}
+	node.front, node.back = node.back, node.front
+}
+
+// clipPolygons removes the parts of polygons that lie inside the solid.
+func (node *bspNode) clipPolygons(polygons []csgPolygon) []csgPolygon {
+	if node.plane == nil {
+		return append([]csgPolygon(nil), polygons...)
+	}
+
+	var fronts, backs []csgPolygon
+	for _, polygon := range polygons {
+		node.plane.split(polygon, &fronts, &backs, &fronts, &backs)
+	}
+	if node.front != nil {
+		fronts = node.front.clipPolygons(fronts)
+	}
+	if node.back != nil {
+		backs = node.back.clipPolygons(backs)
+	} else {
+		backs = nil
+	}
+
+	return append(fronts, backs...)
+}
+
+// clipTo removes the parts of this tree's polygons that lie inside other.
+func (node *bspNode) clipTo(other *bspNode) {
+	node.polygons = other.clipPolygons(node.polygons)
+	if node.front != nil {
+		node.front.clipTo(other)
+	}
+	if node.back != nil {
+		node.back.clipTo(other)
+	}
+}
+
+func (node *bspNode) allPolygons() []csgPolygon {
+	polygons := append([]csgPolygon(nil), node.polygons...)
+	if node.front != nil {
+		polygons = append(polygons, node.front.allPolygons()...)
+	}
+	if node.back != nil {
+		polygons = append(polygons, node.back.allPolygons()...)
+	}
+
+	return polygons
+}
+
+func (node *bspNode) build(polygons []csgPolygon) {
+	if len(polygons) == 0 {
+		return
+	}
+	if node.plane == nil {
+		plane := polygons[0].plane
+		node.plane = &plane
+	}
+
+	var fronts, backs []csgPolygon
+	for _, polygon := range polygons {
+		node.plane.split(polygon, &node.polygons, &node.polygons, &fronts, &backs)
+	}
+	if len(fronts) > 0 {
+		if node.front == nil {
+			node.front = &bspNode{}
+		}
+		node.front.build(fronts)
+	}
+	if len(backs) > 0 {
+		if node.back == nil {
+			node.back = &bspNode{}
+		}
+		node.back.build(backs)
+	}
+}
+
+// solid is a closed surface made of polygons.
+type solid []csgPolygon
+
+func (solid solid) bounds() (lower, upper mgl64.Vec3) {
+	if len(solid) == 0 {
+		return mgl64.Vec3{}, mgl64.Vec3{}
+	}
+	lower = solid[0].vertices[0]
+	upper = lower
+	for _, polygon := range solid {
+		for _, vertex := range polygon.vertices {
+			for axis := range 3 {
+				lower[axis] = min(lower[axis], vertex[axis])
+				upper[axis] = max(upper[axis], vertex[axis])
+			}
+		}
+	}
+
+	return lower, upper
+}
+
+// overlaps reports whether the bounding boxes of the solids overlap, within
+// planeEpsilon.
+func (solid solid) overlaps(other solid) bool {
+	if len(solid) == 0 || len(other) == 0 {
+		return false
+	}
+	lower, upper := solid.bounds()
+	otherLower, otherUpper := other.bounds()
+	for axis := range 3 {
+		if upper[axis] < otherLower[axis]-planeEpsilon || otherUpper[axis] < lower[axis]-planeEpsilon {
+			return false
+		}
+	}
+
+	return true
+}
+
+func union(a, b solid) solid {
+	if !a.overlaps(b) {
+		return append(append(solid(nil), a...), b...)
+	}
+
+	nodeA := newBSPNode(a)
+	nodeB := newBSPNode(b)
+	nodeA.clipTo(nodeB)
+	nodeB.clipTo(nodeA)
+	nodeB.invert()
+	nodeB.clipTo(nodeA)
+	nodeB.invert()
+	nodeA.build(nodeB.allPolygons())
+
+	return nodeA.allPolygons()
+}
+
+func difference(a, b solid) solid {
+	if !a.overlaps(b) {
+		return a
+	}
+
+	nodeA := newBSPNode(a)
+	nodeB := newBSPNode(b)
+	nodeA.invert()
+	nodeA.clipTo(nodeB)
+	nodeB.clipTo(nodeA)
+	nodeB.invert()
+	nodeB.clipTo(nodeA)
+	nodeB.invert()
+	nodeA.build(nodeB.allPolygons())
+	nodeA.invert()
+
+	return nodeA.allPolygons()
+}
+
+func intersection(a, b solid) solid {
+	if !a.overlaps(b) {
+		return nil
+	}
+
+	nodeA := newBSPNode(a)
+	nodeB := newBSPNode(b)
+	nodeA.invert()
+	nodeB.clipTo(nodeA)
+	nodeB.invert()
+	nodeA.clipTo(nodeB)
+	nodeB.clipTo(nodeA)
+	nodeA.build(nodeB.allPolygons())
+	nodeA.invert()
+
+	return nodeA.allPolygons()
+}
+
+// transformed applies transform to every vertex of the solid. Transforms that
+// mirror the solid also reverse the order of the vertices, so that the faces
+// keep pointing outwards.
+func (solid solid) transformed(transform mgl64.Mat4) solid {
+	mirrors := transform.Mat3().Det() < 0
+
+	result := make([]csgPolygon, 0, len(solid))
+	for _, polygon := range solid {
+		vertices := make([]mgl64.Vec3, len(polygon.vertices))
+		for i, vertex := range polygon.vertices {
+			index := i
+			if mirrors {
+				index = len(vertices) - 1 - i
+			}
+			vertices[index] = mgl64.TransformCoordinate(vertex, transform)
+		}
+		if transformed, ok := newPolygon(vertices); ok {
+			result = append(result, transformed)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,507 @@
+package mesh
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+// ErrUnsupported is returned for OpenSCAD code that the mesh backend cannot
+// evaluate, e.g. text or 2D shapes outside of an extrusion.
+var ErrUnsupported = errors.New("not supported by the mesh backend")
+
+// quality holds the special variables that control how finely circles are
+// approximated.
+type quality struct {
+	fa, fs, fn float64
+}
+
+// defaultQuality are OpenSCAD's defaults.
+var defaultQuality = quality{fa: 12, fs: 2, fn: 0}
+
+// extrusion is the linear extrusion that 2D shapes are evaluated in.
+type extrusion struct {
+	bottom, top float64
+}
+
+type evaluator struct {
+	quality quality
+}
+
+func (evaluator *evaluator) fail(statement *statement, format string, args ...any) error {
+	return fmt.Errorf("%w: %s%s", ErrUnsupported, statement.location(), fmt.Sprintf(format, args...))
+}
+
+// evaluate evaluates the statements of a block and returns the union of their
+// solids. 2D shapes are extruded by extrusion, which is nil outside of
+// extrusions.
+func (evaluator *evaluator) evaluate(statements []*statement, extrusion *extrusion) (solid, error) {
+	var result solid
+	for _, statement := range statements {
+		solid, err := evaluator.statement(statement, extrusion)
+		if err != nil {
+			return nil, err
+		}
+		result = union(result, solid)
+	}
+
+	return result, nil
+}
+
+func (evaluator *evaluator) statement(statement *statement, extrusion *extrusion) (solid, error) {
+	switch statement.modifier {
+	case '%', '*':
+		// Background and disabled objects are not part of the rendered model.
+		return nil, nil
+	case '!':
+		return nil, evaluator.fail(statement, "the root modifier %q", statement.modifier)
+	}
+
+	if statement.isAssignment() {
+		return nil, evaluator.assign(statement)
+	}
+
+	arguments, err := newArguments(statement)
+	if err != nil {
+		return nil, err
+	}
+
+	switch statement.name {
+	case "":
+		return evaluator.evaluate(statement.children, extrusion)
+	case "union":
+		return evaluator.evaluate(statement.children, extrusion)
+	case "difference", "intersection":
+		return evaluator.boolean(statement, extrusion)
+	case "translate", "rotate", "mirror", "multmatrix", "scale":
+		transform, err := arguments.transform(statement.name)
+		if err != nil {
+			return nil, err
+		}
+		children, err := evaluator.evaluate(statement.children, extrusion)
+		if err != nil {
+			return nil, err
+		}
+
+		return children.transformed(transform), nil
+	case "linear_extrude":
+		inner, err := evaluator.linearExtrusion(statement, arguments, extrusion)
+		if err != nil {
+			return nil, err
+		}
+
+		return evaluator.evaluate(statement.children, inner)
+	default:
+		return evaluator.leaf(statement, arguments, extrusion)
+	}
+}
+
+// leaf evaluates the shapes that have no children.
+func (evaluator *evaluator) leaf(statement *statement, arguments arguments, extrusion *extrusion) (solid, error) {
+	switch statement.name {
+	case "cube":
+		if extrusion != nil {
+			return nil, evaluator.fail(statement, "cube within a 2D context")
+		}
+		size, err := arguments.vector("size", 0, mgl64.Vec3{1, 1, 1})
+		if err != nil {
+			return nil, err
+		}
+		center, err := arguments.boolean("center", false)
+
+		return newCube(size, center), err
+	case "cylinder":
+		if extrusion != nil {
+			return nil, evaluator.fail(statement, "cylinder within a 2D context")
+		}
+
+		return evaluator.cylinder(arguments)
+	case "polygon":
+		if extrusion == nil {
+			return nil, evaluator.fail(statement, "polygon outside of an extrusion")
+		}
+		outline, err := arguments.outline()
+
+		return newPrism(outline, extrusion.bottom, extrusion.top), err
+	case "square":
+		if extrusion == nil {
+			return nil, evaluator.fail(statement, "square outside of an extrusion")
+		}
+		size, err := arguments.vector("size", 0, mgl64.Vec3{1, 1, 0})
+		if err != nil {
+			return nil, err
+		}
+		center, err := arguments.boolean("center", false)
+		lower := mgl64.Vec2{}
+		if center {
+			lower = mgl64.Vec2{-size.X() / 2, -size.Y() / 2}
+		}
+
+		return newPrism([]mgl64.Vec2{
+			lower,
+			{lower.X() + size.X(), lower.Y()},
+			{lower.X() + size.X(), lower.Y() + size.Y()},
+			{lower.X(), lower.Y() + size.Y()},
+		}, extrusion.bottom, extrusion.top), err
+	default:
+		return nil, evaluator.fail(statement, "the module %s", statement.name)
+	}
+}
+
+func (evaluator *evaluator) assign(statement *statement) error {
+	value, ok := statement.value.(float64)
+	switch {
+	case !ok:
+		return evaluator.fail(statement, "assigning %v to %s", statement.value, statement.name)
+	case statement.name == "$fa":
+		evaluator.quality.fa = max(value, 0.01)
+	case statement.name == "$fs":
+		evaluator.quality.fs = max(value, 0.01)
+	case statement.name == "$fn":
+		evaluator.quality.fn = value
+	default:
+		return evaluator.fail(statement, "the special variable %s", statement.name)
+	}
+
+	return nil
+}
+
+// boolean subtracts all but the first child from the first one, or
+// intersects them.
+func (evaluator *evaluator) boolean(statement *statement, extrusion *extrusion) (solid, error) {
+	var result solid
+	first := true
+	for _, child := range statement.children {
+		solid, err := evaluator.statement(child, extrusion)
+		if err != nil {
+			return nil, err
+		}
+		if child.modifier == '%' || child.modifier == '*' || child.isAssignment() {
+			continue
+		}
+
+		switch {
+		case first:
+			result = solid
+			first = false
+		case statement.name == "difference":
+			result = difference(result, solid)
+		default:
+			result = intersection(result, solid)
+		}
+	}
+
+	return result, nil
+}
+
+func (evaluator *evaluator) cylinder(arguments arguments) (solid, error) {
+	cylinder, err := arguments.cylinder()
+	if err != nil {
+		return nil, err
+	}
+	quality, err := arguments.quality(evaluator.quality)
+	if err != nil {
+		return nil, err
+	}
+
+	return newCylinder(cylinder.height, cylinder.bottomRadius, cylinder.topRadius, cylinder.center, quality), nil
+}
+
+// linearExtrusion returns the extrusion that the children of a linear_extrude
+// are evaluated in.
+func (evaluator *evaluator) linearExtrusion(statement *statement, arguments arguments, outer *extrusion) (*extrusion, error) {
+	if outer != nil {
+		return nil, evaluator.fail(statement, "linear_extrude within a 2D context")
+	}
+
+	height, err := arguments.number("height", 0, 100)
+	if err != nil {
+		return nil, err
+	}
+	center, err := arguments.boolean("center", false)
+	if err != nil {
+		return nil, err
+	}
+	if twist, err := arguments.number("twist", -1, 0); err != nil || twist != 0 {
+		return nil, errors.Join(err, evaluator.fail(statement, "twisted extrusions"))
+	}
+	if scale, err := arguments.number("scale", -1, 1); err != nil || scale != 1 {
+		return nil, errors.Join(err, evaluator.fail(statement, "scaled extrusions"))
+	}
+
+	if center {
+		return &extrusion{bottom: -height / 2, top: height / 2}, nil
+	}
+
+	return &extrusion{bottom: 0, top: height}, nil
+}
+
+// arguments are the arguments of a module call.
+type arguments struct {
+	statement *statement
+}
+
+func newArguments(statement *statement) (arguments, error) {
+	seen := map[string]bool{}
+	for _, argument := range statement.arguments {
+		if argument.name != "" && seen[argument.name] {
+			return arguments{}, fmt.Errorf("%w: %s%s is given twice", ErrUnsupported, statement.location(), argument.name)
+		}
+		seen[argument.name] = true
+	}
+
+	return arguments{statement: statement}, nil
+}
+
+// get returns the argument with the given name, or the positional argument
+// at position if position is not negative.
+func (arguments arguments) get(name string, position int) (any, bool) {
+	positional := 0
+	for _, argument := range arguments.statement.arguments {
+		if argument.name == name {
+			return argument.value, true
+		}
+		if argument.name == "" {
+			if positional == position {
+				return argument.value, true
+			}
+			positional++
+		}
+	}
+
+	return nil, false
+}
+
+func (arguments arguments) fail(name string, format string, args ...any) error {
+	return fmt.Errorf("%w: %s%s of %s %s", ErrUnsupported, arguments.statement.location(), name, arguments.statement.name, fmt.Sprintf(format, args...))
+}
+
+func (arguments arguments) number(name string, position int, fallback float64) (float64, error) {
+	value, ok := arguments.get(name, position)
+	if !ok {
+		return fallback, nil
+	}
+	number, ok := value.(float64)
+	if !ok {
+		return 0, arguments.fail(name, "must be a number, got %v", value)
+	}
+
+	return number, nil
+}
+
+func (arguments arguments) boolean(name string, fallback bool) (bool, error) {
+	value, ok := arguments.get(name, -1)
+	if !ok {
+		return fallback, nil
+	}
+	boolean, ok := value.(bool)
+	if !ok {
+		return false, arguments.fail(name, "must be a boolean, got %v", value)
+	}
+
+	return boolean, nil
+}
+
+// vector returns a vector argument. Numbers are repeated along all axes and
+// missing coordinates are taken from fallback.
+func (arguments arguments) vector(name string, position int, fallback mgl64.Vec3) (mgl64.Vec3, error) {
+	value, ok := arguments.get(name, position)
+	if !ok {
+		return fallback, nil
+	}
+
+	return arguments.toVector(name, value, fallback)
+}
+
+func (arguments arguments) toVector(name string, value any, fallback mgl64.Vec3) (mgl64.Vec3, error) {
+	switch value := value.(type) {
+	case float64:
+		return mgl64.Vec3{value, value, value}, nil
+	case []any:
+		if len(value) > 3 {
+			return mgl64.Vec3{}, arguments.fail(name, "must have at most 3 coordinates, got %d", len(value))
+		}
+		vector := fallback
+		for i, coordinate := range value {
+			number, ok := coordinate.(float64)
+			if !ok {
+				return mgl64.Vec3{}, arguments.fail(name, "must consist of numbers, got %v", coordinate)
+			}
+			vector[i] = number
+		}
+
+		return vector, nil
+	default:
+		return mgl64.Vec3{}, arguments.fail(name, "must be a vector, got %v", value)
+	}
+}
+
+// cylinderArguments are the dimensions of a cylinder.
+type cylinderArguments struct {
+	height, bottomRadius, topRadius float64
+	center                          bool
+}
+
+func (arguments arguments) cylinder() (cylinderArguments, error) {
+	height, err := arguments.number("h", 0, 1)
+	if err != nil {
+		return cylinderArguments{}, err
+	}
+	radius, err := arguments.number("r", -1, 1)
+	if err != nil {
+		return cylinderArguments{}, err
+	}
+	bottomRadius, err := arguments.number("r1", -1, radius)
+	if err != nil {
+		return cylinderArguments{}, err
+	}
+	topRadius, err := arguments.number("r2", -1, radius)
+	if err != nil {
+		return cylinderArguments{}, err
+	}
+	center, err := arguments.boolean("center", false)
+	if err != nil {
+		return cylinderArguments{}, err
+	}
+
+	return cylinderArguments{height: height, bottomRadius: bottomRadius, topRadius: topRadius, center: center}, nil
+}
+
+func (arguments arguments) quality(outer quality) (quality, error) {
+	result := outer
+	for _, special := range []struct {
+		name  string
+		value *float64
+	}{{"$fa", &result.fa}, {"$fs", &result.fs}, {"$fn", &result.fn}} {
+		number, err := arguments.number(special.name, -1, *special.value)
+		if err != nil {
+			return quality{}, err
+		}
+		*special.value = number
+	}
+
+	return result, nil
+}
+
+func (arguments arguments) outline() ([]mgl64.Vec2, error) {
+	if _, ok := arguments.get("paths", -1); ok {
+		return nil, arguments.fail("paths", "are not supported")
+	}
+	value, ok := arguments.get("points", 0)
+	if !ok {
+		return nil, nil
+	}
+	points, ok := value.([]any)
+	if !ok {
+		return nil, arguments.fail("points", "must be a list, got %v", value)
+	}
+
+	outline := make([]mgl64.Vec2, 0, len(points))
+	for _, point := range points {
+		vector, err := arguments.toVector("points", point, mgl64.Vec3{})
+		if err != nil {
+			return nil, err
+		}
+		outline = append(outline, vector.Vec2())
+	}
+
+	return outline, nil
+}
+
+// transform returns the matrix of a transformation module.
+func (arguments arguments) transform(name string) (mgl64.Mat4, error) {
+	switch name {
+	case "translate":
+		vector, err := arguments.vector("v", 0, mgl64.Vec3{})
+
+		return mgl64.Translate3D(vector.X(), vector.Y(), vector.Z()), err
+	case "scale":
+		vector, err := arguments.vector("v", 0, mgl64.Vec3{1, 1, 1})
+
+		return mgl64.Scale3D(vector.X(), vector.Y(), vector.Z()), err
+	case "rotate":
+		return arguments.rotation()
+	case "mirror":
+		normal, err := arguments.vector("v", 0, mgl64.Vec3{1, 0, 0})
+		if err != nil || normal.Len() < parallelEpsilon {
+			return mgl64.Ident4(), err
+		}
+		normal = normal.Normalize()
+		reflection := mgl64.Ident3().Sub(mgl64.Mat3{
+			normal[0] * normal[0], normal[1] * normal[0], normal[2] * normal[0],
+			normal[0] * normal[1], normal[1] * normal[1], normal[2] * normal[1],
+			normal[0] * normal[2], normal[1] * normal[2], normal[2] * normal[2],
+		}.Mul(2))
+
+		return reflection.Mat4(), nil
+	case "multmatrix":
+		return arguments.matrix()
+	default:
+		panic("unknown transform " + name)
+	}
+}
+
+func (arguments arguments) rotation() (mgl64.Mat4, error) {
+	angle, ok := arguments.get("a", 0)
+	if !ok {
+		return mgl64.Ident4(), nil
+	}
+
+	if axis, ok := arguments.get("v", 1); ok {
+		degrees, ok := angle.(float64)
+		if !ok {
+			return mgl64.Mat4{}, arguments.fail("a", "must be a number if v is given, got %v", angle)
+		}
+		vector, err := arguments.toVector("v", axis, mgl64.Vec3{})
+		if err != nil || vector.Len() < parallelEpsilon {
+			return mgl64.Ident4(), err
+		}
+
+		return mgl64.HomogRotate3D(mgl64.DegToRad(degrees), vector.Normalize()), nil
+	}
+
+	if degrees, ok := angle.(float64); ok {
+		return mgl64.HomogRotate3DZ(mgl64.DegToRad(degrees)), nil
+	}
+	angles, err := arguments.toVector("a", angle, mgl64.Vec3{})
+	if err != nil {
+		return mgl64.Mat4{}, err
+	}
+
+	return mgl64.HomogRotate3DZ(mgl64.DegToRad(angles.Z())).
+		Mul4(mgl64.HomogRotate3DY(mgl64.DegToRad(angles.Y()))).
+		Mul4(mgl64.HomogRotate3DX(mgl64.DegToRad(angles.X()))), nil
+}
+
+func (arguments arguments) matrix() (mgl64.Mat4, error) {
+	value, ok := arguments.get("m", 0)
+	if !ok {
+		return mgl64.Ident4(), nil
+	}
+	rows, ok := value.([]any)
+	if !ok || len(rows) < 3 || len(rows) > 4 {
+		return mgl64.Mat4{}, arguments.fail("m", "must be a list of 3 or 4 rows, got %v", value)
+	}
+
+	matrix := mgl64.Ident4()
+	for i, row := range rows {
+		columns, ok := row.([]any)
+		if !ok || len(columns) != 4 {
+			return mgl64.Mat4{}, arguments.fail("m", "must have rows of 4 numbers, got %v", row)
+		}
+		for j, column := range columns {
+			number, ok := column.(float64)
+			if !ok {
+				return mgl64.Mat4{}, arguments.fail("m", "must consist of numbers, got %v", column)
+			}
+			matrix.Set(i, j, number)
+		}
+	}
+	if math.Abs(matrix.Row(3).Sub(mgl64.Vec4{0, 0, 0, 1}).Len()) > parallelEpsilon {
+		return mgl64.Mat4{}, arguments.fail("m", "must not be a projection")
+	}
+
+	return matrix, nil
+}
@@ -0,0 +1,148 @@
+package mesh
+
+import (
+	"bufio"
+	"bytes"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/ljanyst/ghostscad/primitive"
+
+	"github.com/yeldiRium/3d-rack-brackets/internal/ghostscad"
+)
+
+// Hole is a cylinder that is subtracted from a shape, e.g. a screw hole.
+type Hole struct {
+	// Center is the middle of the hole's axis.
+	Center mgl64.Vec3
+
+	// Axis is the direction of the hole's axis, with unit length.
+	Axis mgl64.Vec3
+
+	// Diameter is the hole's narrowest diameter, e.g. that of a countersunk
+	// hole's bore.
+	Diameter float64
+
+	Depth float64
+}
+
+// HolesFromSCAD lists the holes in OpenSCAD code, see FromSCAD for what is
+// understood. Holes are the cylinders that are subtracted in a difference.
+// Cones that end in a point and other shapes that are subtracted, like
+// polygonal pockets, are not holes.
+func HolesFromSCAD(source string) ([]Hole, error) {
+	statements, err := parseSCAD(source)
+	if err != nil {
+		return nil, err
+	}
+
+	evaluator := &evaluator{quality: defaultQuality}
+
+	return evaluator.holes(statements, mgl64.Ident4(), false)
+}
+
+// PrimitiveHoles lists the holes in a primitive, see HolesFromSCAD.
+func PrimitiveHoles(item primitive.Primitive) ([]Hole, error) {
+	var source bytes.Buffer
+	writer := bufio.NewWriter(&source)
+	ghostscad.RenderGlobals(writer)
+	item.Render(writer)
+	if err := writer.Flush(); err != nil {
+		return nil, err
+	}
+
+	return HolesFromSCAD(source.String())
+}
+
+// holes lists the holes in a block, whose shapes are placed by transform.
+// subtracted is whether the block is subtracted from a shape, so that its
+// cylinders are holes.
+func (evaluator *evaluator) holes(statements []*statement, transform mgl64.Mat4, subtracted bool) ([]Hole, error) {
+	var result []Hole
+	for _, statement := range statements {
+		holes, err := evaluator.statementHoles(statement, transform, subtracted)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, holes...)
+	}
+
+	return result, nil
+}
+
+func (evaluator *evaluator) statementHoles(statement *statement, transform mgl64.Mat4, subtracted bool) ([]Hole, error) {
+	if statement.modifier == '%' || statement.modifier == '*' {
+		return nil, nil
+	}
+	if statement.isAssignment() {
+		return nil, evaluator.assign(statement)
+	}
+
+	arguments, err := newArguments(statement)
+	if err != nil {
+		return nil, err
+	}
+
+	switch statement.name {
+	case "", "union", "intersection":
+		return evaluator.holes(statement.children, transform, subtracted)
+	case "difference":
+		var result []Hole
+		first := true
+		for _, child := range statement.children {
+			// What is subtracted from a hole fills it, so it is no hole.
+			holes, err := evaluator.statementHoles(child, transform, subtracted != !first)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, holes...)
+			if child.modifier != '%' && child.modifier != '*' && !child.isAssignment() {
+				first = false
+			}
+		}
+
+		return result, nil
+	case "translate", "rotate", "mirror", "multmatrix", "scale":
+		childTransform, err := arguments.transform(statement.name)
+		if err != nil {
+			return nil, err
+		}
+
+		return evaluator.holes(statement.children, transform.Mul4(childTransform), subtracted)
+	case "cylinder":
+		if !subtracted {
+			return nil, nil
+		}
+
+		return cylinderHole(arguments, transform)
+	default:
+		// Extrusions and other shapes are not holes.
+		return nil, nil
+	}
+}
+
+// cylinderHole returns the hole that a subtracted cylinder cuts, if any.
+func cylinderHole(arguments arguments, transform mgl64.Mat4) ([]Hole, error) {
+	cylinder, err := arguments.cylinder()
+	if err != nil {
+		return nil, err
+	}
+
+	narrowest := min(cylinder.bottomRadius, cylinder.topRadius)
+	if narrowest <= 0 || cylinder.height <= 0 {
+		return nil, nil
+	}
+
+	middle := mgl64.Vec3{0, 0, cylinder.height / 2}
+	if cylinder.center {
+		middle = mgl64.Vec3{}
+	}
+	axis := mgl64.TransformNormal(mgl64.Vec3{0, 0, 1}, transform)
+	radial := mgl64.TransformNormal(mgl64.Vec3{narrowest, 0, 0}, transform)
+
+	return []Hole{{
+		Center:   mgl64.TransformCoordinate(middle, transform),
+		Axis:     axis.Normalize(),
+		Diameter: 2 * radial.Len(),
+		Depth:    cylinder.height * axis.Len(),
+	}}, nil
+}
@@ -0,0 +1,168 @@
+// Package mesh evaluates the OpenSCAD code generated by GhostSCAD into
+// triangle meshes, so that models can be exported without OpenSCAD.
+//
+// The boolean operations are implemented with binary space partitioning
+// trees. They split polygons without splitting their neighbours, so their
+// results are stitched together before they are split into triangles, which
+// makes every edge of the mesh shared by exactly the two triangles beside it.
+package mesh
+
+import (
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/ljanyst/ghostscad/primitive"
+
+	"github.com/yeldiRium/3d-rack-brackets/internal/ghostscad"
+)
+
+// Triangle is a face of a mesh. Its vertices are ordered counter-clockwise
+// when seen from outside.
+type Triangle [3]mgl64.Vec3
+
+// Normal returns the outward unit normal of the triangle, or the zero vector
+// for degenerate triangles.
+func (triangle Triangle) Normal() mgl64.Vec3 {
+	normal := triangle[1].Sub(triangle[0]).Cross(triangle[2].Sub(triangle[0]))
+	if normal.Len() < parallelEpsilon {
+		return mgl64.Vec3{}
+	}
+
+	return normal.Normalize()
+}
+
+// Area returns the area of the triangle in square millimetres.
+func (triangle Triangle) Area() float64 {
+	return triangle[1].Sub(triangle[0]).Cross(triangle[2].Sub(triangle[0])).Len() / 2
+}
+
+// Mesh is a closed surface made of triangles.
+type Mesh struct {
+	Triangles []Triangle
+}
+
+// FromSCAD evaluates OpenSCAD code into a mesh. Only the subset of OpenSCAD
+// that GhostSCAD generates for the rack is understood, everything else fails
+// with ErrUnsupported.
+func FromSCAD(source string) (*Mesh, error) {
+	statements, err := parseSCAD(source)
+	if err != nil {
+		return nil, err
+	}
+
+	evaluator := &evaluator{quality: defaultQuality}
+	solid, err := evaluator.evaluate(statements, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return fromSolid(solid), nil
+}
+
+// FromPrimitive evaluates a primitive into a mesh. Circles are approximated
+// like the OpenSCAD code would, using the fragment settings of the ghostscad
+// package. The primitive is evaluated directly instead of the OpenSCAD code it
+// renders, which GhostSCAD rounds to six decimals.
+func FromPrimitive(item primitive.Primitive) (*Mesh, error) {
+	root, err := statementOf(item)
+	if err != nil {
+		return nil, err
+	}
+
+	evaluator := &evaluator{quality: globalQuality()}
+	solid, err := evaluator.statement(root, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return fromSolid(solid), nil
+}
+
+// globalQuality returns the fragment settings of the ghostscad package.
+func globalQuality() quality {
+	fa, fs, fn := ghostscad.Fragments()
+
+	return quality{fa: fa, fs: fs, fn: float64(fn)}
+}
+
+// fromSolid stitches the convex polygons of the solid together and splits
+// them into triangles.
+func fromSolid(solid solid) *Mesh {
+	mesh := &Mesh{}
+	for _, outline := range stitch(solid) {
+		mesh.Triangles = append(mesh.Triangles, fan(outline)...)
+	}
+
+	return mesh
+}
+
+// Volume returns the volume enclosed by the mesh in cubic millimetres.
+func (mesh *Mesh) Volume() float64 {
+	volume := 0.0
+	for _, triangle := range mesh.Triangles {
+		volume += triangle[0].Dot(triangle[1].Cross(triangle[2]))
+	}
+
+	return volume / 6
+}
+
+// Area returns the surface area of the mesh in square millimetres.
+func (mesh *Mesh) Area() float64 {
+	area := 0.0
+	for _, triangle := range mesh.Triangles {
+		area += triangle.Area()
+	}
+
+	return area
+}
+
+// Bounds returns the bounding box of the mesh.
+func (mesh *Mesh) Bounds() Box {
+	box := EmptyBox()
+	for _, triangle := range mesh.Triangles {
+		for _, vertex := range triangle {
+			box = box.Extend(vertex)
+		}
+	}
+
+	return box
+}
+
+// Transformed returns a copy of the mesh with transform applied to every
+// vertex. Transforms that mirror the mesh keep its faces pointing outwards.
+func (mesh *Mesh) Transformed(transform mgl64.Mat4) *Mesh {
+	mirrors := transform.Mat3().Det() < 0
+
+	result := &Mesh{Triangles: make([]Triangle, 0, len(mesh.Triangles))}
+	for _, triangle := range mesh.Triangles {
+		transformed := Triangle{}
+		for i, vertex := range triangle {
+			transformed[i] = mgl64.TransformCoordinate(vertex, transform)
+		}
+		if mirrors {
+			transformed[1], transformed[2] = transformed[2], transformed[1]
+		}
+		result.Triangles = append(result.Triangles, transformed)
+	}
+
+	return result
+}
+
+// OpenEdges returns the number of directed edges of the mesh that lack an
+// opposite edge in a neighbouring triangle. A mesh is closed and can be
+// exported if it has none.
+func (mesh *Mesh) OpenEdges() int {
+	balance := map[[2]mgl64.Vec3]int{}
+	for _, triangle := range mesh.Triangles {
+		for i, from := range triangle {
+			to := triangle[(i+1)%3]
+			balance[[2]mgl64.Vec3{from, to}]++
+			balance[[2]mgl64.Vec3{to, from}]--
+		}
+	}
+
+	open := 0
+	for _, count := range balance {
+		open += max(count, 0)
+	}
+
+	return open
+}
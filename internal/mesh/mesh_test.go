@@ -0,0 +1,425 @@
+package mesh
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/ljanyst/ghostscad/primitive"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromSCAD(t *testing.T) {
+	t.Parallel()
+
+	t.Run("evaluates cubes.", func(t *testing.T) {
+		t.Parallel()
+
+		mesh, err := FromSCAD("translate([5, 0, 0]) cube([2, 3, 4], center=true);")
+		require.NoError(t, err)
+
+		assert.InDelta(t, 24, mesh.Volume(), 1e-9)
+		assert.InDelta(t, 52, mesh.Area(), 1e-9)
+		assert.Len(t, mesh.Triangles, 12)
+	})
+
+	t.Run("approximates cylinders like OpenSCAD.", func(t *testing.T) {
+		t.Parallel()
+
+		mesh, err := FromSCAD("$fn=6;\ncylinder(h=2, r1=1, r2=1, center=true);")
+		require.NoError(t, err)
+
+		// A hexagon with circumradius 1 has an area of 3*sqrt(3)/2.
+		assert.InDelta(t, 3*math.Sqrt(3), mesh.Volume(), 1e-9)
+	})
+
+	t.Run("subtracts holes.", func(t *testing.T) {
+		t.Parallel()
+
+		mesh, err := FromSCAD(`difference() {
+cube([10, 10, 2], center=true);
+cube([2, 2, 4], center=true);
+translate([5, 5, 0]) cube([2, 2, 4], center=true);
+}`)
+		require.NoError(t, err)
+
+		// The second hole only reaches one quarter into the plate.
+		assert.InDelta(t, 200-8-2, mesh.Volume(), 1e-9)
+	})
+
+	t.Run("unions overlapping solids.", func(t *testing.T) {
+		t.Parallel()
+
+		mesh, err := FromSCAD(`union() {
+cube([2, 2, 2]);
+translate([1, 0, 0]) cube([2, 2, 2]);
+}`)
+		require.NoError(t, err)
+
+		assert.InDelta(t, 12, mesh.Volume(), 1e-9)
+	})
+
+	t.Run("closes the T-junctions of boolean operations.", func(t *testing.T) {
+		t.Parallel()
+
+		mesh, err := FromSCAD(`difference() {
+union() {
+cube([4, 4, 2]);
+translate([1, 1, 2]) cube([2, 2, 2]);
+}
+translate([2, -1, 1]) cube([1, 6, 4]);
+}`)
+		require.NoError(t, err)
+
+		assert.InDelta(t, 32+8-4-4, mesh.Volume(), 1e-9)
+		assert.Zero(t, mesh.OpenEdges())
+	})
+
+	t.Run("extrudes polygons.", func(t *testing.T) {
+		t.Parallel()
+
+		mesh, err := FromSCAD("rotate([90, 0, 0]) linear_extrude(height=3, center=true, convexity=10, twist=0.000000, slices=1, scale=1.000000){\npolygon(points=[[0, 0],[4, 0],[0, 4]]);\n}\n")
+		require.NoError(t, err)
+
+		assert.InDelta(t, 24, mesh.Volume(), 1e-9)
+	})
+
+	t.Run("keeps solids inside out when mirrored.", func(t *testing.T) {
+		t.Parallel()
+
+		mesh, err := FromSCAD("mirror([1, 0, 0]) multmatrix([[1, 0, 0, 3],[0, 1, 0, 0],[0, 0, 1, 0],[0, 0, 0, 1]]) cube([1, 2, 3]);")
+		require.NoError(t, err)
+
+		assert.InDelta(t, 6, mesh.Volume(), 1e-9)
+		for _, triangle := range mesh.Triangles {
+			assert.Less(t, triangle[0].X(), -2.9)
+		}
+	})
+
+	t.Run("skips background and disabled objects.", func(t *testing.T) {
+		t.Parallel()
+
+		mesh, err := FromSCAD("cube(1);\n%cube(5);\n*cube(5);\n#cube(1);")
+		require.NoError(t, err)
+
+		assert.InDelta(t, 1, mesh.Volume(), 1e-9)
+	})
+
+	t.Run("rejects unsupported modules.", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := FromSCAD(`cube(1);
+text("anchor");`)
+
+		require.ErrorIs(t, err, ErrUnsupported)
+		assert.ErrorContains(t, err, "line 2, column 1: the module text")
+	})
+
+	t.Run("reports syntax errors with their position.", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := FromSCAD("cube([1, 2, 3]) ;\ncube(1")
+
+		var syntaxError *SyntaxError
+		require.ErrorAs(t, err, &syntaxError)
+		assert.Equal(t, 2, syntaxError.Line)
+	})
+}
+
+func TestBounds(t *testing.T) {
+	t.Parallel()
+
+	t.Run("places shapes by their transforms.", func(t *testing.T) {
+		t.Parallel()
+
+		box, err := Bounds(
+			primitive.NewRotation(mgl64.Vec3{0, 0, 90}, primitive.NewCube(mgl64.Vec3{2, 3, 4}).SetCenter(false)),
+			mgl64.Translate3D(5, 0, 0),
+		)
+		require.NoError(t, err)
+
+		assert.InDeltaSlice(t, []float64{2, 0, 0}, box.Min[:], 1e-9)
+		assert.InDeltaSlice(t, []float64{5, 2, 4}, box.Max[:], 1e-9)
+	})
+
+	t.Run("measures what is left of a difference.", func(t *testing.T) {
+		t.Parallel()
+
+		box, err := Bounds(primitive.NewDifference(
+			primitive.NewCube(mgl64.Vec3{10, 10, 10}).SetCenter(false),
+			primitive.NewTranslation(mgl64.Vec3{0, 5, -5}, primitive.NewCube(mgl64.Vec3{10, 10, 20}).SetCenter(false)),
+		), mgl64.Ident4())
+		require.NoError(t, err)
+
+		assert.InDeltaSlice(t, []float64{0, 0, 0}, box.Min[:], 1e-9)
+		assert.InDeltaSlice(t, []float64{10, 5, 10}, box.Max[:], 1e-9)
+	})
+
+	t.Run("measures what is shared by an intersection.", func(t *testing.T) {
+		t.Parallel()
+
+		box, err := Bounds(primitive.NewIntersection(
+			primitive.NewCube(mgl64.Vec3{10, 10, 10}).SetCenter(false),
+			primitive.NewTranslation(mgl64.Vec3{5, 5, 5}, primitive.NewCube(mgl64.Vec3{10, 10, 10}).SetCenter(false)),
+		), mgl64.Ident4())
+		require.NoError(t, err)
+
+		assert.InDeltaSlice(t, []float64{5, 5, 5}, box.Min[:], 1e-9)
+		assert.InDeltaSlice(t, []float64{10, 10, 10}, box.Max[:], 1e-9)
+	})
+
+	t.Run("matches the bounds of the mesh.", func(t *testing.T) {
+		t.Parallel()
+
+		cylinder := primitive.NewCylinder(2, 3)
+		cylinder.Circular.SetFn(12)
+		item := primitive.NewList(
+			cylinder,
+			primitive.NewLinearExtrusion(4, primitive.NewPolygon([]mgl64.Vec2{{0, 0}, {5, 0}, {0, 6}})).SetCenter(true),
+		)
+		box, err := Bounds(item, mgl64.HomogRotate3DZ(0.5))
+		require.NoError(t, err)
+		mesh, err := FromPrimitive(item)
+		require.NoError(t, err)
+
+		expected := mesh.Transformed(mgl64.HomogRotate3DZ(0.5)).Bounds()
+		assert.InDeltaSlice(t, expected.Min[:], box.Min[:], 1e-9)
+		assert.InDeltaSlice(t, expected.Max[:], box.Max[:], 1e-9)
+	})
+
+	t.Run("ignores disabled and background shapes.", func(t *testing.T) {
+		t.Parallel()
+
+		box, err := Bounds(primitive.NewList(
+			primitive.NewCube(mgl64.Vec3{1, 1, 1}).SetCenter(false),
+			primitive.NewCube(mgl64.Vec3{5, 5, 5}).Transparent(),
+			primitive.NewCube(mgl64.Vec3{5, 5, 5}).Disable(),
+		), mgl64.Ident4())
+		require.NoError(t, err)
+
+		assert.Equal(t, Box{Max: mgl64.Vec3{1, 1, 1}}, box)
+	})
+}
+
+func TestHolesFromSCAD(t *testing.T) {
+	t.Parallel()
+
+	t.Run("lists the cylinders that are subtracted.", func(t *testing.T) {
+		t.Parallel()
+
+		holes, err := HolesFromSCAD(`difference() {
+cube(20);
+cylinder(h=4, r1=2, r2=2);
+translate([10, 0, 5]) rotate([-90, 0, 0]) cylinder(h=20, r1=1.5, r2=3, center=true);
+}
+cylinder(h=2, r1=1, r2=1);`)
+		require.NoError(t, err)
+
+		require.Len(t, holes, 2)
+		assert.Equal(t, Hole{Center: mgl64.Vec3{0, 0, 2}, Axis: mgl64.Vec3{0, 0, 1}, Diameter: 4, Depth: 4}, holes[0])
+		assert.InDeltaSlice(t, []float64{10, 0, 5}, holes[1].Center[:], 1e-9)
+		assert.InDeltaSlice(t, []float64{0, 1, 0}, holes[1].Axis[:], 1e-9)
+		assert.InDelta(t, 3, holes[1].Diameter, 1e-9)
+	})
+
+	t.Run("ignores what is subtracted from holes and cones.", func(t *testing.T) {
+		t.Parallel()
+
+		holes, err := HolesFromSCAD(`difference() {
+cube(20);
+difference() { cylinder(h=4, r1=5, r2=5); cylinder(h=4, r1=1, r2=1); }
+cylinder(h=4, r1=2, r2=0);
+}`)
+		require.NoError(t, err)
+
+		require.Len(t, holes, 1)
+		assert.InDelta(t, 10, holes[0].Diameter, 1e-9)
+	})
+}
+
+func TestBox(t *testing.T) {
+	t.Parallel()
+
+	t.Run("is the identity of union when empty.", func(t *testing.T) {
+		t.Parallel()
+
+		box := Box{Min: mgl64.Vec3{1, 2, 3}, Max: mgl64.Vec3{4, 5, 6}}
+
+		assert.True(t, EmptyBox().IsEmpty())
+		assert.Equal(t, box, EmptyBox().Union(box))
+		assert.Equal(t, box, box.Union(EmptyBox()))
+		assert.Equal(t, mgl64.Vec3{}, EmptyBox().Size())
+	})
+
+	t.Run("is empty if the boxes do not intersect.", func(t *testing.T) {
+		t.Parallel()
+
+		box := Box{Max: mgl64.Vec3{1, 1, 1}}
+		other := Box{Min: mgl64.Vec3{2, 0, 0}, Max: mgl64.Vec3{3, 1, 1}}
+
+		assert.True(t, box.Intersection(other).IsEmpty())
+	})
+
+	t.Run("contains the transformed box.", func(t *testing.T) {
+		t.Parallel()
+
+		box := Box{Max: mgl64.Vec3{2, 1, 1}}.Transformed(mgl64.HomogRotate3DZ(math.Pi / 2))
+
+		assert.InDeltaSlice(t, []float64{-1, 0, 0}, box.Min[:], 1e-9)
+		assert.InDeltaSlice(t, []float64{0, 2, 1}, box.Max[:], 1e-9)
+	})
+
+	t.Run("fits volumes that are at least as large.", func(t *testing.T) {
+		t.Parallel()
+
+		box := Box{Min: mgl64.Vec3{-1, -1, 0}, Max: mgl64.Vec3{1, 1, 3}}
+
+		assert.True(t, box.Fits(mgl64.Vec3{2, 2, 3}))
+		assert.False(t, box.Fits(mgl64.Vec3{2, 2, 2.9}))
+	})
+}
+
+func TestMeshOpenEdges(t *testing.T) {
+	t.Parallel()
+
+	t.Run("is zero for closed meshes.", func(t *testing.T) {
+		t.Parallel()
+
+		mesh, err := FromSCAD("cube([1, 2, 3]);")
+		require.NoError(t, err)
+
+		assert.Zero(t, mesh.OpenEdges())
+	})
+
+	t.Run("counts the edges without an opposite edge.", func(t *testing.T) {
+		t.Parallel()
+
+		mesh := &Mesh{Triangles: []Triangle{
+			{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}},
+			{{1, 0, 0}, {1, 1, 0}, {0, 1, 0}},
+		}}
+
+		// The two triangles share the diagonal.
+		assert.Equal(t, 4, mesh.OpenEdges())
+	})
+}
+
+func TestMeshWriteSTL(t *testing.T) {
+	t.Parallel()
+
+	mesh := &Mesh{Triangles: []Triangle{{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}}}}
+
+	t.Run("writes binary STL.", func(t *testing.T) {
+		t.Parallel()
+
+		var buffer bytes.Buffer
+		require.NoError(t, mesh.WriteSTL(&buffer, "rail-0"))
+
+		data := buffer.Bytes()
+		require.Len(t, data, 80+4+50)
+		assert.Equal(t, "rail-0", strings.TrimRight(string(data[:80]), "\x00"))
+		assert.Equal(t, uint32(1), binary.LittleEndian.Uint32(data[80:]))
+
+		var record [12]float32
+		require.NoError(t, binary.Read(bytes.NewReader(data[84:132]), binary.LittleEndian, &record))
+		assert.Equal(t, [12]float32{0, 0, 1, 0, 0, 0, 1, 0, 0, 0, 1, 0}, record)
+	})
+
+	t.Run("writes ASCII STL.", func(t *testing.T) {
+		t.Parallel()
+
+		var buffer bytes.Buffer
+		require.NoError(t, mesh.WriteASCIISTL(&buffer, "foot"))
+
+		assert.True(t, strings.HasPrefix(buffer.String(), "solid foot\n  facet normal 0.000000e+00 0.000000e+00 1.000000e+00\n"))
+		assert.True(t, strings.HasSuffix(buffer.String(), "endsolid foot\n"))
+		assert.Equal(t, mgl64.Vec3{0, 0, 1}, mesh.Triangles[0].Normal())
+	})
+}
+
+func TestWrite3MF(t *testing.T) {
+	t.Parallel()
+
+	t.Run("writes every object with its metadata.", func(t *testing.T) {
+		t.Parallel()
+
+		cube, err := FromSCAD("cube([1, 2, 3]);")
+		require.NoError(t, err)
+
+		var buffer bytes.Buffer
+		require.NoError(t, Write3MF(&buffer, []Object{{
+			Name:      "foot & rail",
+			Mesh:      cube,
+			Transform: mgl64.Translate3D(1, 2, 3).Mul4(mgl64.HomogRotate3DX(mgl64.DegToRad(-90))),
+			Metadata:  map[string]string{"rack:material": "PETG"},
+		}}, map[string]string{"Title": "rack"}))
+
+		archive, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+		require.NoError(t, err)
+
+		names := make([]string, 0, len(archive.File))
+		for _, file := range archive.File {
+			names = append(names, file.Name)
+		}
+		assert.ElementsMatch(t, []string{"[Content_Types].xml", "_rels/.rels", "3D/3dmodel.model"}, names)
+
+		file, err := archive.Open("3D/3dmodel.model")
+		require.NoError(t, err)
+		var model struct {
+			Unit     string `xml:"unit,attr"`
+			Metadata []struct {
+				Name  string `xml:"name,attr"`
+				Value string `xml:",chardata"`
+			} `xml:"metadata"`
+			Objects []struct {
+				Name     string `xml:"name,attr"`
+				Metadata []struct {
+					Name  string `xml:"name,attr"`
+					Value string `xml:",chardata"`
+				} `xml:"metadatagroup>metadata"`
+				Vertices  []struct{} `xml:"mesh>vertices>vertex"`
+				Triangles []struct{} `xml:"mesh>triangles>triangle"`
+			} `xml:"resources>object"`
+			Items []struct {
+				Transform string `xml:"transform,attr"`
+			} `xml:"build>item"`
+		}
+		require.NoError(t, xml.NewDecoder(file).Decode(&model))
+
+		assert.Equal(t, "millimeter", model.Unit)
+		require.Len(t, model.Metadata, 1)
+		assert.Equal(t, "rack", model.Metadata[0].Value)
+
+		require.Len(t, model.Objects, 1)
+		assert.Equal(t, "foot & rail", model.Objects[0].Name)
+		require.Len(t, model.Objects[0].Metadata, 1)
+		assert.Equal(t, "rack:material", model.Objects[0].Metadata[0].Name)
+		assert.Equal(t, "PETG", model.Objects[0].Metadata[0].Value)
+		// The triangles share the corners of the cube.
+		assert.Len(t, model.Objects[0].Vertices, 8)
+		assert.Len(t, model.Objects[0].Triangles, 12)
+
+		require.Len(t, model.Items, 1)
+		assert.Equal(t, "1 0 0 0 0 -1 0 1 0 1 2 3", model.Items[0].Transform)
+	})
+
+	t.Run("rejects open meshes.", func(t *testing.T) {
+		t.Parallel()
+
+		cube, err := FromSCAD("cube([1, 2, 3]);")
+		require.NoError(t, err)
+		cube.Triangles = cube.Triangles[1:]
+
+		var buffer bytes.Buffer
+		err = Write3MF(&buffer, []Object{{Name: "rail", Mesh: cube, Transform: mgl64.Ident4()}}, nil)
+		require.ErrorIs(t, err, ErrOpenMesh)
+		assert.ErrorContains(t, err, "rail has 3 edges without an opposite edge")
+		assert.Zero(t, buffer.Len())
+	})
+}
@@ -0,0 +1,184 @@
+package mesh
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/ljanyst/ghostscad/primitive"
+
+	"github.com/yeldiRium/3d-rack-brackets/internal/ghostscad"
+)
+
+// Expander is implemented by primitives that consist of other primitives,
+// like the parts of the rack. They are evaluated as the primitive they expand
+// to, which must not carry their modifier.
+type Expander interface {
+	Expand() (primitive.Primitive, error)
+}
+
+// statementOf converts a primitive into the statement that it would render
+// to, but keeps the full precision of its numbers instead of rounding them to
+// six decimals like GhostSCAD does.
+func statementOf(item primitive.Primitive) (*statement, error) {
+	result := &statement{}
+	if prefix := []rune(item.Prefix()); len(prefix) > 0 {
+		result.modifier = prefix[0]
+	}
+
+	var children []primitive.Primitive
+	switch item := item.(type) {
+	case *primitive.List:
+		children = item.Items
+	case *primitive.Nothing:
+	case *primitive.ListOp:
+		// GhostSCAD does not export the name of the operation.
+		result.name = reflect.ValueOf(item).Elem().FieldByName("name").String()
+		children = item.Items.Items
+	case *primitive.Transform:
+		return transformStatement(result, item)
+	case *primitive.Mirror:
+		result.name = "mirror"
+		result.arguments = []argument{{value: vectorValue(item.Mirror[:]...)}}
+		children = item.Items.Items
+	case *ghostscad.MultMatrix:
+		rows := make([]any, 0, 4)
+		for row := range 4 {
+			vector := item.Matrix.Row(row)
+			rows = append(rows, vectorValue(vector[:]...))
+		}
+		result.name = "multmatrix"
+		result.arguments = []argument{{value: rows}}
+		children = item.Items.Items
+	case *primitive.LinearExtrusion:
+		result.name = "linear_extrude"
+		result.arguments = []argument{
+			{name: "height", value: item.Height},
+			{name: "center", value: item.Center},
+			{name: "twist", value: float64(item.Twist)},
+			{name: "scale", value: item.Scale},
+		}
+		children = item.Items.Items
+	case *primitive.Cube:
+		result.name = "cube"
+		result.arguments = []argument{{value: vectorValue(item.Dims[:]...)}, {name: "center", value: item.Center}}
+	case *primitive.Cylinder:
+		result.name = "cylinder"
+		result.arguments = append([]argument{
+			{name: "h", value: item.H},
+			{name: "r1", value: item.RBottom},
+			{name: "r2", value: item.RTop},
+			{name: "center", value: item.Center},
+		}, circularArguments(item.Circular)...)
+	case *primitive.Square:
+		result.name = "square"
+		result.arguments = []argument{{value: vectorValue(item.Dims[:]...)}, {name: "center", value: item.Center}}
+	case *primitive.Polygon:
+		points := make([]any, 0, len(item.Points))
+		for _, point := range item.Points {
+			points = append(points, vectorValue(point[:]...))
+		}
+		result.name = "polygon"
+		result.arguments = []argument{{name: "points", value: points}}
+		if len(item.Paths) > 0 {
+			// Paths are not supported, which the evaluator reports.
+			result.arguments = append(result.arguments, argument{name: "paths", value: []any{}})
+		}
+	case Expander:
+		expanded, err := item.Expand()
+		if err != nil {
+			return nil, err
+		}
+		children = []primitive.Primitive{expanded}
+	default:
+		return nil, fmt.Errorf("%w: the primitive %T", ErrUnsupported, item)
+	}
+
+	for _, child := range children {
+		childStatement, err := statementOf(child)
+		if err != nil {
+			return nil, err
+		}
+		result.children = append(result.children, childStatement)
+	}
+
+	return result, nil
+}
+
+// transformStatement converts the chain of translations and rotations of a
+// transform into nested statements, the first of which is result.
+func transformStatement(result *statement, transform *primitive.Transform) (*statement, error) {
+	inner := &statement{}
+	for _, child := range transform.Items.Items {
+		childStatement, err := statementOf(child)
+		if err != nil {
+			return nil, err
+		}
+		inner.children = append(inner.children, childStatement)
+	}
+
+	// GhostSCAD does not export the transformations, so they are read the way
+	// Transform.Render reads them.
+	elements := reflect.ValueOf(transform).Elem().FieldByName("transforms")
+	for i := elements.Len() - 1; i >= 0; i-- {
+		element := elements.Index(i)
+		vector := reflectedVector(element.FieldByName("vector"))
+		angle := reflectedVector(element.FieldByName("angle"))
+
+		outer := &statement{children: []*statement{inner}}
+		switch name := element.FieldByName("name").String(); name {
+		case "translate":
+			outer.name = "translate"
+			outer.arguments = []argument{{value: vectorValue(vector[:]...)}}
+		case "rotate":
+			outer.name = "rotate"
+			outer.arguments = []argument{{value: vectorValue(angle[:]...)}}
+		case "rotateAngle":
+			outer.name = "rotate"
+			outer.arguments = []argument{{name: "a", value: angle.X()}, {name: "v", value: vectorValue(vector[:]...)}}
+		default:
+			return nil, fmt.Errorf("%w: the transformation %s", ErrUnsupported, name)
+		}
+		inner = outer
+	}
+
+	result.name = inner.name
+	result.arguments = inner.arguments
+	result.children = inner.children
+
+	return result, nil
+}
+
+func reflectedVector(value reflect.Value) mgl64.Vec3 {
+	return mgl64.Vec3{value.Index(0).Float(), value.Index(1).Float(), value.Index(2).Float()}
+}
+
+func vectorValue(coordinates ...float64) []any {
+	vector := make([]any, 0, len(coordinates))
+	for _, coordinate := range coordinates {
+		vector = append(vector, coordinate)
+	}
+
+	return vector
+}
+
+// circularArguments returns the fragment settings that were set on a circular
+// primitive.
+func circularArguments(circular *primitive.Circular) []argument {
+	if circular == nil {
+		return nil
+	}
+
+	var arguments []argument
+	if circular.FaSet {
+		arguments = append(arguments, argument{name: "$fa", value: circular.Fa})
+	}
+	if circular.FsSet {
+		arguments = append(arguments, argument{name: "$fs", value: circular.Fs})
+	}
+	if circular.FnSet {
+		arguments = append(arguments, argument{name: "$fn", value: float64(circular.Fn)})
+	}
+
+	return arguments
+}
@@ -0,0 +1,116 @@
+package mesh_test
+
+import (
+	"bufio"
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/ljanyst/ghostscad/primitive"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yeldiRium/3d-rack-brackets/internal/mesh"
+	"github.com/yeldiRium/3d-rack-brackets/internal/shapes"
+	"github.com/yeldiRium/3d-rack-brackets/internal/shapes/rack"
+)
+
+func TestFromPrimitive(t *testing.T) {
+	t.Parallel()
+
+	t.Run("evaluates primitives like the OpenSCAD code they render.", func(t *testing.T) {
+		t.Parallel()
+
+		cylinder := primitive.NewCylinder(4, 1)
+		cylinder.Circular.SetFn(12)
+		item := primitive.NewDifference(
+			primitive.NewRotation(mgl64.Vec3{30, 45, 60}, primitive.NewCube(mgl64.Vec3{4, 5, 6})),
+			primitive.NewTranslation(mgl64.Vec3{1, 0, 0}, cylinder),
+			primitive.NewMirror(mgl64.Vec3{0, 1, 0}, primitive.NewLinearExtrusion(3, primitive.NewPolygon([]mgl64.Vec2{{0, 0}, {3, 0}, {0, 3}}))),
+			primitive.NewRotationByAxis(45, mgl64.Vec3{1, 1, 0}, primitive.NewCube(mgl64.Vec3{1, 1, 8})).Disable(),
+		)
+
+		var source bytes.Buffer
+		writer := bufio.NewWriter(&source)
+		item.Render(writer)
+		require.NoError(t, writer.Flush())
+
+		fromSCAD, err := mesh.FromSCAD(source.String())
+		require.NoError(t, err)
+		fromPrimitive, err := mesh.FromPrimitive(item)
+		require.NoError(t, err)
+
+		assert.InDelta(t, fromSCAD.Volume(), fromPrimitive.Volume(), 1e-4)
+		assert.InDelta(t, fromSCAD.Area(), fromPrimitive.Area(), 1e-4)
+	})
+
+	t.Run("keeps the precision that OpenSCAD code would round away.", func(t *testing.T) {
+		t.Parallel()
+
+		partMesh, err := mesh.FromPrimitive(primitive.NewTranslation(mgl64.Vec3{1.0 / 3, 0, 0}, primitive.NewCube(mgl64.Vec3{1, 1, 1})))
+		require.NoError(t, err)
+
+		assert.InDelta(t, 1.0/3-0.5, partMesh.Bounds().Min.X(), 1e-10)
+	})
+
+	t.Run("rejects unsupported primitives.", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := mesh.FromPrimitive(primitive.NewSphere(1))
+		require.ErrorIs(t, err, mesh.ErrUnsupported)
+	})
+
+	t.Run("closes the meshes of every print part.", func(t *testing.T) {
+		t.Parallel()
+
+		for _, joint := range rack.AllJoints {
+			config := rack.DefaultConfig()
+			config.Units = 5
+			config.BuildVolume = mgl64.Vec3{150, 150, 150}
+			config.FootHoles = 2
+			config.SideBraceSides = rack.SidesBoth
+			config.Joint = joint
+			shape := rack.MakeRack(config)
+			require.NoError(t, shapes.ResolveAnchors(shape.Foot))
+
+			for _, part := range shape.PrintParts(rack.Parts...) {
+				item, err := part.Primitive()
+				require.NoError(t, err)
+				partMesh, err := mesh.FromPrimitive(item)
+				require.NoError(t, err, part.Name)
+
+				assert.Positive(t, partMesh.Volume(), part.Name)
+				// Every edge of a closed mesh is shared by two triangles that
+				// run along it in opposite directions.
+				assert.Zero(t, partMesh.OpenEdges(), "%s with %s joints", part.Name, joint)
+			}
+		}
+	})
+
+	t.Run("keeps the walls of side braces upright and mirrored exactly.", func(t *testing.T) {
+		t.Parallel()
+
+		shape := rack.MakeRack(rack.DefaultConfig())
+		require.NoError(t, shapes.ResolveAnchors(shape.Foot))
+
+		var areas []float64
+		for _, part := range shape.PrintParts(rack.PartSideBraces) {
+			item, err := part.Primitive()
+			require.NoError(t, err)
+			partMesh, err := mesh.FromPrimitive(item)
+			require.NoError(t, err, part.Name)
+
+			// The braces are printed flat, so their faces either lie on the
+			// plate, face up or stand upright.
+			for _, triangle := range partMesh.Triangles {
+				z := triangle.Normal().Z()
+				assert.InDelta(t, math.Round(z), z, 1e-12, "%s: %v", part.Name, triangle)
+			}
+			areas = append(areas, partMesh.Area())
+		}
+		for i := 0; i < len(areas); i += 2 {
+			assert.InDelta(t, areas[i], areas[i+1], 1e-9, "brace %d", i/2)
+		}
+	})
+}
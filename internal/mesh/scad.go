@@ -0,0 +1,314 @@
+package mesh
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// SyntaxError is returned for OpenSCAD code that the mesh backend cannot
+// parse. The backend only understands the subset of OpenSCAD that GhostSCAD
+// generates: module calls with literal arguments, blocks, modifiers and
+// assignments to special variables.
+type SyntaxError struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (err *SyntaxError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", err.Line, err.Column, err.Message)
+}
+
+// statement is a module call, a block or an assignment. Blocks have no name.
+type statement struct {
+	name      string
+	modifier  rune
+	arguments []argument
+	children  []*statement
+
+	// value is the value assigned by assignments, whose name starts with $.
+	value any
+
+	line, column int
+}
+
+// argument is a positional argument if name is empty. Values are float64,
+// bool, string or []any.
+type argument struct {
+	name  string
+	value any
+}
+
+func (statement *statement) isAssignment() bool {
+	return statement.value != nil
+}
+
+// location describes where the statement is in the source code for error
+// messages. It is empty for statements that were built from primitives.
+func (statement *statement) location() string {
+	if statement.line == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("line %d, column %d: ", statement.line, statement.column)
+}
+
+type parser struct {
+	source       []rune
+	position     int
+	line, column int
+}
+
+// parseSCAD parses a list of statements.
+func parseSCAD(source string) ([]*statement, error) {
+	parser := &parser{source: []rune(source), line: 1, column: 1}
+
+	statements, err := parser.statements()
+	if err != nil {
+		return nil, err
+	}
+	if !parser.atEnd() {
+		return nil, parser.fail("unexpected %q", parser.peek())
+	}
+
+	return statements, nil
+}
+
+func (parser *parser) fail(format string, args ...any) error {
+	return &SyntaxError{Line: parser.line, Column: parser.column, Message: fmt.Sprintf(format, args...)}
+}
+
+func (parser *parser) atEnd() bool {
+	parser.skipSpace()
+
+	return parser.position >= len(parser.source)
+}
+
+func (parser *parser) peek() rune {
+	parser.skipSpace()
+	if parser.position >= len(parser.source) {
+		return 0
+	}
+
+	return parser.source[parser.position]
+}
+
+func (parser *parser) advance() rune {
+	character := parser.source[parser.position]
+	parser.position++
+	if character == '\n' {
+		parser.line++
+		parser.column = 1
+	} else {
+		parser.column++
+	}
+
+	return character
+}
+
+func (parser *parser) skipSpace() {
+	for parser.position < len(parser.source) && unicode.IsSpace(parser.source[parser.position]) {
+		parser.advance()
+	}
+}
+
+func (parser *parser) expect(expected rune) error {
+	if found := parser.peek(); found != expected {
+		if found == 0 {
+			return parser.fail("expected %q, found the end of the code", expected)
+		}
+
+		return parser.fail("expected %q, found %q", expected, found)
+	}
+	parser.advance()
+
+	return nil
+}
+
+// statements parses statements until the end of the code or of the block.
+func (parser *parser) statements() ([]*statement, error) {
+	var statements []*statement
+	for !parser.atEnd() && parser.peek() != '}' {
+		if parser.peek() == ';' {
+			parser.advance()
+
+			continue
+		}
+		statement, err := parser.statement()
+		if err != nil {
+			return nil, err
+		}
+		statements = append(statements, statement)
+	}
+
+	return statements, nil
+}
+
+func (parser *parser) statement() (*statement, error) {
+	parser.skipSpace()
+	result := &statement{line: parser.line, column: parser.column}
+	if strings.ContainsRune("#%!*", parser.peek()) {
+		result.modifier = parser.advance()
+	}
+
+	if parser.peek() == '{' {
+		parser.advance()
+		children, err := parser.statements()
+		if err != nil {
+			return nil, err
+		}
+		result.children = children
+
+		return result, parser.expect('}')
+	}
+
+	name := parser.identifier()
+	if name == "" {
+		if parser.peek() == 0 {
+			return nil, parser.fail("expected a statement, found the end of the code")
+		}
+
+		return nil, parser.fail("expected a statement, found %q", parser.peek())
+	}
+	result.name = name
+
+	if strings.HasPrefix(name, "$") && parser.peek() == '=' {
+		parser.advance()
+		value, err := parser.value()
+		if err != nil {
+			return nil, err
+		}
+		result.value = value
+
+		return result, parser.expect(';')
+	}
+
+	if err := parser.expect('('); err != nil {
+		return nil, err
+	}
+	result.arguments = []argument{}
+	for parser.peek() != ')' {
+		argument, err := parser.argument()
+		if err != nil {
+			return nil, err
+		}
+		result.arguments = append(result.arguments, argument)
+		if parser.peek() != ',' {
+			break
+		}
+		parser.advance()
+	}
+	if err := parser.expect(')'); err != nil {
+		return nil, err
+	}
+
+	switch parser.peek() {
+	case ';':
+		parser.advance()
+	case 0:
+		return nil, parser.fail("expected %q, found the end of the code", ';')
+	default:
+		child, err := parser.statement()
+		if err != nil {
+			return nil, err
+		}
+		// The block of a module call holds its children, e.g. the operands of a
+		// difference.
+		if child.name == "" && child.modifier == 0 {
+			result.children = child.children
+		} else {
+			result.children = []*statement{child}
+		}
+	}
+
+	return result, nil
+}
+
+func (parser *parser) identifier() string {
+	parser.skipSpace()
+	start := parser.position
+	for parser.position < len(parser.source) {
+		character := parser.source[parser.position]
+		if !unicode.IsLetter(character) && !unicode.IsDigit(character) && character != '_' && character != '$' {
+			break
+		}
+		if parser.position == start && unicode.IsDigit(character) {
+			break
+		}
+		parser.advance()
+	}
+
+	return string(parser.source[start:parser.position])
+}
+
+func (parser *parser) argument() (argument, error) {
+	parser.skipSpace()
+	start, line, column := parser.position, parser.line, parser.column
+	if name := parser.identifier(); name != "" && name != "true" && name != "false" && parser.peek() == '=' {
+		parser.advance()
+		value, err := parser.value()
+
+		return argument{name: name, value: value}, err
+	}
+	parser.position, parser.line, parser.column = start, line, column
+
+	value, err := parser.value()
+
+	return argument{value: value}, err
+}
+
+func (parser *parser) value() (any, error) {
+	switch character := parser.peek(); {
+	case character == '[':
+		parser.advance()
+		values := []any{}
+		for parser.peek() != ']' {
+			value, err := parser.value()
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+			if parser.peek() != ',' {
+				break
+			}
+			parser.advance()
+		}
+
+		return values, parser.expect(']')
+	case character == '"':
+		parser.advance()
+		var builder strings.Builder
+		for parser.position < len(parser.source) && parser.source[parser.position] != '"' {
+			if parser.source[parser.position] == '\\' && parser.position+1 < len(parser.source) {
+				parser.advance()
+			}
+			builder.WriteRune(parser.advance())
+		}
+
+		return builder.String(), parser.expect('"')
+	case character == '-' || character == '+' || character == '.' || unicode.IsDigit(character):
+		start := parser.position
+		for parser.position < len(parser.source) && strings.ContainsRune("+-.0123456789eE", parser.source[parser.position]) {
+			parser.advance()
+		}
+		number, err := strconv.ParseFloat(string(parser.source[start:parser.position]), 64)
+		if err != nil {
+			return nil, parser.fail("invalid number %q", string(parser.source[start:parser.position]))
+		}
+
+		return number, nil
+	default:
+		switch name := parser.identifier(); name {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "":
+			return nil, parser.fail("expected a value, found %q", character)
+		default:
+			return nil, parser.fail("expressions like %q are not supported", name)
+		}
+	}
+}
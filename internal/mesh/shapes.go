@@ -0,0 +1,247 @@
+package mesh
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+// gridFine is the radius below which circles are approximated by triangles,
+// like OpenSCAD does.
+const gridFine = 0.00000095367431640625
+
+// fragments returns the number of fragments OpenSCAD approximates a circle of
+// the given radius with.
+func fragments(radius float64, quality quality) int {
+	switch {
+	case radius < gridFine:
+		return 3
+	case quality.fn > 0:
+		return max(int(quality.fn), 3)
+	default:
+		return int(math.Ceil(max(min(360/quality.fa, radius*2*math.Pi/quality.fs), 5)))
+	}
+}
+
+// circle returns the vertices of a circle of the given radius around the z
+// axis at height z, counter-clockwise when seen from above.
+func circle(radius, z float64, count int) []mgl64.Vec3 {
+	vertices := make([]mgl64.Vec3, count)
+	for i := range count {
+		phi := 2 * math.Pi * float64(i) / float64(count)
+		vertices[i] = mgl64.Vec3{radius * math.Cos(phi), radius * math.Sin(phi), z}
+	}
+
+	return vertices
+}
+
+func reversed(vertices []mgl64.Vec3) []mgl64.Vec3 {
+	result := make([]mgl64.Vec3, len(vertices))
+	for i, vertex := range vertices {
+		result[len(result)-1-i] = vertex
+	}
+
+	return result
+}
+
+// addFace appends the polygon with the given vertices to the solid, unless it
+// is degenerate.
+func (solid *solid) addFace(vertices ...mgl64.Vec3) {
+	if polygon, ok := newPolygon(vertices); ok {
+		*solid = append(*solid, polygon)
+	}
+}
+
+func newCube(size mgl64.Vec3, center bool) solid {
+	lower := mgl64.Vec3{}
+	if center {
+		lower = size.Mul(-0.5)
+	}
+	upper := lower.Add(size)
+	corner := func(x, y, z int) mgl64.Vec3 {
+		return mgl64.Vec3{
+			[]float64{lower.X(), upper.X()}[x],
+			[]float64{lower.Y(), upper.Y()}[y],
+			[]float64{lower.Z(), upper.Z()}[z],
+		}
+	}
+
+	var cube solid
+	cube.addFace(corner(0, 0, 0), corner(0, 0, 1), corner(0, 1, 1), corner(0, 1, 0))
+	cube.addFace(corner(1, 0, 0), corner(1, 1, 0), corner(1, 1, 1), corner(1, 0, 1))
+	cube.addFace(corner(0, 0, 0), corner(1, 0, 0), corner(1, 0, 1), corner(0, 0, 1))
+	cube.addFace(corner(0, 1, 0), corner(0, 1, 1), corner(1, 1, 1), corner(1, 1, 0))
+	cube.addFace(corner(0, 0, 0), corner(0, 1, 0), corner(1, 1, 0), corner(1, 0, 0))
+	cube.addFace(corner(0, 0, 1), corner(1, 0, 1), corner(1, 1, 1), corner(0, 1, 1))
+
+	return cube
+}
+
+func newCylinder(height, bottomRadius, topRadius float64, center bool, quality quality) solid {
+	bottomZ := 0.0
+	if center {
+		bottomZ = -height / 2
+	}
+	topZ := bottomZ + height
+
+	count := fragments(max(bottomRadius, topRadius), quality)
+	bottom := circle(bottomRadius, bottomZ, count)
+	top := circle(topRadius, topZ, count)
+
+	var cylinder solid
+	if bottomRadius > 0 {
+		cylinder.addFace(reversed(bottom)...)
+	}
+	if topRadius > 0 {
+		cylinder.addFace(top...)
+	}
+	for i := range count {
+		j := (i + 1) % count
+		switch {
+		case bottomRadius <= 0:
+			cylinder.addFace(bottom[i], top[j], top[i])
+		case topRadius <= 0:
+			cylinder.addFace(bottom[i], bottom[j], top[i])
+		default:
+			cylinder.addFace(bottom[i], bottom[j], top[j], top[i])
+		}
+	}
+
+	return cylinder
+}
+
+// newPrism extrudes the outline from z=bottom to z=top.
+func newPrism(outline []mgl64.Vec2, bottom, top float64) solid {
+	outline = simplifyOutline(outline)
+	if len(outline) < 3 {
+		return nil
+	}
+	if signedArea(outline) < 0 {
+		outline = reversedOutline(outline)
+	}
+
+	var prism solid
+	for _, triangle := range triangulate(outline) {
+		a, b, c := outline[triangle[0]], outline[triangle[1]], outline[triangle[2]]
+		prism.addFace(mgl64.Vec3{a.X(), a.Y(), top}, mgl64.Vec3{b.X(), b.Y(), top}, mgl64.Vec3{c.X(), c.Y(), top})
+		prism.addFace(mgl64.Vec3{c.X(), c.Y(), bottom}, mgl64.Vec3{b.X(), b.Y(), bottom}, mgl64.Vec3{a.X(), a.Y(), bottom})
+	}
+	for i, point := range outline {
+		next := outline[(i+1)%len(outline)]
+		prism.addFace(
+			mgl64.Vec3{point.X(), point.Y(), bottom},
+			mgl64.Vec3{next.X(), next.Y(), bottom},
+			mgl64.Vec3{next.X(), next.Y(), top},
+			mgl64.Vec3{point.X(), point.Y(), top},
+		)
+	}
+
+	return prism
+}
+
+// simplifyOutline drops repeated points of an outline.
+func simplifyOutline(outline []mgl64.Vec2) []mgl64.Vec2 {
+	result := make([]mgl64.Vec2, 0, len(outline))
+	for i, point := range outline {
+		if point.Sub(outline[(i+1)%len(outline)]).Len() > planeEpsilon {
+			result = append(result, point)
+		}
+	}
+
+	return result
+}
+
+func reversedOutline(outline []mgl64.Vec2) []mgl64.Vec2 {
+	result := make([]mgl64.Vec2, len(outline))
+	for i, point := range outline {
+		result[len(result)-1-i] = point
+	}
+
+	return result
+}
+
+// signedArea is positive for outlines that run counter-clockwise.
+func signedArea(outline []mgl64.Vec2) float64 {
+	area := 0.0
+	for i, point := range outline {
+		next := outline[(i+1)%len(outline)]
+		area += point.X()*next.Y() - next.X()*point.Y()
+	}
+
+	return area / 2
+}
+
+func cross2D(origin, a, b mgl64.Vec2) float64 {
+	return (a.X()-origin.X())*(b.Y()-origin.Y()) - (a.Y()-origin.Y())*(b.X()-origin.X())
+}
+
+// triangulate splits a simple, counter-clockwise outline into triangles by
+// clipping its ears. The triangles are returned as indices into the outline.
+func triangulate(outline []mgl64.Vec2) [][3]int {
+	remaining := make([]int, len(outline))
+	for i := range remaining {
+		remaining[i] = i
+	}
+
+	var triangles [][3]int
+	for len(remaining) > 3 {
+		earFound := false
+		for i := range remaining {
+			previous := remaining[(i+len(remaining)-1)%len(remaining)]
+			current := remaining[i]
+			next := remaining[(i+1)%len(remaining)]
+			turn := cross2D(outline[previous], outline[current], outline[next])
+			if turn < -parallelEpsilon {
+				continue
+			}
+			if turn <= parallelEpsilon {
+				// Collinear points do not span a triangle and can be dropped.
+				remaining = append(remaining[:i], remaining[i+1:]...)
+				earFound = true
+
+				break
+			}
+			if containsAny(outline, remaining, previous, current, next) {
+				continue
+			}
+
+			triangles = append(triangles, [3]int{previous, current, next})
+			remaining = append(remaining[:i], remaining[i+1:]...)
+			earFound = true
+
+			break
+		}
+		if !earFound {
+			// Only self-intersecting outlines have no ears. Fanning them out is
+			// the best that can be done.
+			for i := 1; i < len(remaining)-1; i++ {
+				triangles = append(triangles, [3]int{remaining[0], remaining[i], remaining[i+1]})
+			}
+
+			return triangles
+		}
+	}
+	if len(remaining) == 3 && cross2D(outline[remaining[0]], outline[remaining[1]], outline[remaining[2]]) > parallelEpsilon {
+		triangles = append(triangles, [3]int{remaining[0], remaining[1], remaining[2]})
+	}
+
+	return triangles
+}
+
+// containsAny reports whether any of the remaining points other than the
+// triangle's corners lies within the triangle.
+func containsAny(outline []mgl64.Vec2, remaining []int, a, b, c int) bool {
+	for _, index := range remaining {
+		if index == a || index == b || index == c {
+			continue
+		}
+		point := outline[index]
+		if cross2D(outline[a], outline[b], point) >= 0 &&
+			cross2D(outline[b], outline[c], point) >= 0 &&
+			cross2D(outline[c], outline[a], point) >= 0 {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,250 @@
+package mesh
+
+import (
+	"cmp"
+	"math"
+	"slices"
+
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+// weldEpsilon is the distance in mm below which vertices are merged into one.
+const weldEpsilon = planeEpsilon
+
+// snapResolution is the number of steps per mm that vertices are rounded to.
+// It is far below what printers resolve, but removes the noise that chains of
+// transformations leave in the coordinates, so that faces parallel to the
+// axes stay exactly parallel.
+const snapResolution = 1e10
+
+// cellSize is the edge length in mm of the grid cells that vertices are
+// looked up in. It is on the order of the rack's smallest features.
+const cellSize = 1.0
+
+type cell [3]int
+
+func cellOf(point mgl64.Vec3) cell {
+	return cell{
+		int(math.Floor(point.X() / cellSize)),
+		int(math.Floor(point.Y() / cellSize)),
+		int(math.Floor(point.Z() / cellSize)),
+	}
+}
+
+// vertexGrid holds the welded vertices of a solid, sorted into grid cells.
+type vertexGrid struct {
+	vertices []mgl64.Vec3
+	cells    map[cell][]int
+
+	// edges caches the vertices that lie on the edge between two vertices,
+	// keyed by the lower index first and ordered from it.
+	edges map[[2]int][]int
+}
+
+func newVertexGrid() *vertexGrid {
+	return &vertexGrid{cells: map[cell][]int{}, edges: map[[2]int][]int{}}
+}
+
+// near calls visit with the index of every vertex that may be within radius
+// of point, until visit returns false.
+func (grid *vertexGrid) near(point mgl64.Vec3, radius float64, visit func(index int) bool) {
+	extent := mgl64.Vec3{radius, radius, radius}
+	lower, upper := cellOf(point.Sub(extent)), cellOf(point.Add(extent))
+	for x := lower[0]; x <= upper[0]; x++ {
+		for y := lower[1]; y <= upper[1]; y++ {
+			for z := lower[2]; z <= upper[2]; z++ {
+				for _, index := range grid.cells[cell{x, y, z}] {
+					if !visit(index) {
+						return
+					}
+				}
+			}
+		}
+	}
+}
+
+// weld returns the index of the vertex within weldEpsilon of point. If there
+// is none, point is snapped to snapResolution and added as a new vertex.
+func (grid *vertexGrid) weld(point mgl64.Vec3) int {
+	for axis := range 3 {
+		point[axis] = math.Round(point[axis]*snapResolution) / snapResolution
+	}
+	welded := -1
+	grid.near(point, weldEpsilon, func(index int) bool {
+		if grid.vertices[index].Sub(point).Len() < weldEpsilon {
+			welded = index
+
+			return false
+		}
+
+		return true
+	})
+	if welded >= 0 {
+		return welded
+	}
+
+	grid.vertices = append(grid.vertices, point)
+	home := cellOf(point)
+	grid.cells[home] = append(grid.cells[home], len(grid.vertices)-1)
+
+	return len(grid.vertices) - 1
+}
+
+// between returns the vertices that lie on the edge from a to b, ordered from
+// a to b and excluding both.
+func (grid *vertexGrid) between(a, b int) []int {
+	if a > b {
+		reversed := slices.Clone(grid.between(b, a))
+		slices.Reverse(reversed)
+
+		return reversed
+	}
+	if cached, ok := grid.edges[[2]int{a, b}]; ok {
+		return cached
+	}
+
+	from, to := grid.vertices[a], grid.vertices[b]
+	direction := to.Sub(from)
+	length := direction.Len()
+	direction = direction.Mul(1 / length)
+
+	var found []int
+	positions := map[int]float64{}
+	// Every point of the edge is within half a cell of one of the samples, so
+	// the vertices on the edge are within that radius of them.
+	samples := int(math.Ceil(length/cellSize)) + 1
+	for sample := range samples {
+		center := from.Add(direction.Mul(length * float64(sample) / float64(samples-1)))
+		grid.near(center, cellSize/2+weldEpsilon, func(index int) bool {
+			if index == a || index == b {
+				return true
+			}
+			if _, ok := positions[index]; ok {
+				return true
+			}
+			offset := grid.vertices[index].Sub(from)
+			position := offset.Dot(direction)
+			if position < weldEpsilon || position > length-weldEpsilon {
+				return true
+			}
+			if offset.Sub(direction.Mul(position)).Len() >= weldEpsilon {
+				return true
+			}
+			positions[index] = position
+			found = append(found, index)
+
+			return true
+		})
+	}
+	slices.SortFunc(found, func(first, second int) int {
+		return cmp.Compare(positions[first], positions[second])
+	})
+	grid.edges[[2]int{a, b}] = found
+
+	return found
+}
+
+// stitch returns the outlines of the solid's polygons after merging vertices
+// that are closer than weldEpsilon and inserting the vertices that lie on the
+// edges of a polygon into that edge.
+//
+// The boolean operations split polygons without splitting their neighbours, so
+// a vertex of one polygon may lie in the middle of another polygon's edge. Such
+// T-junctions leave the mesh open, because the edges of the neighbours no
+// longer match. After stitching, neighbouring polygons share every edge.
+func stitch(solid solid) [][]mgl64.Vec3 {
+	grid := newVertexGrid()
+
+	loops := make([][]int, 0, len(solid))
+	for _, polygon := range solid {
+		loop := make([]int, 0, len(polygon.vertices))
+		for _, vertex := range polygon.vertices {
+			index := grid.weld(vertex)
+			if len(loop) == 0 || loop[len(loop)-1] != index {
+				loop = append(loop, index)
+			}
+		}
+		for len(loop) > 1 && loop[0] == loop[len(loop)-1] {
+			loop = loop[:len(loop)-1]
+		}
+		if len(loop) >= 3 {
+			loops = append(loops, loop)
+		}
+	}
+
+	outlines := make([][]mgl64.Vec3, 0, len(loops))
+	for _, loop := range loops {
+		outline := make([]mgl64.Vec3, 0, len(loop))
+		for i, a := range loop {
+			outline = append(outline, grid.vertices[a])
+			for _, index := range grid.between(a, loop[(i+1)%len(loop)]) {
+				outline = append(outline, grid.vertices[index])
+			}
+		}
+		outlines = append(outlines, outline)
+	}
+
+	return outlines
+}
+
+// fan splits a convex outline into triangles. Outlines whose vertices
+// all lie on a line have no area and yield no triangles.
+//
+// Outlines with vertices in the middle of their edges are fanned out from
+// their centroid, because a fan from one of their vertices would contain
+// triangles without area along the edges through that vertex.
+func fan(outline []mgl64.Vec3) []Triangle {
+	straight := false
+	for i, vertex := range outline {
+		previous := outline[(i+len(outline)-1)%len(outline)]
+		next := outline[(i+1)%len(outline)]
+		if distanceToLine(vertex, previous, next) < weldEpsilon {
+			straight = true
+
+			break
+		}
+	}
+
+	if !straight {
+		triangles := make([]Triangle, 0, len(outline)-2)
+		for i := 2; i < len(outline); i++ {
+			triangles = append(triangles, Triangle{outline[0], outline[i-1], outline[i]})
+		}
+
+		return triangles
+	}
+
+	farthest := outline[0]
+	centroid := mgl64.Vec3{}
+	for _, vertex := range outline {
+		if vertex.Sub(outline[0]).Len() > farthest.Sub(outline[0]).Len() {
+			farthest = vertex
+		}
+		centroid = centroid.Add(vertex)
+	}
+	centroid = centroid.Mul(1 / float64(len(outline)))
+	if !slices.ContainsFunc(outline, func(vertex mgl64.Vec3) bool {
+		return distanceToLine(vertex, outline[0], farthest) >= weldEpsilon
+	}) {
+		return nil
+	}
+
+	triangles := make([]Triangle, 0, len(outline))
+	for i, vertex := range outline {
+		triangles = append(triangles, Triangle{centroid, vertex, outline[(i+1)%len(outline)]})
+	}
+
+	return triangles
+}
+
+// distanceToLine returns the distance of point from the line through a and b,
+// or from a if a and b coincide.
+func distanceToLine(point, a, b mgl64.Vec3) float64 {
+	direction := b.Sub(a)
+	length := direction.Len()
+	if length < parallelEpsilon {
+		return point.Sub(a).Len()
+	}
+
+	return direction.Cross(point.Sub(a)).Len() / length
+}
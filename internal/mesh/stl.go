@@ -0,0 +1,71 @@
+package mesh
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+// stlHeaderSize is the size of the free-form header of binary STL files.
+const stlHeaderSize = 80
+
+// WriteSTL writes the mesh as a binary STL file. The name is stored in the
+// file's header.
+func (mesh *Mesh) WriteSTL(writer io.Writer, name string) error {
+	if len(mesh.Triangles) > math.MaxUint32 {
+		return fmt.Errorf("%d triangles do not fit a binary STL file", len(mesh.Triangles))
+	}
+
+	buffered := bufio.NewWriter(writer)
+
+	header := make([]byte, stlHeaderSize)
+	copy(header, name)
+	if _, err := buffered.Write(header); err != nil {
+		return err
+	}
+	if err := binary.Write(buffered, binary.LittleEndian, uint32(len(mesh.Triangles))); err != nil {
+		return err
+	}
+
+	record := make([]byte, 0, 50)
+	for _, triangle := range mesh.Triangles {
+		record = record[:0]
+		for _, vector := range []mgl64.Vec3{triangle.Normal(), triangle[0], triangle[1], triangle[2]} {
+			for _, coordinate := range vector {
+				record = binary.LittleEndian.AppendUint32(record, math.Float32bits(float32(coordinate)))
+			}
+		}
+		// The attribute byte count is unused.
+		record = binary.LittleEndian.AppendUint16(record, 0)
+
+		if _, err := buffered.Write(record); err != nil {
+			return err
+		}
+	}
+
+	return buffered.Flush()
+}
+
+// WriteASCIISTL writes the mesh as an ASCII STL file with the given solid name.
+func (mesh *Mesh) WriteASCIISTL(writer io.Writer, name string) error {
+	buffered := bufio.NewWriter(writer)
+
+	fmt.Fprintf(buffered, "solid %s\n", name)
+	for _, triangle := range mesh.Triangles {
+		normal := triangle.Normal()
+		fmt.Fprintf(buffered, "  facet normal %e %e %e\n", normal.X(), normal.Y(), normal.Z())
+		fmt.Fprintf(buffered, "    outer loop\n")
+		for _, vertex := range triangle {
+			fmt.Fprintf(buffered, "      vertex %e %e %e\n", vertex.X(), vertex.Y(), vertex.Z())
+		}
+		fmt.Fprintf(buffered, "    endloop\n")
+		fmt.Fprintf(buffered, "  endfacet\n")
+	}
+	fmt.Fprintf(buffered, "endsolid %s\n", name)
+
+	return buffered.Flush()
+}
@@ -0,0 +1,198 @@
+package mesh
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+// Namespace3MF is the XML namespace of the metadata that this project adds to
+// 3MF files. Metadata names with the prefix "rack:" are in this namespace.
+const Namespace3MF = "https://github.com/yeldiRium/3d-rack-brackets"
+
+const (
+	namespace3MFCore    = "http://schemas.microsoft.com/3dmanufacturing/core/2015/02"
+	relationship3DModel = "http://schemas.microsoft.com/3dmanufacturing/2013/01/3dmodel"
+	modelPath3MF        = "3D/3dmodel.model"
+)
+
+const contentTypes3MF = `<?xml version="1.0" encoding="UTF-8"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+  <Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+  <Default Extension="model" ContentType="application/vnd.ms-package.3dmanufacturing-3dmodel+xml"/>
+</Types>
+`
+
+const relationships3MF = `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+  <Relationship Target="/` + modelPath3MF + `" Id="rel0" Type="` + relationship3DModel + `"/>
+</Relationships>
+`
+
+// Object is a named mesh in a 3MF file.
+type Object struct {
+	Name string
+	Mesh *Mesh
+
+	// Transform places the mesh on the build plate, e.g. in the orientation it
+	// is best printed in.
+	Transform mgl64.Mat4
+
+	// Metadata is written along with the object. Names other than those of the
+	// 3MF specification must have a namespace prefix, e.g. "rack:material".
+	Metadata map[string]string
+}
+
+// ErrOpenMesh is returned for meshes that are not closed, which 3MF does not
+// allow.
+var ErrOpenMesh = errors.New("mesh is not closed")
+
+// Write3MF writes the objects to a 3MF package. Every object is an object of
+// the model and an item of its build, in millimetres. The model is annotated
+// with metadata. Objects whose meshes are not closed fail with ErrOpenMesh
+// before anything is written.
+func Write3MF(writer io.Writer, objects []Object, metadata map[string]string) error {
+	for _, object := range objects {
+		if open := object.Mesh.OpenEdges(); open > 0 {
+			return fmt.Errorf("%w: %s has %d edges without an opposite edge", ErrOpenMesh, object.Name, open)
+		}
+	}
+
+	archive := zip.NewWriter(writer)
+
+	for _, file := range []struct {
+		name, content string
+	}{
+		{"[Content_Types].xml", contentTypes3MF},
+		{"_rels/.rels", relationships3MF},
+	} {
+		entry, err := archive.Create(file.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(entry, file.content); err != nil {
+			return err
+		}
+	}
+
+	entry, err := archive.Create(modelPath3MF)
+	if err != nil {
+		return err
+	}
+	if err := writeModel(entry, objects, metadata); err != nil {
+		return err
+	}
+
+	return archive.Close()
+}
+
+func writeModel(writer io.Writer, objects []Object, metadata map[string]string) error {
+	model := &strings.Builder{}
+
+	model.WriteString(xml.Header)
+	fmt.Fprintf(model, `<model unit="millimeter" xml:lang="en-US" xmlns="%s" xmlns:rack="%s">`+"\n", namespace3MFCore, Namespace3MF)
+	writeMetadata(model, "  ", metadata)
+
+	model.WriteString("  <resources>\n")
+	for i, object := range objects {
+		fmt.Fprintf(model, `    <object id="%d" type="model" name="%s">`+"\n", i+1, escapeXML(object.Name))
+		if len(object.Metadata) > 0 {
+			model.WriteString("      <metadatagroup>\n")
+			writeMetadata(model, "        ", object.Metadata)
+			model.WriteString("      </metadatagroup>\n")
+		}
+		writeMesh(model, object.Mesh)
+		model.WriteString("    </object>\n")
+	}
+	model.WriteString("  </resources>\n")
+
+	model.WriteString("  <build>\n")
+	for i, object := range objects {
+		fmt.Fprintf(model, `    <item objectid="%d" transform="%s"/>`+"\n", i+1, formatTransform(object.Transform))
+	}
+	model.WriteString("  </build>\n")
+	model.WriteString("</model>\n")
+
+	_, err := io.WriteString(writer, model.String())
+
+	return err
+}
+
+func writeMetadata(model *strings.Builder, indent string, metadata map[string]string) {
+	for _, name := range slices.Sorted(maps.Keys(metadata)) {
+		fmt.Fprintf(model, `%s<metadata name="%s">%s</metadata>`+"\n", indent, escapeXML(name), escapeXML(metadata[name]))
+	}
+}
+
+// writeMesh writes every vertex of the mesh once and lets the triangles refer
+// to them by index, as 3MF requires. Triangles share vertices whose
+// coordinates are equal, which the mesh's welded vertices are.
+func writeMesh(model *strings.Builder, mesh *Mesh) {
+	indices := map[mgl64.Vec3]int{}
+	var vertices []mgl64.Vec3
+	index := func(vertex mgl64.Vec3) int {
+		if i, ok := indices[vertex]; ok {
+			return i
+		}
+		indices[vertex] = len(vertices)
+		vertices = append(vertices, vertex)
+
+		return len(vertices) - 1
+	}
+
+	triangles := make([][3]int, 0, len(mesh.Triangles))
+	for _, triangle := range mesh.Triangles {
+		triangles = append(triangles, [3]int{index(triangle[0]), index(triangle[1]), index(triangle[2])})
+	}
+
+	model.WriteString("      <mesh>\n        <vertices>\n")
+	for _, vertex := range vertices {
+		fmt.Fprintf(model, `          <vertex x="%s" y="%s" z="%s"/>`+"\n", formatNumber(vertex.X()), formatNumber(vertex.Y()), formatNumber(vertex.Z()))
+	}
+	model.WriteString("        </vertices>\n        <triangles>\n")
+	for _, triangle := range triangles {
+		fmt.Fprintf(model, `          <triangle v1="%d" v2="%d" v3="%d"/>`+"\n", triangle[0], triangle[1], triangle[2])
+	}
+	model.WriteString("        </triangles>\n      </mesh>\n")
+}
+
+// formatTransform formats a transform the way 3MF expects it: as the first
+// three rows of the transposed matrix, since 3MF multiplies row vectors.
+func formatTransform(transform mgl64.Mat4) string {
+	values := make([]string, 0, 12)
+	for column := range 4 {
+		for row := range 3 {
+			values = append(values, formatNumber(transform.At(row, column)))
+		}
+	}
+
+	return strings.Join(values, " ")
+}
+
+// formatNumber formats a number with a precision of a micrometre, which is
+// far below what printers resolve.
+func formatNumber(number float64) string {
+	rounded := math.Round(number*1e6) / 1e6
+	if rounded == 0 {
+		// Avoids negative zeros.
+		rounded = 0
+	}
+
+	return strconv.FormatFloat(rounded, 'f', -1, 64)
+}
+
+func escapeXML(text string) string {
+	escaped := &strings.Builder{}
+	_ = xml.EscapeText(escaped, []byte(text))
+
+	return escaped.String()
+}
@@ -0,0 +1,216 @@
+// Package openscad runs the OpenSCAD binary to export OpenSCAD code to the
+// formats that OpenSCAD renders, e.g. meshes or images.
+package openscad
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DefaultBinary is the name of the OpenSCAD binary, which is looked up in the
+// PATH.
+const DefaultBinary = "openscad"
+
+var (
+	ErrNotFound          = errors.New("openscad binary not found")
+	ErrUnsupportedFormat = errors.New("unsupported export format")
+)
+
+// Formats lists the formats OpenSCAD can export to.
+var Formats = []string{"stl", "3mf", "off", "png"}
+
+// Severity is the kind of a message that OpenSCAD reports.
+type Severity string
+
+const (
+	SeverityWarning    Severity = "WARNING"
+	SeverityError      Severity = "ERROR"
+	SeverityDeprecated Severity = "DEPRECATED"
+)
+
+// Diagnostic is a warning or an error that OpenSCAD reported, e.g. that the
+// rendered object is not a valid 2-manifold.
+type Diagnostic struct {
+	Severity Severity
+	Message  string
+
+	// Line is the line of the OpenSCAD code that the message refers to, or 0.
+	Line int
+}
+
+func (diagnostic Diagnostic) String() string {
+	if diagnostic.Line == 0 {
+		return fmt.Sprintf("%s: %s", diagnostic.Severity, diagnostic.Message)
+	}
+
+	return fmt.Sprintf("%s: line %d: %s", diagnostic.Severity, diagnostic.Line, diagnostic.Message)
+}
+
+// DiagnosticsError is returned when OpenSCAD fails or reports warnings or
+// errors. ExitCode is 0 if OpenSCAD exited successfully despite them.
+type DiagnosticsError struct {
+	ExitCode    int
+	Diagnostics []Diagnostic
+}
+
+func (err *DiagnosticsError) Error() string {
+	messages := make([]string, 0, len(err.Diagnostics))
+	for _, diagnostic := range err.Diagnostics {
+		messages = append(messages, diagnostic.String())
+	}
+
+	switch {
+	case err.ExitCode != 0 && len(messages) == 0:
+		return fmt.Sprintf("openscad exited with code %d", err.ExitCode)
+	case err.ExitCode != 0:
+		return fmt.Sprintf("openscad exited with code %d: %s", err.ExitCode, strings.Join(messages, "; "))
+	default:
+		return "openscad reported " + strings.Join(messages, "; ")
+	}
+}
+
+// Runner runs an OpenSCAD binary.
+type Runner struct {
+	// Binary is the path or name of the OpenSCAD binary, see DefaultBinary.
+	Binary string
+
+	// Logger receives the progress that OpenSCAD reports.
+	Logger *slog.Logger
+}
+
+var (
+	diagnosticPattern = regexp.MustCompile(`^(WARNING|ERROR|DEPRECATED): (.*)$`)
+	linePattern       = regexp.MustCompile(`, line (\d+)`)
+)
+
+// Export renders the OpenSCAD code read from source to the given format and
+// writes the result to output. OpenSCAD's progress is logged while it runs,
+// and it is killed if ctx is cancelled. Any warning or error that OpenSCAD
+// reports fails the export with a *DiagnosticsError.
+func (runner *Runner) Export(ctx context.Context, source io.Reader, format string, output io.Writer) error {
+	arguments, err := exportArguments(format)
+	if err != nil {
+		return err
+	}
+
+	binary, err := exec.LookPath(runner.Binary)
+	if err != nil {
+		return fmt.Errorf("%w: %s, install OpenSCAD or point --openscad to it: %w", ErrNotFound, runner.Binary, err)
+	}
+
+	directory, err := os.MkdirTemp("", "openscad-")
+	if err != nil {
+		return fmt.Errorf("failed to create directory for openscad's output: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(directory) }()
+	outputPath := filepath.Join(directory, "output."+format)
+
+	// OpenSCAD reads the code from stdin if the input file is "-".
+	command := exec.CommandContext(ctx, binary, append([]string{"-o", outputPath}, append(arguments, "-")...)...)
+	command.Stdin = source
+	stderr, err := command.StderrPipe()
+	if err != nil {
+		return err
+	}
+	// OpenSCAD reports its progress on both streams, so they are read together.
+	command.Stdout = command.Stderr
+
+	logger := runner.Logger.With(slog.String("binary", binary), slog.String("format", format))
+	logger.Debug("starting openscad")
+	if err := command.Start(); err != nil {
+		return fmt.Errorf("failed to start openscad: %w", err)
+	}
+
+	diagnostics := runner.readLog(ctx, logger, stderr)
+
+	err = command.Wait()
+	if ctx.Err() != nil {
+		return fmt.Errorf("openscad was cancelled: %w", ctx.Err())
+	}
+
+	var exitError *exec.ExitError
+	switch {
+	case errors.As(err, &exitError):
+		return &DiagnosticsError{ExitCode: exitError.ExitCode(), Diagnostics: diagnostics}
+	case err != nil:
+		return fmt.Errorf("failed to run openscad: %w", err)
+	case len(diagnostics) > 0:
+		return &DiagnosticsError{Diagnostics: diagnostics}
+	}
+
+	result, err := os.Open(outputPath)
+	if err != nil {
+		return fmt.Errorf("openscad did not write its output: %w", err)
+	}
+	defer func() { _ = result.Close() }()
+
+	_, err = io.Copy(output, result)
+
+	return err
+}
+
+// readLog logs every line that OpenSCAD reports and returns its warnings and
+// errors.
+func (runner *Runner) readLog(ctx context.Context, logger *slog.Logger, log io.Reader) []Diagnostic {
+	var diagnostics []Diagnostic
+
+	scanner := bufio.NewScanner(log)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		match := diagnosticPattern.FindStringSubmatch(line)
+		if match == nil {
+			logger.Info("openscad", slog.String("message", line))
+
+			continue
+		}
+
+		diagnostic := Diagnostic{Severity: Severity(match[1]), Message: match[2]}
+		if lineMatch := linePattern.FindStringSubmatch(diagnostic.Message); lineMatch != nil {
+			diagnostic.Line, _ = strconv.Atoi(lineMatch[1])
+		}
+		level := slog.LevelWarn
+		if diagnostic.Severity == SeverityError {
+			level = slog.LevelError
+		}
+		attributes := []any{slog.String("severity", string(diagnostic.Severity)), slog.String("message", diagnostic.Message)}
+		if diagnostic.Line != 0 {
+			attributes = append(attributes, slog.Int("line", diagnostic.Line))
+		}
+		logger.Log(ctx, level, "openscad", attributes...)
+
+		// Deprecations do not affect the result.
+		if diagnostic.Severity != SeverityDeprecated {
+			diagnostics = append(diagnostics, diagnostic)
+		}
+	}
+
+	return diagnostics
+}
+
+// exportArguments returns the arguments that select the format.
+func exportArguments(format string) ([]string, error) {
+	switch format {
+	case "stl":
+		return []string{"--export-format", "binstl"}, nil
+	case "3mf", "off":
+		return []string{"--export-format", format}, nil
+	case "png":
+		return []string{"--viewall", "--autocenter"}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, format)
+	}
+}
@@ -0,0 +1,121 @@
+package openscad
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeOpenSCAD writes a shell script that stands in for OpenSCAD. The script
+// receives the output path as $2 and runs body.
+func fakeOpenSCAD(t *testing.T, body string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "openscad")
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\noutput=\"$2\"\n"+body+"\n"), 0o755)) //nolint:gosec
+
+	return path
+}
+
+func newRunner(binary string) (*Runner, *bytes.Buffer) {
+	log := &bytes.Buffer{}
+
+	return &Runner{
+		Binary: binary,
+		Logger: slog.New(slog.NewTextHandler(log, &slog.HandlerOptions{Level: slog.LevelDebug})),
+	}, log
+}
+
+func TestRunnerExport(t *testing.T) {
+	t.Parallel()
+
+	t.Run("pipes the code into openscad and writes its output.", func(t *testing.T) {
+		t.Parallel()
+
+		runner, log := newRunner(fakeOpenSCAD(t, `echo "Rendering Polygon Mesh using CGAL..." >&2
+echo "$@" > "$output"
+cat >> "$output"`))
+
+		var output bytes.Buffer
+		err := runner.Export(t.Context(), strings.NewReader("cube(1);"), "stl", &output)
+		require.NoError(t, err)
+
+		assert.Contains(t, output.String(), "--export-format binstl -\ncube(1);")
+		assert.Contains(t, log.String(), `message="Rendering Polygon Mesh using CGAL..."`)
+	})
+
+	t.Run("fails on warnings.", func(t *testing.T) {
+		t.Parallel()
+
+		runner, log := newRunner(fakeOpenSCAD(t, `echo "DEPRECATED: The assign() module will be removed in future releases." >&2
+echo "WARNING: Object may not be a valid 2-manifold and may need repair!"
+touch "$output"`))
+
+		err := runner.Export(t.Context(), strings.NewReader("cube(1);"), "off", &bytes.Buffer{})
+
+		var diagnosticsError *DiagnosticsError
+		require.ErrorAs(t, err, &diagnosticsError)
+		assert.Equal(t, 0, diagnosticsError.ExitCode)
+		assert.Equal(t, []Diagnostic{{Severity: SeverityWarning, Message: "Object may not be a valid 2-manifold and may need repair!"}}, diagnosticsError.Diagnostics)
+		assert.Contains(t, log.String(), `severity=DEPRECATED message="The assign() module will be removed in future releases."`)
+	})
+
+	t.Run("reports errors with their line.", func(t *testing.T) {
+		t.Parallel()
+
+		runner, _ := newRunner(fakeOpenSCAD(t, `echo 'ERROR: Parser error in file "", line 3: syntax error' >&2
+exit 1`))
+
+		err := runner.Export(t.Context(), strings.NewReader("cube(1);"), "png", &bytes.Buffer{})
+
+		var diagnosticsError *DiagnosticsError
+		require.ErrorAs(t, err, &diagnosticsError)
+		assert.Equal(t, 1, diagnosticsError.ExitCode)
+		require.Len(t, diagnosticsError.Diagnostics, 1)
+		assert.Equal(t, 3, diagnosticsError.Diagnostics[0].Line)
+		assert.EqualError(t, err, `openscad exited with code 1: ERROR: line 3: Parser error in file "", line 3: syntax error`)
+	})
+
+	t.Run("stops openscad when the context is cancelled.", func(t *testing.T) {
+		t.Parallel()
+
+		runner, _ := newRunner(fakeOpenSCAD(t, "exec sleep 10"))
+		ctx, cancel := context.WithTimeout(t.Context(), 100*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		err := runner.Export(ctx, strings.NewReader("cube(1);"), "stl", &bytes.Buffer{})
+
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), 5*time.Second)
+	})
+
+	t.Run("fails clearly if the binary is missing.", func(t *testing.T) {
+		t.Parallel()
+
+		runner, _ := newRunner(filepath.Join(t.TempDir(), "openscad"))
+
+		err := runner.Export(t.Context(), strings.NewReader("cube(1);"), "stl", &bytes.Buffer{})
+
+		require.ErrorIs(t, err, ErrNotFound)
+		assert.ErrorContains(t, err, "install OpenSCAD or point --openscad to it")
+	})
+
+	t.Run("rejects formats that openscad does not export.", func(t *testing.T) {
+		t.Parallel()
+
+		runner, _ := newRunner(DefaultBinary)
+
+		err := runner.Export(t.Context(), strings.NewReader("cube(1);"), "scad", &bytes.Buffer{})
+
+		require.ErrorIs(t, err, ErrUnsupportedFormat)
+	})
+}
@@ -0,0 +1,172 @@
+// Package plate arranges parts on as few build plates as possible.
+//
+// The parts are packed by their bounding boxes into shelves: rows along x
+// that are as deep as their deepest part. Parts are sorted by depth first and
+// may be turned by 90° about z, so that the shelves are shallow. This wastes
+// some space, but is predictable and good enough for the few parts of a rack.
+package plate
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/go-gl/mathgl/mgl64"
+
+	"github.com/yeldiRium/3d-rack-brackets/internal/mesh"
+)
+
+// PartTooLargeError is returned for parts that do not fit the build plate in
+// either orientation.
+type PartTooLargeError struct {
+	Name        string
+	Size        mgl64.Vec3
+	BuildVolume mgl64.Vec3
+}
+
+func (err *PartTooLargeError) Error() string {
+	return fmt.Sprintf(
+		"%s measures %.1f x %.1f x %.1f mm and does not fit the build volume of %g x %g x %g mm",
+		err.Name, err.Size.X(), err.Size.Y(), err.Size.Z(), err.BuildVolume.X(), err.BuildVolume.Y(), err.BuildVolume.Z(),
+	)
+}
+
+// Item is a part to arrange, in its print orientation.
+type Item struct {
+	Name   string
+	Bounds mesh.Box
+}
+
+// Placement places an item on a plate.
+type Placement struct {
+	// Item is the index of the item in the items given to Arrange.
+	Item int
+
+	// Transform moves the item from its print orientation to its place on the
+	// plate, whose corner is at the origin.
+	Transform mgl64.Mat4
+}
+
+// Plate is a build plate and the items on it.
+type Plate struct {
+	Placements []Placement
+}
+
+// Arrange packs items onto as few plates of the build volume as it can,
+// keeping spacing between them. Every item that does not fit the build
+// volume is reported with a *PartTooLargeError.
+func Arrange(items []Item, buildVolume mgl64.Vec3, spacing float64) ([]Plate, error) {
+	footprints := make([]footprint, 0, len(items))
+	var errs []error
+	for index, item := range items {
+		footprint, ok := orient(index, item, buildVolume)
+		if !ok {
+			errs = append(errs, &PartTooLargeError{Name: item.Name, Size: item.Bounds.Size(), BuildVolume: buildVolume})
+
+			continue
+		}
+		footprints = append(footprints, footprint)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	slices.SortStableFunc(footprints, func(a, b footprint) int {
+		return cmp.Compare(b.size.Y(), a.size.Y())
+	})
+
+	var plates []*plate
+	for _, footprint := range footprints {
+		placed := false
+		for _, plate := range plates {
+			if plate.place(footprint, buildVolume, spacing) {
+				placed = true
+
+				break
+			}
+		}
+		if !placed {
+			plate := &plate{}
+			if !plate.place(footprint, buildVolume, spacing) {
+				panic("the item fits the build volume. this should not happen")
+			}
+			plates = append(plates, plate)
+		}
+	}
+
+	result := make([]Plate, 0, len(plates))
+	for _, plate := range plates {
+		result = append(result, plate.Plate)
+	}
+
+	return result, nil
+}
+
+// footprint is an item in the orientation it is placed in.
+type footprint struct {
+	item     int
+	rotation mgl64.Mat4
+	bounds   mesh.Box
+	size     mgl64.Vec3
+}
+
+// orient turns the item by 90° about z if that makes it shallower and it
+// still fits, or if it only fits that way. ok is false if it does not fit.
+func orient(index int, item Item, buildVolume mgl64.Vec3) (result footprint, ok bool) {
+	var candidates []footprint
+	for _, rotation := range []mgl64.Mat4{mgl64.Ident4(), mgl64.HomogRotate3DZ(math.Pi / 2)} {
+		bounds := item.Bounds.Transformed(rotation)
+		if bounds.Fits(buildVolume) {
+			candidates = append(candidates, footprint{item: index, rotation: rotation, bounds: bounds, size: bounds.Size()})
+		}
+	}
+	if len(candidates) == 0 {
+		return footprint{}, false
+	}
+
+	return slices.MinFunc(candidates, func(a, b footprint) int {
+		return cmp.Compare(a.size.Y(), b.size.Y())
+	}), true
+}
+
+// shelf is a row of items along x.
+type shelf struct {
+	y, depth, width float64
+}
+
+type plate struct {
+	Plate
+
+	shelves []shelf
+}
+
+// place puts the footprint onto the first shelf that has room for it, or onto
+// a new shelf behind the others. It reports whether there was room.
+func (plate *plate) place(footprint footprint, buildVolume mgl64.Vec3, spacing float64) bool {
+	index := slices.IndexFunc(plate.shelves, func(shelf shelf) bool {
+		return footprint.size.Y() <= shelf.depth && shelf.width+spacing+footprint.size.X() <= buildVolume.X()
+	})
+	if index < 0 {
+		y := 0.0
+		if len(plate.shelves) > 0 {
+			last := plate.shelves[len(plate.shelves)-1]
+			y = last.y + last.depth + spacing
+		}
+		if y+footprint.size.Y() > buildVolume.Y() {
+			return false
+		}
+		plate.shelves = append(plate.shelves, shelf{y: y, depth: footprint.size.Y(), width: -spacing})
+		index = len(plate.shelves) - 1
+	}
+
+	shelf := &plate.shelves[index]
+	x := shelf.width + spacing
+	shelf.width = x + footprint.size.X()
+
+	translation := mgl64.Translate3D(x-footprint.bounds.Min.X(), shelf.y-footprint.bounds.Min.Y(), -footprint.bounds.Min.Z())
+	plate.Placements = append(plate.Placements, Placement{Item: footprint.item, Transform: translation.Mul4(footprint.rotation)})
+
+	return true
+}
@@ -0,0 +1,119 @@
+package plate
+
+import (
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yeldiRium/3d-rack-brackets/internal/mesh"
+)
+
+func box(x, y, z float64) mesh.Box {
+	return mesh.Box{Max: mgl64.Vec3{x, y, z}}
+}
+
+// placedBounds returns where the items ended up on the plate.
+func placedBounds(items []Item, plate Plate) []mesh.Box {
+	result := make([]mesh.Box, 0, len(plate.Placements))
+	for _, placement := range plate.Placements {
+		result = append(result, items[placement.Item].Bounds.Transformed(placement.Transform))
+	}
+
+	return result
+}
+
+func assertBoxInDelta(t *testing.T, expected, actual mesh.Box) {
+	t.Helper()
+
+	assert.InDeltaSlice(t, expected.Min[:], actual.Min[:], 1e-9)
+	assert.InDeltaSlice(t, expected.Max[:], actual.Max[:], 1e-9)
+}
+
+func TestArrange(t *testing.T) {
+	t.Parallel()
+
+	buildVolume := mgl64.Vec3{100, 100, 50}
+
+	t.Run("puts items next to each other with spacing.", func(t *testing.T) {
+		t.Parallel()
+
+		items := []Item{
+			{Name: "a", Bounds: mesh.Box{Min: mgl64.Vec3{-20, -10, -5}, Max: mgl64.Vec3{20, 10, 5}}},
+			{Name: "b", Bounds: box(30, 20, 10)},
+		}
+
+		plates, err := Arrange(items, buildVolume, 5)
+		require.NoError(t, err)
+
+		require.Len(t, plates, 1)
+		placed := placedBounds(items, plates[0])
+		assertBoxInDelta(t, box(40, 20, 10), placed[0])
+		assertBoxInDelta(t, mesh.Box{Min: mgl64.Vec3{45, 0, 0}, Max: mgl64.Vec3{75, 20, 10}}, placed[1])
+	})
+
+	t.Run("starts a new shelf behind a full one.", func(t *testing.T) {
+		t.Parallel()
+
+		items := []Item{
+			{Name: "a", Bounds: box(60, 30, 10)},
+			{Name: "b", Bounds: box(60, 20, 10)},
+		}
+
+		plates, err := Arrange(items, buildVolume, 5)
+		require.NoError(t, err)
+
+		require.Len(t, plates, 1)
+		placed := placedBounds(items, plates[0])
+		assertBoxInDelta(t, box(60, 30, 10), placed[0])
+		assertBoxInDelta(t, mesh.Box{Min: mgl64.Vec3{0, 35, 0}, Max: mgl64.Vec3{60, 55, 10}}, placed[1])
+	})
+
+	t.Run("turns deep items so that they lie along x.", func(t *testing.T) {
+		t.Parallel()
+
+		items := []Item{{Name: "a", Bounds: box(10, 80, 10)}}
+
+		plates, err := Arrange(items, buildVolume, 5)
+		require.NoError(t, err)
+
+		require.Len(t, plates, 1)
+		assertBoxInDelta(t, box(80, 10, 10), placedBounds(items, plates[0])[0])
+	})
+
+	t.Run("uses more plates when one is full.", func(t *testing.T) {
+		t.Parallel()
+
+		items := []Item{
+			{Name: "a", Bounds: box(90, 60, 10)},
+			{Name: "b", Bounds: box(90, 60, 10)},
+			{Name: "c", Bounds: box(90, 30, 10)},
+		}
+
+		plates, err := Arrange(items, buildVolume, 5)
+		require.NoError(t, err)
+
+		require.Len(t, plates, 2)
+		assert.Equal(t, []int{0, 2}, []int{plates[0].Placements[0].Item, plates[0].Placements[1].Item})
+		assert.Equal(t, 1, plates[1].Placements[0].Item)
+	})
+
+	t.Run("reports every item that does not fit.", func(t *testing.T) {
+		t.Parallel()
+
+		items := []Item{
+			{Name: "wide", Bounds: box(120, 10, 10)},
+			{Name: "fine", Bounds: box(10, 10, 10)},
+			{Name: "tall", Bounds: box(10, 10, 60)},
+		}
+
+		_, err := Arrange(items, buildVolume, 5)
+
+		var tooLarge *PartTooLargeError
+		require.ErrorAs(t, err, &tooLarge)
+		assert.Equal(t, "wide", tooLarge.Name)
+		assert.EqualError(t, err, "wide measures 120.0 x 10.0 x 10.0 mm and does not fit the build volume of 100 x 100 x 50 mm\n"+
+			"tall measures 10.0 x 10.0 x 60.0 mm and does not fit the build volume of 100 x 100 x 50 mm")
+	})
+}
@@ -0,0 +1,248 @@
+// Package printability finds the features of a part that are hard to print
+// without support: overhangs, long bridges, thin walls and small holes. Parts
+// are analysed in the orientation they are printed in, with z pointing up
+// from the build plate.
+package printability
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/go-gl/mathgl/mgl64"
+
+	"github.com/yeldiRium/3d-rack-brackets/internal/mesh"
+)
+
+// Kind is a kind of issue.
+type Kind string
+
+const (
+	KindOverhang  Kind = "overhang"
+	KindBridge    Kind = "bridge"
+	KindThinWall  Kind = "thin-wall"
+	KindSmallHole Kind = "small-hole"
+)
+
+// Settings are the limits of the printer.
+type Settings struct {
+	// MaxOverhangAngle is the largest angle in degrees between a surface that
+	// faces down and the vertical that prints without support.
+	MaxOverhangAngle float64
+
+	// MaxBridgeLength is the longest distance in mm that a horizontal surface
+	// facing down may span without support.
+	MaxBridgeLength float64
+
+	// MinWallThickness is the thinnest wall in mm that prints, which depends
+	// on the nozzle's diameter.
+	MinWallThickness float64
+
+	// MinHoleDiameter is the smallest diameter in mm of a hole that prints.
+	MinHoleDiameter float64
+}
+
+// Issue is a feature of a part that is hard to print.
+type Issue struct {
+	Kind Kind `json:"kind"`
+
+	// Position is the center of the feature in the part's print orientation.
+	Position mgl64.Vec3 `json:"position"`
+
+	Message string `json:"message"`
+}
+
+const (
+	// floorTolerance is the height in mm above the build plate below which
+	// surfaces count as lying on it.
+	floorTolerance = 1e-4
+
+	// horizontalTolerance is the angle in degrees from horizontal below which
+	// surfaces count as horizontal, so that they are bridged.
+	horizontalTolerance = 1
+
+	// minimumArea is the area in mm² below which overhangs are ignored. The
+	// mesh contains slivers, where surfaces meet, that slicers ignore as well.
+	minimumArea = 1
+)
+
+// Check finds every issue of a part, given its mesh and its holes in print
+// orientation.
+func Check(partMesh *mesh.Mesh, holes []mesh.Hole, settings Settings) []Issue {
+	issues := Overhangs(partMesh, settings)
+	issues = append(issues, ThinWalls(partMesh, settings)...)
+	issues = append(issues, SmallHoles(holes, settings)...)
+
+	return issues
+}
+
+// Overhangs finds the surfaces that face down more steeply than the maximum
+// overhang angle and do not lie on the build plate. Horizontal surfaces are
+// bridged, so they are only reported if they are wider than the maximum
+// bridge length in both directions.
+func Overhangs(partMesh *mesh.Mesh, settings Settings) []Issue {
+	floor := partMesh.Bounds().Min.Z()
+
+	var overhangs, bridges []face
+	for _, triangle := range partMesh.Triangles {
+		normal := triangle.Normal()
+		if normal.Z() >= 0 || onFloor(triangle, floor) {
+			continue
+		}
+		angle := overhangAngle(normal)
+		switch {
+		case angle > 90-horizontalTolerance:
+			bridges = append(bridges, face{triangle: triangle, value: angle})
+		case angle > settings.MaxOverhangAngle:
+			overhangs = append(overhangs, face{triangle: triangle, value: angle})
+		}
+	}
+
+	var issues []Issue
+	for _, region := range regions(overhangs, touchTolerance) {
+		if region.area < minimumArea {
+			continue
+		}
+		issues = append(issues, Issue{
+			Kind:     KindOverhang,
+			Position: region.center(),
+			Message:  fmt.Sprintf("%.1f mm² overhang at up to %.0f° from vertical", region.area, region.maximum),
+		})
+	}
+	for _, region := range regions(bridges, touchTolerance) {
+		size := region.bounds.Size()
+		span := min(size.X(), size.Y())
+		if region.area < minimumArea || span <= settings.MaxBridgeLength {
+			continue
+		}
+		issues = append(issues, Issue{
+			Kind:     KindBridge,
+			Position: region.center(),
+			Message:  fmt.Sprintf("%.1f x %.1f mm ceiling bridges %.1f mm", size.X(), size.Y(), span),
+		})
+	}
+
+	return issues
+}
+
+// overhangAngle returns the angle in degrees between a surface with the given
+// normal and the vertical.
+func overhangAngle(normal mgl64.Vec3) float64 {
+	return mgl64.RadToDeg(math.Asin(math.Min(1, math.Abs(normal.Z()))))
+}
+
+func onFloor(triangle mesh.Triangle, floor float64) bool {
+	for _, vertex := range triangle {
+		if vertex.Z()-floor > floorTolerance {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ThinWalls finds the surfaces that are closer to the opposite side of the
+// part than the minimum wall thickness. The thickness is measured from the
+// center of every triangle straight into the part.
+func ThinWalls(partMesh *mesh.Mesh, settings Settings) []Issue {
+	if settings.MinWallThickness <= 0 {
+		return nil
+	}
+
+	triangles := partMesh.Triangles
+	boxes := make([]mesh.Box, len(triangles))
+	normals := make([]mgl64.Vec3, len(triangles))
+	for index, triangle := range triangles {
+		boxes[index] = triangleBounds(triangle)
+		normals[index] = triangle.Normal()
+	}
+
+	var thin []face
+	for index, triangle := range triangles {
+		normal := normals[index]
+		if normal == (mgl64.Vec3{}) {
+			continue
+		}
+		origin := triangle[0].Add(triangle[1]).Add(triangle[2]).Mul(1.0 / 3)
+		end := origin.Sub(normal.Mul(settings.MinWallThickness))
+		ray := mesh.EmptyBox().Extend(origin).Extend(end)
+
+		thickness := math.Inf(1)
+		for other, otherTriangle := range triangles {
+			// Only the opposite side of a wall faces the other way.
+			if other == index || normals[other].Dot(normal) >= 0 || ray.Intersection(boxes[other]).IsEmpty() {
+				continue
+			}
+			if distance, ok := intersect(origin, normal.Mul(-1), otherTriangle); ok && distance > floorTolerance {
+				thickness = min(thickness, distance)
+			}
+		}
+		if thickness < settings.MinWallThickness {
+			thin = append(thin, face{triangle: triangle, value: thickness})
+		}
+	}
+
+	// Both sides of a thin wall are reported as one.
+	var issues []Issue
+	for _, region := range regions(thin, settings.MinWallThickness) {
+		if region.area < minimumArea {
+			continue
+		}
+		issues = append(issues, Issue{
+			Kind:     KindThinWall,
+			Position: region.center(),
+			Message:  fmt.Sprintf("%.1f mm² of wall are %.2f mm thin, thinner than %.2f mm", region.area, region.minimum, settings.MinWallThickness),
+		})
+	}
+
+	return issues
+}
+
+// intersect returns the distance along direction from origin at which the ray
+// hits triangle, using the Möller–Trumbore algorithm.
+func intersect(origin, direction mgl64.Vec3, triangle mesh.Triangle) (float64, bool) {
+	edge1 := triangle[1].Sub(triangle[0])
+	edge2 := triangle[2].Sub(triangle[0])
+	p := direction.Cross(edge2)
+	determinant := edge1.Dot(p)
+	if math.Abs(determinant) < 1e-12 {
+		return 0, false
+	}
+	inverse := 1 / determinant
+
+	offset := origin.Sub(triangle[0])
+	u := offset.Dot(p) * inverse
+	if u < 0 || u > 1 {
+		return 0, false
+	}
+	q := offset.Cross(edge1)
+	v := direction.Dot(q) * inverse
+	if v < 0 || u+v > 1 {
+		return 0, false
+	}
+
+	distance := edge2.Dot(q) * inverse
+
+	return distance, distance >= 0
+}
+
+// SmallHoles finds the holes that are narrower than the minimum hole
+// diameter.
+func SmallHoles(holes []mesh.Hole, settings Settings) []Issue {
+	var issues []Issue
+	for _, hole := range holes {
+		if hole.Diameter >= settings.MinHoleDiameter {
+			continue
+		}
+		direction := "vertical"
+		if math.Abs(hole.Axis.Z()) < math.Sqrt2/2 {
+			direction = "horizontal"
+		}
+		issues = append(issues, Issue{
+			Kind:     KindSmallHole,
+			Position: hole.Center,
+			Message:  fmt.Sprintf("%s hole of %.2f mm is narrower than %.2f mm", direction, hole.Diameter, settings.MinHoleDiameter),
+		})
+	}
+
+	return issues
+}
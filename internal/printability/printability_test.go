@@ -0,0 +1,127 @@
+package printability
+
+import (
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yeldiRium/3d-rack-brackets/internal/mesh"
+)
+
+var settings = Settings{
+	MaxOverhangAngle: 45,
+	MaxBridgeLength:  10,
+	MinWallThickness: 0.8,
+	MinHoleDiameter:  2,
+}
+
+func kinds(issues []Issue) []Kind {
+	result := make([]Kind, 0, len(issues))
+	for _, issue := range issues {
+		result = append(result, issue.Kind)
+	}
+
+	return result
+}
+
+func TestOverhangs(t *testing.T) {
+	t.Parallel()
+
+	t.Run("ignores surfaces on the build plate.", func(t *testing.T) {
+		t.Parallel()
+
+		partMesh, err := mesh.FromSCAD("cube(10);")
+		require.NoError(t, err)
+
+		assert.Empty(t, Overhangs(partMesh, settings))
+	})
+
+	t.Run("reports surfaces that face down too steeply.", func(t *testing.T) {
+		t.Parallel()
+
+		// A wedge whose bottom rises at 30° from the horizontal, 60° from the
+		// vertical.
+		partMesh, err := mesh.FromSCAD("rotate([90, 0, 0]) linear_extrude(height=10) polygon([[0, 0], [10, 0], [20, 5.773503], [20, 10], [0, 10]]);")
+		require.NoError(t, err)
+
+		issues := Overhangs(partMesh, settings)
+
+		require.Len(t, issues, 1)
+		assert.Equal(t, KindOverhang, issues[0].Kind)
+		assert.Equal(t, "115.5 mm² overhang at up to 60° from vertical", issues[0].Message)
+	})
+
+	t.Run("allows surfaces up to the maximum angle.", func(t *testing.T) {
+		t.Parallel()
+
+		partMesh, err := mesh.FromSCAD("rotate([90, 0, 0]) linear_extrude(height=10) polygon([[0, 0], [10, 0], [15, 10], [0, 10]]);")
+		require.NoError(t, err)
+
+		assert.Empty(t, Overhangs(partMesh, settings))
+	})
+
+	t.Run("reports ceilings that are too wide to bridge.", func(t *testing.T) {
+		t.Parallel()
+
+		narrow, err := mesh.FromSCAD("difference() { cube([20, 20, 10]); translate([5, -1, 0]) cube([8, 22, 5]); }")
+		require.NoError(t, err)
+		wide, err := mesh.FromSCAD("difference() { cube([20, 20, 10]); translate([2, -1, 0]) cube([16, 22, 5]); }")
+		require.NoError(t, err)
+
+		assert.Empty(t, Overhangs(narrow, settings))
+		issues := Overhangs(wide, settings)
+		require.Len(t, issues, 1)
+		assert.Equal(t, KindBridge, issues[0].Kind)
+		assert.Equal(t, mgl64.Vec3{10, 10, 5}, issues[0].Position)
+	})
+}
+
+func TestThinWalls(t *testing.T) {
+	t.Parallel()
+
+	t.Run("reports walls thinner than the minimum.", func(t *testing.T) {
+		t.Parallel()
+
+		partMesh, err := mesh.FromSCAD("cube([10, 0.5, 10]);")
+		require.NoError(t, err)
+
+		issues := ThinWalls(partMesh, settings)
+
+		require.Len(t, issues, 1)
+		assert.Equal(t, KindThinWall, issues[0].Kind)
+		assert.Equal(t, "200.0 mm² of wall are 0.50 mm thin, thinner than 0.80 mm", issues[0].Message)
+	})
+
+	t.Run("ignores walls that are thick enough.", func(t *testing.T) {
+		t.Parallel()
+
+		partMesh, err := mesh.FromSCAD("difference() { cube([10, 10, 10]); translate([1, 1, 1]) cube([8, 8, 10]); }")
+		require.NoError(t, err)
+
+		assert.Empty(t, ThinWalls(partMesh, settings))
+	})
+}
+
+func TestSmallHoles(t *testing.T) {
+	t.Parallel()
+
+	t.Run("reports holes narrower than the minimum.", func(t *testing.T) {
+		t.Parallel()
+
+		holes, err := mesh.HolesFromSCAD(`difference() {
+cube(10);
+cylinder(h=10, r1=0.75, r2=0.75);
+translate([5, 5, 5]) rotate([90, 0, 0]) cylinder(h=10, r1=0.5, r2=0.5, center=true);
+translate([8, 8, 0]) cylinder(h=10, r1=1.5, r2=1.5);
+}`)
+		require.NoError(t, err)
+
+		issues := SmallHoles(holes, settings)
+
+		assert.Equal(t, []Kind{KindSmallHole, KindSmallHole}, kinds(issues))
+		assert.Equal(t, "vertical hole of 1.50 mm is narrower than 2.00 mm", issues[0].Message)
+		assert.Equal(t, "horizontal hole of 1.00 mm is narrower than 2.00 mm", issues[1].Message)
+	})
+}
@@ -0,0 +1,91 @@
+package printability
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl64"
+
+	"github.com/yeldiRium/3d-rack-brackets/internal/mesh"
+)
+
+// touchTolerance is the distance in mm within which triangles count as
+// touching.
+const touchTolerance = 1e-4
+
+// face is a triangle with an issue, along with a measure of it, e.g. the
+// thickness of the wall there.
+type face struct {
+	triangle mesh.Triangle
+	value    float64
+}
+
+// region is a connected patch of faces, which is reported as one issue.
+type region struct {
+	bounds mesh.Box
+	area   float64
+
+	// minimum and maximum are the extremes of the faces' values.
+	minimum, maximum float64
+}
+
+func (region region) center() mgl64.Vec3 {
+	return region.bounds.Min.Add(region.bounds.Max).Mul(0.5)
+}
+
+// regions groups faces into regions of faces that are within tolerance of
+// each other. The distance is measured between their bounding boxes, which
+// joins faces that share an edge even if the mesh has T-junctions there.
+func regions(faces []face, tolerance float64) []region {
+	boxes := make([]mesh.Box, len(faces))
+	for index, face := range faces {
+		boxes[index] = triangleBounds(face.triangle)
+	}
+
+	parents := make([]int, len(faces))
+	for index := range parents {
+		parents[index] = index
+	}
+	root := func(index int) int {
+		for parents[index] != index {
+			parents[index] = parents[parents[index]]
+			index = parents[index]
+		}
+
+		return index
+	}
+
+	for index := range faces {
+		grown := mesh.Box{
+			Min: boxes[index].Min.Sub(mgl64.Vec3{tolerance, tolerance, tolerance}),
+			Max: boxes[index].Max.Add(mgl64.Vec3{tolerance, tolerance, tolerance}),
+		}
+		for other := index + 1; other < len(faces); other++ {
+			if !grown.Intersection(boxes[other]).IsEmpty() {
+				parents[root(other)] = root(index)
+			}
+		}
+	}
+
+	indices := map[int]int{}
+	var result []region
+	for index, face := range faces {
+		group, ok := indices[root(index)]
+		if !ok {
+			group = len(result)
+			indices[root(index)] = group
+			result = append(result, region{bounds: mesh.EmptyBox(), minimum: math.Inf(1), maximum: math.Inf(-1)})
+		}
+
+		region := &result[group]
+		region.bounds = region.bounds.Union(boxes[index])
+		region.area += face.triangle.Area()
+		region.minimum = min(region.minimum, face.value)
+		region.maximum = max(region.maximum, face.value)
+	}
+
+	return result
+}
+
+func triangleBounds(triangle mesh.Triangle) mesh.Box {
+	return mesh.EmptyBox().Extend(triangle[0]).Extend(triangle[1]).Extend(triangle[2])
+}
@@ -2,16 +2,30 @@ package shapes
 
 import (
 	"errors"
+	"fmt"
+	"maps"
 	"math"
+	"slices"
+	"strings"
 
 	"github.com/go-gl/mathgl/mgl64"
-	"github.com/ljanyst/ghostscad/primitive"
 
-	"github.com/yeldiRium/3d-rack-brackets/internal/ghostscad"
+	"github.com/yeldiRium/3d-rack-brackets/internal/mesh"
 )
 
 var (
-	ErrAnchorAlreadyConnected = errors.New("anchor already has different connection")
+	ErrAnchorAlreadyConnected     = errors.New("anchor already has different connection")
+	ErrConflictingAnchorTransform = errors.New("trying to set conflicting anchor transforms")
+)
+
+const (
+	// positionTolerance is the distance in mm below which two placements of a
+	// part are considered identical.
+	positionTolerance = 1e-6
+
+	// angleTolerance is the angle in degrees below which two placements of a
+	// part are considered identical.
+	angleTolerance = 1e-6
 )
 
 type Anchor interface {
@@ -19,8 +33,9 @@ type Anchor interface {
 	Parent() Anchored
 	Connect(target Anchor, angle float64) error
 	Connection() *anchorConnection
-	Translation() *primitive.Transform
+	Translation() mgl64.Vec3
 	Normal() mgl64.Vec3
+	Up() mgl64.Vec3
 }
 
 type anchor struct {
@@ -32,27 +47,61 @@ type anchor struct {
 	parent Anchored
 
 	// translation is a translation from the anchored parent's origin to the an-
-	// chor. Thus applying the inverse transform to the anchor results in the an-
-	// chored parent's origin.
-	translation *primitive.Transform
+	// chor. Thus subtracting it from the anchor results in the anchored par-
+	// ent's origin.
+	translation mgl64.Vec3
 
 	// normal is the direction in which the anchor connects. When connecting to
 	// another anchor, they will be rotated so that their normals are opposite
 	// each other.
 	normal mgl64.Vec3
 
+	// up is a unit vector orthogonal to the normal. Together with the normal it
+	// fixes the anchor's roll: when connecting to another anchor, the ups of
+	// both anchors are aligned before the connection's angle is applied.
+	up mgl64.Vec3
+
 	// connectedAnchor is a second anchor that is connected to this one. Each an-
 	// chor can always be tied to at most one other anchor.
 	connection *anchorConnection
 }
 
-func NewAnchor(name string, parent Anchored, translation *primitive.Transform, normal mgl64.Vec3) *anchor {
+// NewAnchor creates an anchor whose up is derived from its normal, see
+// defaultUp.
+func NewAnchor(name string, parent Anchored, translation mgl64.Vec3, normal mgl64.Vec3) *anchor {
+	return NewOrientedAnchor(name, parent, translation, normal, defaultUp(normal))
+}
+
+// NewOrientedAnchor creates an anchor with an explicit up. The up does not have
+// to be orthogonal to the normal, only its part that is orthogonal to the
+// normal is used.
+func NewOrientedAnchor(name string, parent Anchored, translation mgl64.Vec3, normal mgl64.Vec3, up mgl64.Vec3) *anchor {
+	normal = normal.Normalize()
+	up = up.Sub(normal.Mul(normal.Dot(up)))
+	if up.Len() < parallelEpsilon {
+		panic("the up of an anchor must not be parallel to its normal")
+	}
+
 	return &anchor{
 		name:        name,
 		parent:      parent,
 		translation: translation,
 		normal:      normal,
+		up:          up.Normalize(),
+	}
+}
+
+// defaultUp returns the up of anchors that do not specify one. It is the
+// direction closest to the z axis that is orthogonal to normal, or the y axis
+// if normal is parallel to the z axis.
+func defaultUp(normal mgl64.Vec3) mgl64.Vec3 {
+	normal = normal.Normalize()
+	reference := mgl64.Vec3{0, 0, 1}
+	if normal.Cross(reference).Len() < parallelEpsilon {
+		reference = mgl64.Vec3{0, 1, 0}
 	}
+
+	return reference.Sub(normal.Mul(normal.Dot(reference))).Normalize()
 }
 
 func (anchor *anchor) Name() string {
@@ -63,18 +112,22 @@ func (anchor *anchor) Parent() Anchored { //nolint:ireturn
 	return anchor.parent
 }
 
+// Connect connects the anchor to target. The target is placed so that its nor-
+// mal points against the anchor's normal and its up is the anchor's up rolled
+// by angle degrees around the anchor's normal. Seen from the target, the
+// anchor is rolled by the same angle around the target's normal, so both an-
+// chors store the same angle.
 func (anchor *anchor) Connect(target Anchor, angle float64) error {
 	if target == nil {
 		panic("target must not be nil")
 	}
 
-	inverseAngle := math.Mod(360-angle, 360)
 	if anchor.connection != nil &&
 		anchor.connection.target == target &&
 		anchor.connection.angle == angle &&
 		target.Connection() != nil &&
 		target.Connection().Target() == anchor &&
-		target.Connection().Angle() == inverseAngle {
+		target.Connection().Angle() == angle {
 		return nil
 	}
 
@@ -83,7 +136,7 @@ func (anchor *anchor) Connect(target Anchor, angle float64) error {
 	}
 	targetConnection := target.Connection()
 	if targetConnection != nil {
-		if targetConnection.Target() != anchor || targetConnection.Angle() != inverseAngle {
+		if targetConnection.Target() != anchor || targetConnection.Angle() != angle {
 			return ErrAnchorAlreadyConnected
 		}
 	}
@@ -92,7 +145,7 @@ func (anchor *anchor) Connect(target Anchor, angle float64) error {
 		target: target,
 		angle:  angle,
 	}
-	err := target.Connect(anchor, inverseAngle)
+	err := target.Connect(anchor, angle)
 	if err != nil {
 		return err
 	}
@@ -104,7 +157,7 @@ func (anchor *anchor) Connection() *anchorConnection {
 	return anchor.connection
 }
 
-func (anchor *anchor) Translation() *primitive.Transform {
+func (anchor *anchor) Translation() mgl64.Vec3 {
 	return anchor.translation
 }
 
@@ -112,6 +165,10 @@ func (anchor *anchor) Normal() mgl64.Vec3 {
 	return anchor.normal
 }
 
+func (anchor *anchor) Up() mgl64.Vec3 {
+	return anchor.up
+}
+
 type AnchorConnection interface {
 	Target() Anchor
 	Angle() float64
@@ -136,14 +193,26 @@ func (c *anchorConnection) WasResolved() bool {
 	return c.wasResolved
 }
 
+// Anchored is a shape that can be connected to other shapes via anchors. Its
+// anchor transform is the matrix that moves the shape from its own origin to
+// its place in the assembly, as calculated by ResolveAnchors.
 type Anchored interface {
+	Name() string
 	Anchors() map[string]Anchor
-	SetAnchorTransform(t primitive.Transform) error
-	GetAnchorTransform() *primitive.Transform
+	SetAnchorTransform(t mgl64.Mat4) error
+	GetAnchorTransform() *mgl64.Mat4
+
+	// Bounds returns the bounding box of the shape at its place in the
+	// assembly, moved by transform. It fails if the anchors were not resolved.
+	Bounds(transform mgl64.Mat4) (mesh.Box, error)
 }
 
+// ResolveAnchors places every part that is connected to start, directly or
+// indirectly, relative to start. Assemblies may contain loops, as long as every
+// path to a part places it at the same spot. Otherwise a *ConflictingPlacement-
+// Error is returned.
 func ResolveAnchors(start Anchored) error {
-	err := start.SetAnchorTransform(*primitive.NewTranslation(mgl64.Vec3{}))
+	err := start.SetAnchorTransform(mgl64.Ident4())
 	if err != nil {
 		return err
 	}
@@ -151,6 +220,9 @@ func ResolveAnchors(start Anchored) error {
 
 	processedAnchoreds := map[Anchored]bool{}
 
+	// paths records the connections via which each part was placed.
+	paths := map[Anchored][]string{start: nil}
+
 	for len(anchoredQueue) > 0 {
 		currentAnchored := anchoredQueue[0]
 		anchoredQueue = anchoredQueue[1:]
@@ -166,32 +238,46 @@ func ResolveAnchors(start Anchored) error {
 			panic("an already processed anchored unexpectedly has no transform. this should never happen")
 		}
 
-		for _, anchor := range currentAnchored.Anchors() {
+		// The anchors are visited in a fixed order, so that loops are always
+		// closed at the same connection.
+		anchors := currentAnchored.Anchors()
+		for _, name := range slices.Sorted(maps.Keys(anchors)) {
+			anchor := anchors[name]
 			connection := anchor.Connection()
 			if connection == nil || connection.wasResolved {
 				continue
 			}
 
 			targetAnchor := connection.Target()
-			angle := connection.Angle()
 			targetAnchored := targetAnchor.Parent()
 
-			matchAnchorOrientationRotation := calculateRotationFromVec3ToVec3(anchor.Normal(), targetAnchor.Normal().Mul(-1))
-
-			matchAnchorOrientation := primitive.NewRotation(matchAnchorOrientationRotation)
-			rotateAroundConnection := primitive.NewRotationByAxis(angle, anchor.Normal())
-			moveByStartAnchor := anchor.Translation()
-			moveByTargetAnchor := targetAnchor.Translation().Inverse()
-
-			targetTransformation := ghostscad.CloneTransform(currentTransform)
-			targetTransformation.Append(moveByStartAnchor)
-			targetTransformation.Append(rotateAroundConnection)
-			targetTransformation.Append(matchAnchorOrientation)
-			targetTransformation.Append(moveByTargetAnchor)
-
-			err := targetAnchored.SetAnchorTransform(*targetTransformation)
-			if err != nil {
-				return err
+			targetTransformation := currentTransform.Mul4(connectionTransform(anchor, targetAnchor, connection.Angle()))
+			targetPath := append(slices.Clone(paths[currentAnchored]), fmt.Sprintf(
+				"%s.%s -> %s.%s",
+				currentAnchored.Name(), anchor.Name(),
+				targetAnchored.Name(), targetAnchor.Name(),
+			))
+
+			if existingTransform := targetAnchored.GetAnchorTransform(); existingTransform != nil {
+				distance, angle := placementDiscrepancy(*existingTransform, targetTransformation)
+				if distance > positionTolerance || angle > angleTolerance {
+					firstPath, wasPlacedHere := paths[targetAnchored]
+
+					return &ConflictingPlacementError{
+						Part:                   targetAnchored.Name(),
+						FirstPath:              firstPath,
+						SecondPath:             targetPath,
+						Distance:               distance,
+						Angle:                  angle,
+						placedBeforeResolution: !wasPlacedHere,
+					}
+				}
+			} else {
+				err := targetAnchored.SetAnchorTransform(targetTransformation)
+				if err != nil {
+					return err
+				}
+				paths[targetAnchored] = targetPath
 			}
 			connection.wasResolved = true
 			targetAnchor.Connection().wasResolved = true
@@ -203,55 +289,147 @@ func ResolveAnchors(start Anchored) error {
 	return nil
 }
 
-// TODO: fix rotation for parallel vectors.
-func calculateRotationFromVec3ToVec3(from, to mgl64.Vec3) mgl64.Vec3 {
-	axis := from.Cross(to).Normalize()
-	if math.IsNaN(axis[0]) {
-		// If any of the axis' values are NaN, from and to are parallel and we can
-		// choose any orthogonal
-		axis = findOrthogonal(from)
+// ConflictingPlacementError is returned by ResolveAnchors when two paths
+// through the anchor graph place a part at different spots.
+type ConflictingPlacementError struct {
+	// Part is the name of the part that was placed inconsistently.
+	Part string
+
+	// FirstPath and SecondPath list the connections via which the part was
+	// placed, starting at the part the resolution started at.
+	FirstPath  []string
+	SecondPath []string
+
+	// Distance is the distance between both placements in mm, Angle the angle
+	// in degrees by which they are rotated against each other.
+	Distance float64
+	Angle    float64
+
+	// placedBeforeResolution is set if the part was placed before the
+	// resolution started, so that there is no first path.
+	placedBeforeResolution bool
+}
+
+func (err *ConflictingPlacementError) Error() string {
+	formatPath := func(path []string) string {
+		if len(path) == 0 {
+			return "as the start of the resolution"
+		}
+
+		return "via " + strings.Join(path, ", ")
+	}
 
-		// TODO: rotate by 180deg or 0deg, dependening on orientation of from and to
+	first := formatPath(err.FirstPath)
+	if err.placedBeforeResolution {
+		first = "before the resolution started"
 	}
-	angle := math.Acos(from.Normalize().Dot(to.Normalize()))
 
-	rotationMatrix := mgl64.HomogRotate3D(angle, axis)
-	eulerAngles := eulerAngles(rotationMatrix)
+	return fmt.Sprintf(
+		"%s: %s is placed %s, but %s it is %.6g mm and %.6g° away from that",
+		ErrConflictingAnchorTransform, err.Part, first, formatPath(err.SecondPath), err.Distance, err.Angle,
+	)
+}
+
+func (err *ConflictingPlacementError) Unwrap() error {
+	return ErrConflictingAnchorTransform
+}
 
-	return mgl64.Vec3{
-		mgl64.RadToDeg(eulerAngles[0]),
-		mgl64.RadToDeg(eulerAngles[1]),
-		mgl64.RadToDeg(eulerAngles[2]),
+// placementDiscrepancy calculates how far apart two placements of a part are,
+// as the distance between their origins in mm and the angle in degrees by
+// which they are rotated against each other.
+func placementDiscrepancy(a, b mgl64.Mat4) (distance, angle float64) {
+	distance = a.Col(3).Vec3().Sub(b.Col(3).Vec3()).Len()
+
+	// The trace of the rotation from a to b is 1 + 2·cos(angle), and its skew
+	// part is sin(angle) times its axis. atan2 stays precise for angles close
+	// to 0°, where acos turns rounding errors into microdegrees.
+	relativeRotation := a.Mat3().Transpose().Mul3(b.Mat3())
+	skew := mgl64.Vec3{
+		relativeRotation.At(2, 1) - relativeRotation.At(1, 2),
+		relativeRotation.At(0, 2) - relativeRotation.At(2, 0),
+		relativeRotation.At(1, 0) - relativeRotation.At(0, 1),
 	}
+	angle = mgl64.RadToDeg(math.Atan2(skew.Len(), relativeRotation.Trace()-1))
+
+	return distance, angle
 }
 
-// To find an orthogonal u for v, we need to set u dot v = 0
-// u1 * v1 + u2 * v2 + u3 * v3 = 0
-// setting u1 = 1 and u2 = 1 we get
-// v1 + v2 + u3 * v3 = 0
-// u3 = (-v1 - v2) / v3.
-func findOrthogonal(v mgl64.Vec3) mgl64.Vec3 {
-	z := (-v[0] - v[1]) / v[2]
+// connectionTransform calculates the transform from the origin of anchor's
+// parent to the origin of target's parent, when target is connected to anchor
+// with the given angle.
+func connectionTransform(anchor, target Anchor, angle float64) mgl64.Mat4 {
+	moveByStartAnchor := mgl64.Translate3D(anchor.Translation().Elem())
+	matchAnchorOrientation := connectionRotation(anchor, target, angle)
+	moveByTargetAnchor := mgl64.Translate3D(target.Translation().Mul(-1).Elem())
+
+	return moveByStartAnchor.
+		Mul4(matchAnchorOrientation).
+		Mul4(moveByTargetAnchor)
+}
+
+// connectionRotation calculates the rotation that turns target's normal
+// against anchor's normal and target's up onto anchor's up rolled by angle
+// degrees around anchor's normal.
+func connectionRotation(anchor, target Anchor, angle float64) mgl64.Mat4 {
+	normal := anchor.Normal().Normalize()
+
+	// The target is turned so that its anchor's normal points against the
+	// normal of the anchor it connects to. This leaves its up somewhere in
+	// the plane orthogonal to the normal.
+	matchNormals := rotationFromVec3ToVec3(target.Normal(), normal.Mul(-1))
+	turnedUp := mgl64.TransformNormal(target.Up(), matchNormals)
 
-	return mgl64.Vec3{1, 1, z}
+	// Then it is rolled around the normal until the ups match.
+	rolledUp := mgl64.TransformNormal(anchor.Up(), mgl64.HomogRotate3D(mgl64.DegToRad(angle), normal))
+	roll := math.Atan2(normal.Dot(turnedUp.Cross(rolledUp)), turnedUp.Dot(rolledUp))
+
+	return mgl64.HomogRotate3D(roll, normal).Mul4(matchNormals)
 }
 
-// eulerAngles takes a radians rotation matrix and calculates its radians euler angles.
-func eulerAngles(rotationMatrix mgl64.Mat4) mgl64.Vec3 {
-	sy := math.Sqrt(rotationMatrix[0]*rotationMatrix[0] + rotationMatrix[1]*rotationMatrix[1])
-
-	singular := sy < 1e-6
-
-	var x, y, z float64
-	if !singular {
-		x = math.Atan2(rotationMatrix[6], rotationMatrix[10])
-		y = math.Atan2(-rotationMatrix[2], sy)
-		z = math.Atan2(rotationMatrix[1], rotationMatrix[0])
-	} else {
-		x = math.Atan2(-rotationMatrix[9], rotationMatrix[5])
-		y = math.Atan2(-rotationMatrix[2], sy)
-		z = 0
+// parallelEpsilon is the length of the cross product of two unit vectors below
+// which they are treated as parallel.
+const parallelEpsilon = 1e-12
+
+// rotationFromVec3ToVec3 calculates the rotation matrix of the shortest
+// rotation that turns from into to. Neither vector has to be normalized, but
+// both must have a length.
+func rotationFromVec3ToVec3(from, to mgl64.Vec3) mgl64.Mat4 {
+	from = from.Normalize()
+	to = to.Normalize()
+
+	axis := from.Cross(to)
+	sin := axis.Len()
+	cos := from.Dot(to)
+
+	if sin < parallelEpsilon {
+		if cos > 0 {
+			return mgl64.Ident4()
+		}
+
+		// from and to point in opposite directions, so every axis orthogonal
+		// to them makes for a shortest rotation.
+		return mgl64.HomogRotate3D(math.Pi, findOrthogonal(from))
+	}
+
+	// atan2 stays precise for angles close to 0° and 180°, unlike acos.
+	return mgl64.HomogRotate3D(math.Atan2(sin, cos), axis.Mul(1/sin))
+}
+
+// findOrthogonal returns a unit vector orthogonal to v. It crosses v with the
+// coordinate axis that is least aligned with it, so the result is well-defined
+// for every v with a length, including vectors in a coordinate plane.
+func findOrthogonal(v mgl64.Vec3) mgl64.Vec3 {
+	absolute := mgl64.Vec3{math.Abs(v[0]), math.Abs(v[1]), math.Abs(v[2])}
+
+	var leastAligned mgl64.Vec3
+	switch {
+	case absolute[0] <= absolute[1] && absolute[0] <= absolute[2]:
+		leastAligned = mgl64.Vec3{1, 0, 0}
+	case absolute[1] <= absolute[2]:
+		leastAligned = mgl64.Vec3{0, 1, 0}
+	default:
+		leastAligned = mgl64.Vec3{0, 0, 1}
 	}
 
-	return mgl64.Vec3{x, y, z}
+	return v.Cross(leastAligned).Normalize()
 }
@@ -2,57 +2,39 @@ package shapes
 
 import (
 	"fmt"
+	"math"
+	"math/rand/v2"
 	"testing"
 
 	"github.com/go-gl/mathgl/mgl64"
 	"github.com/ljanyst/ghostscad/primitive"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
-
-	"github.com/yeldiRium/3d-rack-brackets/internal/test"
 )
 
 type FooAnchored struct {
-	primitive.ParentImpl
-	primitive.Cube
-
-	name            string
-	anchors         map[string]Anchor
-	anchorTransform *primitive.Transform
+	AnchoredBase
 }
 
 func NewFoo(name string, edge float64) *FooAnchored {
-	foo := &FooAnchored{
-		name: name,
-		Cube: *primitive.NewCube(mgl64.Vec3{edge, edge, edge}),
-	}
-	foo.anchors = map[string]Anchor{
-		"top":    NewAnchor("top", foo, primitive.NewTranslation(mgl64.Vec3{0, 0, edge / 2}), mgl64.Vec3{0, 0, 1}),
-		"bottom": NewAnchor("bottom", foo, primitive.NewTranslation(mgl64.Vec3{0, 0, -edge / 2}), mgl64.Vec3{0, 0, -1}),
-		"right":  NewAnchor("right", foo, primitive.NewTranslation(mgl64.Vec3{edge / 2, 0, 0}), mgl64.Vec3{1, 0, 0}),
-	}
+	foo := &FooAnchored{}
+	foo.AnchoredBase = MakeAnchoredBase(foo, name)
+	foo.Add(primitive.NewCube(mgl64.Vec3{edge, edge, edge}))
+	foo.AddAnchors(
+		NewAnchor("top", foo, mgl64.Vec3{0, 0, edge / 2}, mgl64.Vec3{0, 0, 1}),
+		NewAnchor("bottom", foo, mgl64.Vec3{0, 0, -edge / 2}, mgl64.Vec3{0, 0, -1}),
+		NewAnchor("right", foo, mgl64.Vec3{edge / 2, 0, 0}, mgl64.Vec3{1, 0, 0}),
+	)
 
 	return foo
 }
 
-func (foo *FooAnchored) Anchors() map[string]Anchor {
-	return foo.anchors
-}
+func assertMat4InDelta(t *testing.T, expected, actual mgl64.Mat4, delta float64, msgAndArgs ...any) {
+	t.Helper()
 
-func (foo *FooAnchored) SetAnchorTransform(transform primitive.Transform) error {
-	if foo.anchorTransform != nil {
-		// TODO check if the preexisting anchorTransform might be identical to
-		// transform. If so, don't return an error.
-		return fmt.Errorf("trying to set conflicting anchor transforms")
+	for i := range 16 {
+		assert.InDelta(t, expected[i], actual[i], delta, msgAndArgs...)
 	}
-
-	foo.anchorTransform = &transform
-
-	return nil
-}
-
-func (foo *FooAnchored) GetAnchorTransform() *primitive.Transform {
-	return foo.anchorTransform
 }
 
 func TestResolveAnchors(t *testing.T) {
@@ -70,19 +52,26 @@ func TestResolveAnchors(t *testing.T) {
 		err = ResolveAnchors(fooOne)
 		require.NoError(t, err)
 
-		expectedTransformFooOne := primitive.NewTranslation(mgl64.Vec3{0, 0, 0})
-		expectedTransformFooTwo := primitive.NewTranslation(mgl64.Vec3{})
-		expectedTransformFooTwo.Append(primitive.NewTranslation(mgl64.Vec3{0, 0, -3.5}))
-		expectedTransformFooTwo.Append(primitive.NewRotationByAxis(45, mgl64.Vec3{0, 0, -1}))
-		expectedTransformFooTwo.Append(primitive.NewRotation(mgl64.Vec3{0, 90, 0}))
-		expectedTransformFooTwo.Append(primitive.NewTranslation(mgl64.Vec3{-1, 0, 0}))
+		// fooTwo's right normal points up against fooOne's bottom normal. Its up,
+		// the z axis, is fooOne's up, the y axis, rolled by 45° around the
+		// bottom normal.
+		sqrtHalf := math.Sqrt(0.5)
+		expectedRotationFooTwo := mgl64.Mat4FromCols(
+			mgl64.Vec4{0, 0, 1, 0},
+			mgl64.Vec4{sqrtHalf, -sqrtHalf, 0, 0},
+			mgl64.Vec4{sqrtHalf, sqrtHalf, 0, 0},
+			mgl64.Vec4{0, 0, 0, 1},
+		)
+		expectedTransformFooTwo := mgl64.Translate3D(0, 0, -3.5).
+			Mul4(expectedRotationFooTwo).
+			Mul4(mgl64.Translate3D(-1, 0, 0))
 
-		test.RemoveParent(fooOne.anchorTransform.Items)
-		test.RemoveParent(fooTwo.anchorTransform.Items)
-		test.RemoveParent(expectedTransformFooOne.Items)
-		test.RemoveParent(expectedTransformFooTwo.Items)
-		assert.Equal(t, expectedTransformFooOne, fooOne.anchorTransform)
-		assert.Equal(t, expectedTransformFooTwo, fooTwo.anchorTransform)
+		assertMat4InDelta(t, mgl64.Ident4(), *fooOne.anchorTransform, 1e-12)
+		assertMat4InDelta(t, expectedTransformFooTwo, *fooTwo.anchorTransform, 1e-12)
+
+		// fooTwo's right anchor sits on fooOne's bottom anchor and faces it.
+		assertVec3InDelta(t, mgl64.Vec3{0, 0, -3.5}, mgl64.TransformCoordinate(mgl64.Vec3{1, 0, 0}, *fooTwo.anchorTransform), 1e-12)
+		assertVec3InDelta(t, mgl64.Vec3{0, 0, 1}, mgl64.TransformNormal(mgl64.Vec3{1, 0, 0}, *fooTwo.anchorTransform), 1e-12)
 	})
 
 	t.Run("resolves a transform correctly for opposite normals", func(t *testing.T) {
@@ -97,18 +86,314 @@ func TestResolveAnchors(t *testing.T) {
 		err = ResolveAnchors(fooOne)
 		require.NoError(t, err)
 
-		expectedTransformFooOne := primitive.NewTranslation(mgl64.Vec3{0, 0, 0})
-		expectedTransformFooTwo := primitive.NewTranslation(mgl64.Vec3{})
-		expectedTransformFooTwo.Append(primitive.NewTranslation(mgl64.Vec3{0, 0, -3.5}))
-		expectedTransformFooTwo.Append(primitive.NewRotationByAxis(0, mgl64.Vec3{0, 0, -1}))
-		expectedTransformFooTwo.Append(primitive.NewRotation(mgl64.Vec3{0, 0, 0})) // TODO: fix rotation
-		expectedTransformFooTwo.Append(primitive.NewTranslation(mgl64.Vec3{0, 0, -1}))
+		assertMat4InDelta(t, mgl64.Ident4(), *fooOne.anchorTransform, 1e-12)
+		assertMat4InDelta(t, mgl64.Translate3D(0, 0, -4.5), *fooTwo.anchorTransform, 1e-12)
+	})
+
+	t.Run("aligns explicit ups of connected anchors.", func(t *testing.T) {
+		t.Parallel()
+
+		fooOne := NewFoo("fooOne", 2)
+		fooTwo := NewFoo("fooTwo", 2)
+		fooOne.AddAnchors(NewOrientedAnchor("front", fooOne, mgl64.Vec3{0, -1, 0}, mgl64.Vec3{0, -1, 0}, mgl64.Vec3{1, 0, 0}))
+		fooTwo.AddAnchors(NewOrientedAnchor("back", fooTwo, mgl64.Vec3{0, 1, 0}, mgl64.Vec3{0, 1, 0}, mgl64.Vec3{0, 0, 1}))
+
+		err := fooOne.Anchors()["front"].Connect(fooTwo.Anchors()["back"], 0)
+		require.NoError(t, err)
+
+		err = ResolveAnchors(fooOne)
+		require.NoError(t, err)
+
+		assertVec3InDelta(t, mgl64.Vec3{0, -1, 0}, mgl64.TransformCoordinate(mgl64.Vec3{0, 1, 0}, *fooTwo.anchorTransform), 1e-12)
+		assertVec3InDelta(t, mgl64.Vec3{0, 1, 0}, mgl64.TransformNormal(mgl64.Vec3{0, 1, 0}, *fooTwo.anchorTransform), 1e-12)
+		assertVec3InDelta(t, mgl64.Vec3{1, 0, 0}, mgl64.TransformNormal(mgl64.Vec3{0, 0, 1}, *fooTwo.anchorTransform), 1e-12)
+	})
+
+	t.Run("places parts the same way regardless of which side is resolved first.", func(t *testing.T) {
+		t.Parallel()
+
+		relativeTransform := func(resolveFromTwo bool) mgl64.Mat4 {
+			fooOne := NewFoo("fooOne", 7)
+			fooTwo := NewFoo("fooTwo", 2)
+
+			err := fooOne.Anchors()["bottom"].Connect(fooTwo.Anchors()["right"], 70)
+			require.NoError(t, err)
+
+			if resolveFromTwo {
+				err = ResolveAnchors(fooTwo)
+			} else {
+				err = ResolveAnchors(fooOne)
+			}
+			require.NoError(t, err)
+
+			return fooOne.anchorTransform.Inv().Mul4(*fooTwo.anchorTransform)
+		}
+
+		assertMat4InDelta(t, relativeTransform(false), relativeTransform(true), 1e-12)
+	})
+
+	t.Run("accepts loops that place parts consistently.", func(t *testing.T) {
+		t.Parallel()
+
+		fooOne := NewFoo("fooOne", 2)
+		fooTwo := NewFoo("fooTwo", 2)
+		fooOne.AddAnchors(NewAnchor("top2", fooOne, mgl64.Vec3{0.5, 0, 1}, mgl64.Vec3{0, 0, 1}))
+		fooTwo.AddAnchors(NewAnchor("bottom2", fooTwo, mgl64.Vec3{0.5, 0, -1}, mgl64.Vec3{0, 0, -1}))
+
+		err := fooOne.Anchors()["top"].Connect(fooTwo.Anchors()["bottom"], 0)
+		require.NoError(t, err)
+		err = fooOne.Anchors()["top2"].Connect(fooTwo.Anchors()["bottom2"], 0)
+		require.NoError(t, err)
+
+		err = ResolveAnchors(fooOne)
+		require.NoError(t, err)
+
+		assertMat4InDelta(t, mgl64.Translate3D(0, 0, 2), *fooTwo.anchorTransform, 1e-12)
+		assert.True(t, fooTwo.Anchors()["bottom2"].Connection().WasResolved())
+	})
+
+	t.Run("accepts loops that place parts consistently at any angle.", func(t *testing.T) {
+		t.Parallel()
+
+		for angle := 1.0; angle < 360; angle++ {
+			fooOne := NewFoo("fooOne", 2)
+			fooTwo := NewFoo("fooTwo", 2)
+			// The second pair of anchors is offset from the first one on the same
+			// faces, so both connections place fooTwo the same way.
+			placement := connectionTransform(fooOne.Anchors()["right"], fooTwo.Anchors()["bottom"], angle)
+			translation := mgl64.Vec3{1, 0.5, 0.3}
+			fooOne.AddAnchors(NewAnchor("right2", fooOne, translation, mgl64.Vec3{1, 0, 0}))
+			fooTwo.AddAnchors(NewAnchor("bottom2", fooTwo, mgl64.TransformCoordinate(translation, placement.Inv()), mgl64.Vec3{0, 0, -1}))
+
+			err := fooOne.Anchors()["right"].Connect(fooTwo.Anchors()["bottom"], angle)
+			require.NoError(t, err)
+			err = fooOne.Anchors()["right2"].Connect(fooTwo.Anchors()["bottom2"], angle)
+			require.NoError(t, err)
+
+			err = ResolveAnchors(fooOne)
+			require.NoError(t, err, "angle %g", angle)
+		}
+	})
+
+	t.Run("reports loops that place parts inconsistently.", func(t *testing.T) {
+		t.Parallel()
+
+		fooOne := NewFoo("fooOne", 2)
+		fooTwo := NewFoo("fooTwo", 2)
+		fooThree := NewFoo("fooThree", 2)
+		fooOne.AddAnchors(NewAnchor("top2", fooOne, mgl64.Vec3{0.5, 0, 1}, mgl64.Vec3{0, 0, 1}))
+		fooThree.AddAnchors(NewAnchor("bottom2", fooThree, mgl64.Vec3{0.4, 0, -1}, mgl64.Vec3{0, 0, -1}))
+
+		err := fooOne.Anchors()["right"].Connect(fooTwo.Anchors()["bottom"], 0)
+		require.NoError(t, err)
+		err = fooOne.Anchors()["top"].Connect(fooThree.Anchors()["bottom"], 0)
+		require.NoError(t, err)
+		err = fooOne.Anchors()["top2"].Connect(fooThree.Anchors()["bottom2"], 0)
+		require.NoError(t, err)
+
+		err = ResolveAnchors(fooOne)
+
+		var conflictingPlacementError *ConflictingPlacementError
+		require.ErrorAs(t, err, &conflictingPlacementError)
+		require.ErrorIs(t, err, ErrConflictingAnchorTransform)
+		assert.Equal(t, "fooThree", conflictingPlacementError.Part)
+		assert.Equal(t, []string{"fooOne.top -> fooThree.bottom"}, conflictingPlacementError.FirstPath)
+		assert.Equal(t, []string{"fooOne.top2 -> fooThree.bottom2"}, conflictingPlacementError.SecondPath)
+		assert.InDelta(t, 0.1, conflictingPlacementError.Distance, 1e-12)
+		assert.InDelta(t, 0, conflictingPlacementError.Angle, 1e-6)
+		assert.EqualError(
+			t,
+			err,
+			"trying to set conflicting anchor transforms: fooThree is placed via fooOne.top -> fooThree.bottom, "+
+				"but via fooOne.top2 -> fooThree.bottom2 it is 0.1 mm and 0° away from that",
+		)
+	})
+
+	t.Run("reports the angle between inconsistent placements.", func(t *testing.T) {
+		t.Parallel()
+
+		fooOne := NewFoo("fooOne", 2)
+		fooTwo := NewFoo("fooTwo", 2)
+		fooThree := NewFoo("fooThree", 2)
+
+		err := fooOne.Anchors()["top"].Connect(fooTwo.Anchors()["bottom"], 0)
+		require.NoError(t, err)
+		err = fooTwo.Anchors()["top"].Connect(fooThree.Anchors()["bottom"], 0)
+		require.NoError(t, err)
+		err = fooThree.Anchors()["right"].Connect(fooOne.Anchors()["right"], 0)
+		require.NoError(t, err)
+
+		err = ResolveAnchors(fooOne)
+
+		var conflictingPlacementError *ConflictingPlacementError
+		require.ErrorAs(t, err, &conflictingPlacementError)
+		assert.Equal(t, "fooTwo", conflictingPlacementError.Part)
+		assert.Equal(t, []string{"fooOne.top -> fooTwo.bottom"}, conflictingPlacementError.FirstPath)
+		assert.Equal(t, []string{"fooOne.right -> fooThree.right", "fooThree.bottom -> fooTwo.top"}, conflictingPlacementError.SecondPath)
+		assert.InDelta(t, math.Sqrt(20), conflictingPlacementError.Distance, 1e-12)
+		assert.InDelta(t, 180, conflictingPlacementError.Angle, 1e-9)
+	})
+
+	t.Run("places every part of a deep chain exactly.", func(t *testing.T) {
+		t.Parallel()
+
+		const chainLength = 13
+		const angle = 30
+
+		foos := make([]*FooAnchored, 0, chainLength)
+		for i := range chainLength {
+			foos = append(foos, NewFoo(fmt.Sprintf("foo%d", i), 2))
+		}
+		for i := range chainLength - 1 {
+			err := foos[i].Anchors()["bottom"].Connect(foos[i+1].Anchors()["top"], angle)
+			require.NoError(t, err)
+		}
+
+		err := ResolveAnchors(foos[0])
+		require.NoError(t, err)
+
+		// Each foo sits one edge below the previous one and is rolled by a
+		// further 30° around the connection axis, which points down.
+		for i, foo := range foos {
+			expected := mgl64.Translate3D(0, 0, -2*float64(i)).
+				Mul4(mgl64.HomogRotate3DZ(mgl64.DegToRad(-angle * float64(i))))
+
+			assertMat4InDelta(t, expected, *foo.anchorTransform, 1e-12, "foo %d", i)
+		}
+
+		// Twelve rolls by 30° make a full turn.
+		assertMat4InDelta(t, mgl64.Translate3D(0, 0, -24), *foos[12].anchorTransform, 1e-12)
+	})
+}
+
+var axisAlignedNormals = []mgl64.Vec3{
+	{1, 0, 0},
+	{-1, 0, 0},
+	{0, 1, 0},
+	{0, -1, 0},
+	{0, 0, 1},
+	{0, 0, -1},
+}
+
+func assertVec3InDelta(t *testing.T, expected, actual mgl64.Vec3, delta float64, msgAndArgs ...any) {
+	t.Helper()
+
+	for i := range 3 {
+		assert.InDelta(t, expected[i], actual[i], delta, msgAndArgs...)
+	}
+}
+
+func TestRotationFromVec3ToVec3(t *testing.T) {
+	t.Parallel()
+
+	t.Run("turns every axis-aligned normal into every other one.", func(t *testing.T) {
+		t.Parallel()
+
+		for _, from := range axisAlignedNormals {
+			for _, to := range axisAlignedNormals {
+				rotation := rotationFromVec3ToVec3(from, to)
+
+				rotated := rotation.Mul4x1(from.Vec4(0)).Vec3()
+				assertVec3InDelta(t, to, rotated, 1e-12, "from %v to %v", from, to)
+			}
+		}
+	})
+
+	t.Run("turns random vectors into each other.", func(t *testing.T) {
+		t.Parallel()
+
+		random := rand.New(rand.NewPCG(3, 14)) //nolint:gosec // deterministic test data
+		randomVec3 := func() mgl64.Vec3 {
+			for {
+				v := mgl64.Vec3{random.Float64()*2 - 1, random.Float64()*2 - 1, random.Float64()*2 - 1}
+				if v.Len() > 0.1 {
+					return v
+				}
+			}
+		}
+
+		for range 1000 {
+			from := randomVec3()
+			to := randomVec3()
+			rotation := rotationFromVec3ToVec3(from, to)
+
+			rotated := rotation.Mul4x1(from.Normalize().Vec4(0)).Vec3()
+			assertVec3InDelta(t, to.Normalize(), rotated, 1e-12, "from %v to %v", from, to)
+		}
+	})
+
+	// The rotation axis of nearly opposite vectors is their tiny cross product,
+	// so its direction is only known to about 1e-16 / 1e-7 = 1e-9.
+	t.Run("turns nearly parallel and anti-parallel vectors into each other.", func(t *testing.T) {
+		t.Parallel()
+
+		random := rand.New(rand.NewPCG(15, 92)) //nolint:gosec // deterministic test data
+		for range 1000 {
+			from := mgl64.Vec3{random.Float64()*2 - 1, random.Float64()*2 - 1, random.Float64()*2 - 1}.Normalize()
+			noise := mgl64.Vec3{random.Float64(), random.Float64(), random.Float64()}.Mul(1e-7)
+
+			for _, to := range []mgl64.Vec3{from.Add(noise), from.Mul(-1).Add(noise)} {
+				rotation := rotationFromVec3ToVec3(from, to)
+
+				rotated := rotation.Mul4x1(from.Vec4(0)).Vec3()
+				assertVec3InDelta(t, to.Normalize(), rotated, 1e-8, "from %v to %v", from, to)
+			}
+		}
+	})
+}
+
+func TestFindOrthogonal(t *testing.T) {
+	t.Parallel()
+
+	t.Run("finds a unit vector orthogonal to vectors in the coordinate planes.", func(t *testing.T) {
+		t.Parallel()
+
+		vectors := append([]mgl64.Vec3{{1, 1, 0}, {0, 3, -2}, {-5, 0, 1}}, axisAlignedNormals...)
+		for _, v := range vectors {
+			orthogonal := findOrthogonal(v)
+
+			assert.False(t, math.IsNaN(orthogonal[0]) || math.IsNaN(orthogonal[1]) || math.IsNaN(orthogonal[2]), "orthogonal of %v", v)
+			assert.InDelta(t, 0, orthogonal.Dot(v), 1e-12, "orthogonal of %v", v)
+			assert.InDelta(t, 1, orthogonal.Len(), 1e-12, "orthogonal of %v", v)
+		}
+	})
+}
+
+func TestNewOrientedAnchor(t *testing.T) {
+	t.Parallel()
+
+	t.Run("makes the up orthogonal to the normal.", func(t *testing.T) {
+		t.Parallel()
+
+		anchor := NewOrientedAnchor("anchor", nil, mgl64.Vec3{}, mgl64.Vec3{0, 0, 2}, mgl64.Vec3{1, 0, 1})
+
+		assertVec3InDelta(t, mgl64.Vec3{0, 0, 1}, anchor.Normal(), 1e-12)
+		assertVec3InDelta(t, mgl64.Vec3{1, 0, 0}, anchor.Up(), 1e-12)
+	})
+
+	t.Run("panics if the up is parallel to the normal.", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Panics(t, func() {
+			NewOrientedAnchor("anchor", nil, mgl64.Vec3{}, mgl64.Vec3{0, 0, 1}, mgl64.Vec3{0, 0, -3})
+		})
+	})
+}
+
+func TestDefaultUp(t *testing.T) {
+	t.Parallel()
+
+	t.Run("points as far up the z axis as possible.", func(t *testing.T) {
+		t.Parallel()
+
+		assertVec3InDelta(t, mgl64.Vec3{0, 0, 1}, defaultUp(mgl64.Vec3{1, 0, 0}), 1e-12)
+		assertVec3InDelta(t, mgl64.Vec3{-0.5, 0, math.Sqrt(0.75)}, defaultUp(mgl64.Vec3{math.Sqrt(0.75), 0, 0.5}), 1e-12)
+	})
+
+	t.Run("uses the y axis for normals parallel to the z axis.", func(t *testing.T) {
+		t.Parallel()
 
-		test.RemoveParent(fooOne.anchorTransform.Items)
-		test.RemoveParent(fooTwo.anchorTransform.Items)
-		test.RemoveParent(expectedTransformFooOne.Items)
-		test.RemoveParent(expectedTransformFooTwo.Items)
-		assert.Equal(t, expectedTransformFooOne, fooOne.anchorTransform)
-		assert.Equal(t, expectedTransformFooTwo, fooTwo.anchorTransform)
+		assertVec3InDelta(t, mgl64.Vec3{0, 1, 0}, defaultUp(mgl64.Vec3{0, 0, 1}), 1e-12)
+		assertVec3InDelta(t, mgl64.Vec3{0, 1, 0}, defaultUp(mgl64.Vec3{0, 0, -4}), 1e-12)
 	})
 }
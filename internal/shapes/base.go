@@ -0,0 +1,182 @@
+package shapes
+
+import (
+	"bufio"
+	"fmt"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/ljanyst/ghostscad/primitive"
+
+	"github.com/yeldiRium/3d-rack-brackets/internal/ghostscad"
+	"github.com/yeldiRium/3d-rack-brackets/internal/mesh"
+)
+
+// AnchoredBase implements Anchored and primitive.Primitive for parts that are
+// placed via anchors. Parts embed it, add their geometry and anchors in their
+// constructor and get everything else for free:
+//
+//	type Part struct {
+//		shapes.AnchoredBase
+//	}
+//
+//	func NewPart(name string) *Part {
+//		part := &Part{}
+//		part.AnchoredBase = shapes.MakeAnchoredBase(part, name)
+//		part.Add(primitive.NewCube(mgl64.Vec3{1, 1, 1}))
+//		part.AddAnchors(shapes.NewAnchor("top", part, mgl64.Vec3{0, 0, 0.5}, mgl64.Vec3{0, 0, 1}))
+//
+//		return part
+//	}
+type AnchoredBase struct {
+	primitive.ParentImpl
+
+	// self is the part that embeds the base. It is returned by the modifier
+	// methods, so that they can be chained like those of other primitives.
+	self primitive.Primitive
+
+	prefix string
+
+	name     string
+	contents *primitive.List
+
+	anchors         map[string]Anchor
+	anchorTransform *mgl64.Mat4
+}
+
+// MakeAnchoredBase creates the base for the part self.
+func MakeAnchoredBase(self primitive.Primitive, name string) AnchoredBase {
+	return AnchoredBase{
+		self:     self,
+		name:     name,
+		contents: primitive.NewList(),
+		anchors:  map[string]Anchor{},
+	}
+}
+
+func (base *AnchoredBase) Name() string {
+	return base.name
+}
+
+// Add adds geometry to the part. It is placed relative to the part's origin.
+func (base *AnchoredBase) Add(items ...primitive.Primitive) {
+	base.contents.Add(items...)
+}
+
+// AddAnchors adds anchors to the part, keyed by their names.
+func (base *AnchoredBase) AddAnchors(anchors ...Anchor) {
+	for _, anchor := range anchors {
+		base.anchors[anchor.Name()] = anchor
+	}
+}
+
+// Mirror mirrors the part's geometry and anchors at the plane through its
+// origin with the given normal. The anchors keep their names, so a mirrored
+// part connects the same way as the original, only on the other side. Mirror
+// must be called before any of the part's anchors is connected.
+func (base *AnchoredBase) Mirror(normal mgl64.Vec3) {
+	normal = normal.Normalize()
+	reflect := func(v mgl64.Vec3) mgl64.Vec3 {
+		return v.Sub(normal.Mul(2 * normal.Dot(v)))
+	}
+
+	base.contents = primitive.NewList(primitive.NewMirror(normal, base.contents))
+
+	for name, anchor := range base.anchors {
+		if anchor.Connection() != nil {
+			panic(fmt.Sprintf("cannot mirror %s after its anchor %s was connected", base.name, name))
+		}
+
+		base.anchors[name] = NewOrientedAnchor(
+			name,
+			anchor.Parent(),
+			reflect(anchor.Translation()),
+			reflect(anchor.Normal()),
+			reflect(anchor.Up()),
+		)
+	}
+}
+
+func (base *AnchoredBase) Anchors() map[string]Anchor {
+	return base.anchors
+}
+
+func (base *AnchoredBase) SetAnchorTransform(transform mgl64.Mat4) error {
+	if base.anchorTransform != nil {
+		distance, angle := placementDiscrepancy(*base.anchorTransform, transform)
+		if distance > positionTolerance || angle > angleTolerance {
+			return fmt.Errorf("%w: %s is already placed %.6g mm and %.6g° away", ErrConflictingAnchorTransform, base.name, distance, angle)
+		}
+
+		return nil
+	}
+
+	base.anchorTransform = &transform
+
+	return nil
+}
+
+func (base *AnchoredBase) GetAnchorTransform() *mgl64.Mat4 {
+	return base.anchorTransform
+}
+
+func (base *AnchoredBase) Disable() primitive.Primitive { //nolint:ireturn
+	base.prefix = "*"
+
+	return base.self
+}
+
+func (base *AnchoredBase) ShowOnly() primitive.Primitive { //nolint:ireturn
+	base.prefix = "!"
+
+	return base.self
+}
+
+func (base *AnchoredBase) Highlight() primitive.Primitive { //nolint:ireturn
+	base.prefix = "#"
+
+	return base.self
+}
+
+func (base *AnchoredBase) Transparent() primitive.Primitive { //nolint:ireturn
+	base.prefix = "%"
+
+	return base.self
+}
+
+func (base *AnchoredBase) Prefix() string {
+	return base.prefix
+}
+
+// Expand returns the part's geometry at the place its anchors were resolved
+// to, without its modifier. It fails if the anchors were not resolved.
+func (base *AnchoredBase) Expand() (primitive.Primitive, error) { //nolint:ireturn
+	if base.anchorTransform == nil {
+		return nil, fmt.Errorf("cannot place %s without resolving its anchors", base.name)
+	}
+
+	return ghostscad.NewMultMatrix(*base.anchorTransform, base.contents), nil
+}
+
+func (base *AnchoredBase) Bounds(transform mgl64.Mat4) (mesh.Box, error) {
+	if base.anchorTransform == nil {
+		return mesh.Box{}, fmt.Errorf("cannot measure %s without resolving its anchors", base.name)
+	}
+
+	box, err := mesh.Bounds(base.contents, transform.Mul4(*base.anchorTransform))
+	if err != nil {
+		return mesh.Box{}, fmt.Errorf("failed to measure %s: %w", base.name, err)
+	}
+
+	return box, nil
+}
+
+// Render renders the part's geometry at the place its anchors were resolved
+// to. ResolveAnchors must have been called before.
+func (base *AnchoredBase) Render(w *bufio.Writer) {
+	if base.anchorTransform == nil {
+		panic(fmt.Sprintf("cannot render %s without resolving its anchors", base.name))
+	}
+
+	_, _ = w.WriteString(base.prefix)
+	ghostscad.NewMultMatrix(*base.anchorTransform, base.contents).Render(w)
+}
@@ -0,0 +1,187 @@
+package shapes
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func render(t *testing.T, foo *FooAnchored) string {
+	t.Helper()
+
+	var builder strings.Builder
+	w := bufio.NewWriter(&builder)
+	foo.Render(w)
+	require.NoError(t, w.Flush())
+
+	return builder.String()
+}
+
+func TestAnchoredBase(t *testing.T) {
+	t.Parallel()
+
+	t.Run("renders the contents at the resolved anchor transform.", func(t *testing.T) {
+		t.Parallel()
+
+		foo := NewFoo("foo", 2)
+		err := foo.SetAnchorTransform(mgl64.Translate3D(1, 2, 3))
+		require.NoError(t, err)
+
+		rendered := render(t, foo)
+
+		assert.True(t, strings.HasPrefix(rendered, "multmatrix([[1, 0, 0, 1], [0, 1, 0, 2], [0, 0, 1, 3], "), rendered)
+		assert.Contains(t, rendered, "cube([2.000000, 2.000000, 2.000000], center=true);")
+	})
+
+	t.Run("renders the prefix set by the modifiers.", func(t *testing.T) {
+		t.Parallel()
+
+		for prefix, modify := range map[string]func(foo *FooAnchored){
+			"*": func(foo *FooAnchored) { foo.Disable() },
+			"!": func(foo *FooAnchored) { foo.ShowOnly() },
+			"#": func(foo *FooAnchored) { foo.Highlight() },
+			"%": func(foo *FooAnchored) { foo.Transparent() },
+		} {
+			foo := NewFoo("foo", 2)
+			err := foo.SetAnchorTransform(mgl64.Ident4())
+			require.NoError(t, err)
+
+			modify(foo)
+
+			assert.Equal(t, prefix, foo.Prefix())
+			assert.True(t, strings.HasPrefix(render(t, foo), prefix+"multmatrix("), "prefix %s", prefix)
+		}
+	})
+
+	t.Run("returns the embedding part from the modifiers.", func(t *testing.T) {
+		t.Parallel()
+
+		foo := NewFoo("foo", 2)
+
+		assert.Same(t, foo, foo.Highlight())
+	})
+
+	t.Run("accepts the same anchor transform again.", func(t *testing.T) {
+		t.Parallel()
+
+		foo := NewFoo("foo", 2)
+		err := foo.SetAnchorTransform(mgl64.Translate3D(1, 2, 3))
+		require.NoError(t, err)
+
+		err = foo.SetAnchorTransform(mgl64.Translate3D(1, 2, 3+1e-9))
+		require.NoError(t, err)
+	})
+
+	t.Run("rejects a conflicting anchor transform.", func(t *testing.T) {
+		t.Parallel()
+
+		foo := NewFoo("foo", 2)
+		err := foo.SetAnchorTransform(mgl64.Translate3D(1, 2, 3))
+		require.NoError(t, err)
+
+		err = foo.SetAnchorTransform(mgl64.Translate3D(1, 2, 4))
+		require.ErrorIs(t, err, ErrConflictingAnchorTransform)
+		assertMat4InDelta(t, mgl64.Translate3D(1, 2, 3), *foo.GetAnchorTransform(), 0)
+	})
+
+	t.Run("mirrors the geometry and the anchors.", func(t *testing.T) {
+		t.Parallel()
+
+		foo := NewFoo("foo", 2)
+		foo.AddAnchors(NewOrientedAnchor("corner", foo, mgl64.Vec3{1, 1, 1}, mgl64.Vec3{1, 1, 0}, mgl64.Vec3{0, 0, 1}))
+
+		foo.Mirror(mgl64.Vec3{2, 0, 0})
+
+		corner := foo.Anchors()["corner"]
+		assert.Same(t, foo, corner.Parent())
+		assertVec3InDelta(t, mgl64.Vec3{-1, 1, 1}, corner.Translation(), 1e-12)
+		assertVec3InDelta(t, mgl64.Vec3{-1, 1, 0}.Normalize(), corner.Normal(), 1e-12)
+		assertVec3InDelta(t, mgl64.Vec3{0, 0, 1}, corner.Up(), 1e-12)
+		assertVec3InDelta(t, mgl64.Vec3{-1, 0, 0}, foo.Anchors()["right"].Normal(), 1e-12)
+
+		err := foo.SetAnchorTransform(mgl64.Ident4())
+		require.NoError(t, err)
+		assert.Contains(t, render(t, foo), "mirror([1.000000, 0.000000, 0.000000])")
+	})
+
+	t.Run("places mirrored parts as mirror images.", func(t *testing.T) {
+		t.Parallel()
+
+		// The same part is attached once to the right and once, mirrored, to
+		// the left of a foo. Its corner must end up mirrored as well.
+		newAttachment := func(name string) *FooAnchored {
+			attachment := NewFoo(name, 2)
+			attachment.AddAnchors(NewOrientedAnchor("side", attachment, mgl64.Vec3{0, 0, 1}, mgl64.Vec3{0, 0, 1}, mgl64.Vec3{1, 1, 0}))
+
+			return attachment
+		}
+		center := NewFoo("center", 2)
+		center.AddAnchors(NewAnchor("left", center, mgl64.Vec3{-1, 0, 0}, mgl64.Vec3{-1, 0, 0}))
+		right := newAttachment("right")
+		left := newAttachment("left")
+		left.Mirror(mgl64.Vec3{0, 0, 1})
+
+		err := center.Anchors()["right"].Connect(right.Anchors()["side"], 20)
+		require.NoError(t, err)
+		err = center.Anchors()["left"].Connect(left.Anchors()["side"], -20)
+		require.NoError(t, err)
+		err = ResolveAnchors(center)
+		require.NoError(t, err)
+
+		corner := mgl64.Vec3{0.5, 0.7, 0.9}
+		mirroredCorner := mgl64.Vec3{0.5, 0.7, -0.9}
+		rightCorner := mgl64.TransformCoordinate(corner, *right.GetAnchorTransform())
+		leftCorner := mgl64.TransformCoordinate(mirroredCorner, *left.GetAnchorTransform())
+		assertVec3InDelta(t, mgl64.Vec3{-rightCorner[0], rightCorner[1], rightCorner[2]}, leftCorner, 1e-12)
+	})
+
+	t.Run("panics when mirrored after an anchor was connected.", func(t *testing.T) {
+		t.Parallel()
+
+		fooOne := NewFoo("fooOne", 2)
+		fooTwo := NewFoo("fooTwo", 2)
+		err := fooOne.Anchors()["top"].Connect(fooTwo.Anchors()["bottom"], 0)
+		require.NoError(t, err)
+
+		assert.Panics(t, func() {
+			fooOne.Mirror(mgl64.Vec3{1, 0, 0})
+		})
+	})
+
+	t.Run("panics when rendered before its anchors were resolved.", func(t *testing.T) {
+		t.Parallel()
+
+		foo := NewFoo("foo", 2)
+
+		assert.PanicsWithValue(t, "cannot render foo without resolving its anchors", func() {
+			render(t, foo)
+		})
+	})
+
+	t.Run("measures the contents at the resolved anchor transform.", func(t *testing.T) {
+		t.Parallel()
+
+		foo := NewFoo("foo", 2)
+		err := foo.SetAnchorTransform(mgl64.Translate3D(1, 2, 3))
+		require.NoError(t, err)
+
+		box, err := foo.Bounds(mgl64.Scale3D(2, 2, 2))
+		require.NoError(t, err)
+
+		assert.InDeltaSlice(t, []float64{0, 2, 4}, box.Min[:], 1e-9)
+		assert.InDeltaSlice(t, []float64{4, 6, 8}, box.Max[:], 1e-9)
+	})
+
+	t.Run("fails to measure before its anchors were resolved.", func(t *testing.T) {
+		t.Parallel()
+
+		foo := NewFoo("foo", 2)
+
+		_, err := foo.Bounds(mgl64.Ident4())
+		require.EqualError(t, err, "cannot measure foo without resolving its anchors")
+	})
+}
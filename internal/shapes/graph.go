@@ -0,0 +1,185 @@
+package shapes
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+// Graph is a snapshot of the anchor graph, meant for debugging assemblies.
+type Graph struct {
+	Parts []GraphPart `json:"parts"`
+}
+
+type GraphPart struct {
+	Name string `json:"name"`
+
+	// Transform is the part's anchor transform as rows of a 4x4 matrix, or nil
+	// if the part has not been placed.
+	Transform *[4][4]float64 `json:"transform"`
+
+	Anchors []GraphAnchor `json:"anchors"`
+}
+
+type GraphAnchor struct {
+	Name        string           `json:"name"`
+	Translation mgl64.Vec3       `json:"translation"`
+	Normal      mgl64.Vec3       `json:"normal"`
+	Up          mgl64.Vec3       `json:"up"`
+	Connection  *GraphConnection `json:"connection"`
+}
+
+type GraphConnection struct {
+	Part        string  `json:"part"`
+	Anchor      string  `json:"anchor"`
+	Angle       float64 `json:"angle"`
+	WasResolved bool    `json:"wasResolved"`
+}
+
+// CollectGraph walks every part that is connected to start, directly or
+// indirectly. The parts are listed in the order ResolveAnchors visits them,
+// their anchors sorted by name.
+func CollectGraph(start Anchored) *Graph {
+	graph := &Graph{}
+
+	visited := map[Anchored]bool{start: true}
+	queue := []Anchored{start}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		part := GraphPart{
+			Name: current.Name(),
+		}
+		if transform := current.GetAnchorTransform(); transform != nil {
+			rows := [4][4]float64{}
+			for row := range 4 {
+				for column := range 4 {
+					rows[row][column] = transform.At(row, column)
+				}
+			}
+			part.Transform = &rows
+		}
+
+		anchors := current.Anchors()
+		for _, name := range slices.Sorted(maps.Keys(anchors)) {
+			anchor := anchors[name]
+			graphAnchor := GraphAnchor{
+				Name:        anchor.Name(),
+				Translation: anchor.Translation(),
+				Normal:      anchor.Normal(),
+				Up:          anchor.Up(),
+			}
+
+			if connection := anchor.Connection(); connection != nil {
+				target := connection.Target()
+				graphAnchor.Connection = &GraphConnection{
+					Part:        target.Parent().Name(),
+					Anchor:      target.Name(),
+					Angle:       connection.Angle(),
+					WasResolved: connection.WasResolved(),
+				}
+
+				if !visited[target.Parent()] {
+					visited[target.Parent()] = true
+					queue = append(queue, target.Parent())
+				}
+			}
+
+			part.Anchors = append(part.Anchors, graphAnchor)
+		}
+
+		graph.Parts = append(graph.Parts, part)
+	}
+
+	return graph
+}
+
+// WriteDOT writes the graph in the Graphviz DOT language. Every part is a
+// record with a field per anchor, every connection an edge between the
+// anchors' fields. Unresolved connections are dashed.
+func (graph *Graph) WriteDOT(w io.Writer) error {
+	var builder strings.Builder
+
+	builder.WriteString("graph anchors {\n")
+	builder.WriteString("\tnode [shape=record];\n")
+
+	for _, part := range graph.Parts {
+		fields := []string{escapeRecord(part.Name)}
+		if part.Transform != nil {
+			fields = append(fields, escapeRecord(fmt.Sprintf(
+				"at (%.6g, %.6g, %.6g)",
+				part.Transform[0][3], part.Transform[1][3], part.Transform[2][3],
+			)))
+		} else {
+			fields = append(fields, "unplaced")
+		}
+		for _, anchor := range part.Anchors {
+			fields = append(fields, fmt.Sprintf(
+				"<%s> %s",
+				escapeRecord(anchor.Name),
+				escapeRecord(fmt.Sprintf("%s n=%s up=%s", anchor.Name, formatVec3(anchor.Normal), formatVec3(anchor.Up))),
+			))
+		}
+
+		fmt.Fprintf(&builder, "\t%s [label=%s];\n", quoteDOT(part.Name), quoteDOT("{"+strings.Join(fields, "|")+"}"))
+	}
+
+	// Both anchors of a connection know about it, but it is only drawn once.
+	drawn := map[string]bool{}
+	for _, part := range graph.Parts {
+		for _, anchor := range part.Anchors {
+			connection := anchor.Connection
+			if connection == nil {
+				continue
+			}
+
+			from := quoteDOT(part.Name) + ":" + quoteDOT(anchor.Name)
+			to := quoteDOT(connection.Part) + ":" + quoteDOT(connection.Anchor)
+			if drawn[to+" -- "+from] {
+				continue
+			}
+			drawn[from+" -- "+to] = true
+
+			style := "solid"
+			if !connection.WasResolved {
+				style = "dashed"
+			}
+
+			fmt.Fprintf(&builder, "\t%s -- %s [label=%s, style=%s];\n", from, to, quoteDOT(fmt.Sprintf("%g°", connection.Angle)), style)
+		}
+	}
+
+	builder.WriteString("}\n")
+
+	_, err := io.WriteString(w, builder.String())
+
+	return err
+}
+
+func formatVec3(v mgl64.Vec3) string {
+	return fmt.Sprintf("(%.6g, %.6g, %.6g)", v[0], v[1], v[2])
+}
+
+// quoteDOT quotes text as a DOT string. Backslashes are left alone, since they
+// start escape sequences in labels.
+func quoteDOT(text string) string {
+	return `"` + strings.ReplaceAll(text, `"`, `\"`) + `"`
+}
+
+// escapeRecord escapes the characters that have a meaning in labels of record
+// shaped nodes.
+func escapeRecord(text string) string {
+	return strings.NewReplacer(
+		"{", `\{`,
+		"}", `\}`,
+		"|", `\|`,
+		"<", `\<`,
+		">", `\>`,
+	).Replace(text)
+}
@@ -0,0 +1,129 @@
+package shapes
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollectGraph(t *testing.T) {
+	t.Parallel()
+
+	t.Run("lists every connected part with its anchors.", func(t *testing.T) {
+		t.Parallel()
+
+		fooOne := NewFoo("fooOne", 2)
+		fooTwo := NewFoo("fooTwo", 2)
+		fooThree := NewFoo("fooThree", 2)
+
+		err := fooOne.Anchors()["top"].Connect(fooTwo.Anchors()["bottom"], 90)
+		require.NoError(t, err)
+		err = fooTwo.Anchors()["top"].Connect(fooThree.Anchors()["bottom"], 0)
+		require.NoError(t, err)
+
+		graph := CollectGraph(fooOne)
+
+		require.Len(t, graph.Parts, 3)
+		assert.Equal(t, "fooOne", graph.Parts[0].Name)
+		assert.Equal(t, "fooTwo", graph.Parts[1].Name)
+		assert.Equal(t, "fooThree", graph.Parts[2].Name)
+		assert.Nil(t, graph.Parts[0].Transform)
+
+		anchors := graph.Parts[1].Anchors
+		require.Len(t, anchors, 3)
+		assert.Equal(t, GraphAnchor{
+			Name:        "bottom",
+			Translation: mgl64.Vec3{0, 0, -1},
+			Normal:      mgl64.Vec3{0, 0, -1},
+			Up:          mgl64.Vec3{0, 1, 0},
+			Connection: &GraphConnection{
+				Part:        "fooOne",
+				Anchor:      "top",
+				Angle:       90,
+				WasResolved: false,
+			},
+		}, anchors[0])
+		assert.Equal(t, "right", anchors[1].Name)
+		assert.Nil(t, anchors[1].Connection)
+		assert.Equal(t, "top", anchors[2].Name)
+	})
+
+	t.Run("contains the resolved transforms.", func(t *testing.T) {
+		t.Parallel()
+
+		fooOne := NewFoo("fooOne", 2)
+		fooTwo := NewFoo("fooTwo", 2)
+
+		err := fooOne.Anchors()["top"].Connect(fooTwo.Anchors()["bottom"], 0)
+		require.NoError(t, err)
+		err = ResolveAnchors(fooOne)
+		require.NoError(t, err)
+
+		graph := CollectGraph(fooOne)
+
+		require.Len(t, graph.Parts, 2)
+		assert.Equal(t, &[4][4]float64{
+			{1, 0, 0, 0},
+			{0, 1, 0, 0},
+			{0, 0, 1, 2},
+			{0, 0, 0, 1},
+		}, graph.Parts[1].Transform)
+		assert.True(t, graph.Parts[1].Anchors[0].Connection.WasResolved)
+	})
+}
+
+func TestGraphWriteDOT(t *testing.T) {
+	t.Parallel()
+
+	t.Run("draws parts as records and connections as edges between anchors.", func(t *testing.T) {
+		t.Parallel()
+
+		graph := &Graph{
+			Parts: []GraphPart{
+				{
+					Name: "one",
+					Transform: &[4][4]float64{
+						{1, 0, 0, 1},
+						{0, 1, 0, 2},
+						{0, 0, 1, 3},
+						{0, 0, 0, 1},
+					},
+					Anchors: []GraphAnchor{
+						{
+							Name:       "top",
+							Normal:     mgl64.Vec3{0, 0, 1},
+							Up:         mgl64.Vec3{0, 1, 0},
+							Connection: &GraphConnection{Part: "two", Anchor: "bottom", Angle: 45, WasResolved: true},
+						},
+					},
+				},
+				{
+					Name: "two",
+					Anchors: []GraphAnchor{
+						{
+							Name:       "bottom",
+							Normal:     mgl64.Vec3{0, 0, -1},
+							Up:         mgl64.Vec3{0, 1, 0},
+							Connection: &GraphConnection{Part: "one", Anchor: "top", Angle: 45, WasResolved: false},
+						},
+					},
+				},
+			},
+		}
+
+		var builder strings.Builder
+		err := graph.WriteDOT(&builder)
+		require.NoError(t, err)
+
+		assert.Equal(t, `graph anchors {
+	node [shape=record];
+	"one" [label="{one|at (1, 2, 3)|<top> top n=(0, 0, 1) up=(0, 1, 0)}"];
+	"two" [label="{two|unplaced|<bottom> bottom n=(0, 0, -1) up=(0, 1, 0)}"];
+	"one":"top" -- "two":"bottom" [label="45°", style=solid];
+}
+`, builder.String())
+	})
+}
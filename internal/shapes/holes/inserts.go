@@ -0,0 +1,36 @@
+package holes
+
+import (
+	"maps"
+	"slices"
+)
+
+// Insert describes a heat-set insert and the bore it is melted into. All
+// lengths are in millimetres.
+type Insert struct {
+	// Screw is the screw standard that fits the insert's thread.
+	Screw string
+
+	// Length is the length of the insert and BoreRadius the radius of the hole
+	// it is pressed into.
+	Length     float64
+	BoreRadius float64
+}
+
+// Inserts maps the supported heat-set inserts to their dimensions. They are
+// named after their thread and length. The bores follow the recommendations
+// of common insert manufacturers for printed parts.
+var Inserts = map[string]Insert{
+	"m3x4":       {Screw: "m3", Length: 4, BoreRadius: 2},
+	"m3x5.7":     {Screw: "m3", Length: 5.7, BoreRadius: 2},
+	"m4x8.1":     {Screw: "m4", Length: 8.1, BoreRadius: 2.8},
+	"m5x9.5":     {Screw: "m5", Length: 9.5, BoreRadius: 3.2},
+	"m6x8":       {Screw: "m6", Length: 8, BoreRadius: 4},
+	"m6x12.7":    {Screw: "m6", Length: 12.7, BoreRadius: 4},
+	"10-32x6.35": {Screw: "10-32", Length: 6.35, BoreRadius: 2.78},
+}
+
+// InsertNames returns the names of all inserts, sorted.
+func InsertNames() []string {
+	return slices.Sorted(maps.Keys(Inserts))
+}
@@ -0,0 +1,220 @@
+// Package holes provides the geometry of holes that are cut into plates, e.g.
+// to screw rack gear onto a spine.
+//
+// All holes are described in the same frame: the hole's axis is the z axis and
+// the plate lies between z=-thickness/2 at its front, where the screw enters,
+// and z=thickness/2 at its back.
+package holes
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/ljanyst/ghostscad/primitive"
+)
+
+// overcut is how far cutouts reach beyond the faces of the plate, so that the
+// faces of the difference do not coincide.
+const overcut = 0.5
+
+// CageNutHoleEdge is the edge length of the square holes that cage nuts are
+// clipped into.
+const CageNutHoleEdge = 9.5
+
+// NutClearance is added to the width of nuts across flats, so that they can be
+// pushed into nut traps.
+const NutClearance = 0.2
+
+// Profile is the shape of a hole through a plate.
+type Profile interface {
+	// Cutout returns the geometry that is subtracted from a plate of the given
+	// thickness.
+	Cutout(thickness float64) primitive.Primitive
+
+	// FootprintRadius is half the width of the hole along the x and y axes, on
+	// whichever face it is widest.
+	FootprintRadius() float64
+
+	// Validate checks that the hole can be cut into a plate of the given
+	// thickness.
+	Validate(thickness float64) error
+}
+
+// Round is a cylindrical hole, e.g. a clearance hole or a hole that a thread is
+// tapped into.
+type Round struct {
+	Radius float64
+}
+
+func (round Round) Cutout(thickness float64) primitive.Primitive { //nolint:ireturn
+	return primitive.NewCylinder(thickness+2*overcut, round.Radius)
+}
+
+func (round Round) FootprintRadius() float64 {
+	return round.Radius
+}
+
+func (round Round) Validate(_ float64) error {
+	if round.Radius <= 0 {
+		return fmt.Errorf("hole radius must be positive, got %g", round.Radius)
+	}
+
+	return nil
+}
+
+// Square is a square hole, e.g. for cage nuts.
+type Square struct {
+	Edge float64
+}
+
+func (square Square) Cutout(thickness float64) primitive.Primitive { //nolint:ireturn
+	return primitive.NewCube(mgl64.Vec3{square.Edge, square.Edge, thickness + 2*overcut})
+}
+
+func (square Square) FootprintRadius() float64 {
+	return square.Edge / 2
+}
+
+func (square Square) Validate(_ float64) error {
+	if square.Edge <= 0 {
+		return fmt.Errorf("hole edge must be positive, got %g", square.Edge)
+	}
+
+	return nil
+}
+
+// Countersunk is a clearance hole with a countersink at the front, so that
+// the head of a countersunk screw ends flush with the plate.
+type Countersunk struct {
+	Radius     float64
+	HeadRadius float64
+
+	// Angle is the included angle of the countersink's cone in degrees.
+	Angle float64
+}
+
+// depth is how far the countersink reaches into the plate.
+func (countersunk Countersunk) depth() float64 {
+	return (countersunk.HeadRadius - countersunk.Radius) / math.Tan(mgl64.DegToRad(countersunk.Angle/2))
+}
+
+func (countersunk Countersunk) Cutout(thickness float64) primitive.Primitive { //nolint:ireturn
+	depth := countersunk.depth()
+	slope := math.Tan(mgl64.DegToRad(countersunk.Angle / 2))
+
+	// The cone starts in front of the plate, so it is widened accordingly.
+	cone := primitive.NewCylinder(depth+overcut, countersunk.HeadRadius+overcut*slope)
+	cone.RTop = countersunk.Radius
+	cone.Center = false
+
+	return primitive.NewUnion(
+		primitive.NewCylinder(thickness+2*overcut, countersunk.Radius),
+		primitive.NewTranslation(mgl64.Vec3{0, 0, -thickness/2 - overcut}, cone),
+	)
+}
+
+func (countersunk Countersunk) FootprintRadius() float64 {
+	return countersunk.HeadRadius
+}
+
+func (countersunk Countersunk) Validate(thickness float64) error {
+	switch {
+	case countersunk.Radius <= 0:
+		return fmt.Errorf("hole radius must be positive, got %g", countersunk.Radius)
+	case countersunk.HeadRadius <= countersunk.Radius:
+		return fmt.Errorf("countersink radius %g must be larger than hole radius %g", countersunk.HeadRadius, countersunk.Radius)
+	case countersunk.Angle <= 0 || countersunk.Angle >= 180:
+		return fmt.Errorf("countersink angle must be between 0° and 180°, got %g°", countersunk.Angle)
+	case countersunk.depth() >= thickness:
+		return fmt.Errorf("countersink depth %g must be less than plate thickness %g", countersunk.depth(), thickness)
+	default:
+		return nil
+	}
+}
+
+// NutTrap is a clearance hole with a hexagonal pocket at the back, which holds
+// a nut so that the screw can be tightened from the front.
+type NutTrap struct {
+	Radius float64
+
+	// NutWidth is the width of the nut across flats and NutThickness its
+	// height. The pocket is widened by NutClearance.
+	NutWidth     float64
+	NutThickness float64
+}
+
+// pocketRadius is the radius of the pocket's corners.
+func (nutTrap NutTrap) pocketRadius() float64 {
+	return (nutTrap.NutWidth + NutClearance) / math.Sqrt(3)
+}
+
+func (nutTrap NutTrap) Cutout(thickness float64) primitive.Primitive { //nolint:ireturn
+	pocket := primitive.NewCylinder(nutTrap.NutThickness+overcut, nutTrap.pocketRadius())
+	pocket.Center = false
+	pocket.Circular.SetFn(6)
+
+	return primitive.NewUnion(
+		primitive.NewCylinder(thickness+2*overcut, nutTrap.Radius),
+		primitive.NewTranslation(mgl64.Vec3{0, 0, thickness/2 - nutTrap.NutThickness}, pocket),
+	)
+}
+
+func (nutTrap NutTrap) FootprintRadius() float64 {
+	return nutTrap.pocketRadius()
+}
+
+func (nutTrap NutTrap) Validate(thickness float64) error {
+	switch {
+	case nutTrap.Radius <= 0:
+		return fmt.Errorf("hole radius must be positive, got %g", nutTrap.Radius)
+	case nutTrap.NutWidth <= 2*nutTrap.Radius:
+		return fmt.Errorf("nut width %g must be larger than hole diameter %g", nutTrap.NutWidth, 2*nutTrap.Radius)
+	case nutTrap.NutThickness <= 0:
+		return fmt.Errorf("nut thickness must be positive, got %g", nutTrap.NutThickness)
+	case nutTrap.NutThickness >= thickness:
+		return fmt.Errorf("nut thickness %g must be less than plate thickness %g", nutTrap.NutThickness, thickness)
+	default:
+		return nil
+	}
+}
+
+// HeatSetInsert is a bore at the front that a heat-set insert is melted into,
+// followed by a clearance hole for the end of the screw.
+type HeatSetInsert struct {
+	Radius float64
+
+	// BoreRadius is the radius of the bore and Depth how far it reaches into the
+	// plate.
+	BoreRadius float64
+	Depth      float64
+}
+
+func (insert HeatSetInsert) Cutout(thickness float64) primitive.Primitive { //nolint:ireturn
+	bore := primitive.NewCylinder(insert.Depth+overcut, insert.BoreRadius)
+	bore.Center = false
+
+	return primitive.NewUnion(
+		primitive.NewCylinder(thickness+2*overcut, insert.Radius),
+		primitive.NewTranslation(mgl64.Vec3{0, 0, -thickness/2 - overcut}, bore),
+	)
+}
+
+func (insert HeatSetInsert) FootprintRadius() float64 {
+	return insert.BoreRadius
+}
+
+func (insert HeatSetInsert) Validate(thickness float64) error {
+	switch {
+	case insert.Radius <= 0:
+		return fmt.Errorf("hole radius must be positive, got %g", insert.Radius)
+	case insert.BoreRadius <= insert.Radius:
+		return fmt.Errorf("insert bore radius %g must be larger than hole radius %g", insert.BoreRadius, insert.Radius)
+	case insert.Depth <= 0:
+		return fmt.Errorf("insert length must be positive, got %g", insert.Depth)
+	case insert.Depth >= thickness:
+		return fmt.Errorf("insert length %g must be less than plate thickness %g", insert.Depth, thickness)
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,151 @@
+package holes
+
+import (
+	"bufio"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/ljanyst/ghostscad/primitive"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func render(t *testing.T, item primitive.Primitive) string {
+	t.Helper()
+
+	var builder strings.Builder
+	w := bufio.NewWriter(&builder)
+	item.Render(w)
+	require.NoError(t, w.Flush())
+
+	return builder.String()
+}
+
+func TestProfiles(t *testing.T) {
+	t.Parallel()
+
+	t.Run("cuts round holes through the whole plate.", func(t *testing.T) {
+		t.Parallel()
+
+		rendered := render(t, Round{Radius: 3}.Cutout(4))
+
+		assert.Contains(t, rendered, "cylinder(h=5.000000, r1=3.000000, r2=3.000000, center=true")
+	})
+
+	t.Run("cuts square holes through the whole plate.", func(t *testing.T) {
+		t.Parallel()
+
+		square := Square{Edge: CageNutHoleEdge}
+
+		assert.Contains(t, render(t, square.Cutout(4)), "cube([9.500000, 9.500000, 5.000000], center=true);")
+		assert.InDelta(t, 4.75, square.FootprintRadius(), 1e-12)
+	})
+
+	t.Run("widens countersunk holes towards the front.", func(t *testing.T) {
+		t.Parallel()
+
+		countersunk := Countersunk{Radius: 3, HeadRadius: 5, Angle: 90}
+
+		rendered := render(t, countersunk.Cutout(4))
+
+		assert.Contains(t, rendered, "translate([0.000000, 0.000000, -2.500000])")
+		assert.Contains(t, rendered, "cylinder(h=2.500000, r1=5.500000, r2=3.000000, center=false")
+		assert.InDelta(t, 5, countersunk.FootprintRadius(), 1e-12)
+	})
+
+	t.Run("pockets nut traps into the back.", func(t *testing.T) {
+		t.Parallel()
+
+		nutTrap := NutTrap{Radius: 3, NutWidth: 10, NutThickness: 3}
+
+		rendered := render(t, nutTrap.Cutout(4))
+
+		assert.Contains(t, rendered, "translate([0.000000, 0.000000, -1.000000])")
+		assert.Contains(t, rendered, "$fn=6")
+		assert.InDelta(t, 10.2/math.Sqrt(3), nutTrap.FootprintRadius(), 1e-12)
+	})
+
+	t.Run("bores heat-set inserts into the front.", func(t *testing.T) {
+		t.Parallel()
+
+		insert := HeatSetInsert{Radius: 3, BoreRadius: 4, Depth: 3}
+
+		rendered := render(t, insert.Cutout(4))
+
+		assert.Contains(t, rendered, "translate([0.000000, 0.000000, -2.500000])")
+		assert.Contains(t, rendered, "cylinder(h=3.500000, r1=4.000000, r2=4.000000, center=false")
+		assert.InDelta(t, 4, insert.FootprintRadius(), 1e-12)
+	})
+}
+
+func TestProfileValidate(t *testing.T) {
+	t.Parallel()
+
+	for _, testCase := range []struct {
+		name    string
+		profile Profile
+		message string
+	}{
+		{"rejects round holes without a radius.", Round{}, "hole radius must be positive, got 0"},
+		{"rejects square holes without an edge.", Square{Edge: -1}, "hole edge must be positive, got -1"},
+		{"rejects countersinks narrower than the hole.", Countersunk{Radius: 3, HeadRadius: 2, Angle: 90}, "countersink radius 2 must be larger than hole radius 3"},
+		{"rejects flat countersinks.", Countersunk{Radius: 3, HeadRadius: 5, Angle: 180}, "countersink angle must be between 0° and 180°, got 180°"},
+		{"rejects countersinks deeper than the plate.", Countersunk{Radius: 1, HeadRadius: 6, Angle: 90}, "countersink depth 5 must be less than plate thickness 4"},
+		{"rejects nuts that do not fit around the screw.", NutTrap{Radius: 3, NutWidth: 5, NutThickness: 2}, "nut width 5 must be larger than hole diameter 6"},
+		{"rejects insert bores narrower than the hole.", HeatSetInsert{Radius: 3, BoreRadius: 3, Depth: 2}, "insert bore radius 3 must be larger than hole radius 3"},
+		{"rejects inserts longer than the plate.", HeatSetInsert{Radius: 3, BoreRadius: 4, Depth: 5}, "insert length 5 must be less than plate thickness 4"},
+		{"rejects nuts thicker than the plate.", NutTrap{Radius: 3, NutWidth: 10, NutThickness: 4}, "nut thickness 4 must be less than plate thickness 4"},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.EqualError(t, testCase.profile.Validate(4), testCase.message)
+		})
+	}
+
+	t.Run("accepts the profiles of all screws in a 15mm plate.", func(t *testing.T) {
+		t.Parallel()
+
+		for name, screw := range Screws {
+			for _, holeType := range Types {
+				assert.NoError(t, ProfileFor(holeType, screw).Validate(15), "%s %s", name, holeType)
+			}
+		}
+	})
+}
+
+func TestProfileFor(t *testing.T) {
+	t.Parallel()
+
+	screw := Screws["m6"]
+
+	assert.Equal(t, Round{Radius: 3}, ProfileFor(TypeRound, screw))
+	assert.Equal(t, Round{Radius: 2.5}, ProfileFor(TypeTapped, screw))
+	assert.Equal(t, Square{Edge: CageNutHoleEdge}, ProfileFor(TypeCageNut, screw))
+	assert.Equal(t, Countersunk{Radius: 3.3, HeadRadius: 6, Angle: 90}, ProfileFor(TypeCountersunk, screw))
+	assert.Equal(t, NutTrap{Radius: 3.3, NutWidth: 10, NutThickness: 5.2}, ProfileFor(TypeNutTrap, screw))
+	assert.Equal(t, HeatSetInsert{Radius: 3.3, BoreRadius: 4, Depth: 8}, ProfileFor(TypeHeatSetInsert, screw))
+	assert.Panics(t, func() { ProfileFor("oval", screw) })
+}
+
+func TestInserts(t *testing.T) {
+	t.Parallel()
+
+	t.Run("fit a known screw.", func(t *testing.T) {
+		t.Parallel()
+
+		for name, insert := range Inserts {
+			assert.Contains(t, Screws, insert.Screw, name)
+		}
+	})
+
+	t.Run("are the default of every screw they fit.", func(t *testing.T) {
+		t.Parallel()
+
+		for name, screw := range Screws {
+			require.Contains(t, Inserts, screw.Insert, name)
+			assert.Equal(t, name, Inserts[screw.Insert].Screw)
+		}
+	})
+}
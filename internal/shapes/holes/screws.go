@@ -0,0 +1,84 @@
+package holes
+
+// Screw describes the dimensions of a screw and its nut that holes are cut
+// for. All lengths are in millimetres.
+type Screw struct {
+	// Radius is the nominal radius of the thread.
+	Radius float64
+
+	// TapDrillRadius is the radius of a hole the thread is tapped into.
+	TapDrillRadius float64
+
+	// ClearanceRadius is the radius of a hole the screw passes through freely.
+	ClearanceRadius float64
+
+	// HeadRadius is the radius of a countersunk head and CountersinkAngle the
+	// included angle of its cone in degrees.
+	HeadRadius       float64
+	CountersinkAngle float64
+
+	// NutWidth is the width of the screw's hex nut across flats and
+	// NutThickness its height.
+	NutWidth     float64
+	NutThickness float64
+
+	// Insert names the heat-set insert from Inserts that is used for the screw
+	// by default.
+	Insert string
+}
+
+// Screws maps the supported screw standards to their dimensions. The metric
+// screws use ISO 273 medium clearance holes, DIN 7991 countersunk heads and
+// ISO 4032 nuts. 10-32 UNF is the common screw of racks in the US.
+var Screws = map[string]Screw{
+	"m3": {
+		Radius:           1.5,
+		TapDrillRadius:   1.25,
+		ClearanceRadius:  1.7,
+		HeadRadius:       3,
+		CountersinkAngle: 90,
+		NutWidth:         5.5,
+		NutThickness:     2.4,
+		Insert:           "m3x5.7",
+	},
+	"m4": {
+		Radius:           2,
+		TapDrillRadius:   1.65,
+		ClearanceRadius:  2.25,
+		HeadRadius:       4,
+		CountersinkAngle: 90,
+		NutWidth:         7,
+		NutThickness:     3.2,
+		Insert:           "m4x8.1",
+	},
+	"m5": {
+		Radius:           2.5,
+		TapDrillRadius:   2.1,
+		ClearanceRadius:  2.75,
+		HeadRadius:       5,
+		CountersinkAngle: 90,
+		NutWidth:         8,
+		NutThickness:     4.7,
+		Insert:           "m5x9.5",
+	},
+	"m6": {
+		Radius:           3,
+		TapDrillRadius:   2.5,
+		ClearanceRadius:  3.3,
+		HeadRadius:       6,
+		CountersinkAngle: 90,
+		NutWidth:         10,
+		NutThickness:     5.2,
+		Insert:           "m6x8",
+	},
+	"10-32": {
+		Radius:           2.413,
+		TapDrillRadius:   2.0195,
+		ClearanceRadius:  2.55,
+		HeadRadius:       4.89,
+		CountersinkAngle: 82,
+		NutWidth:         9.525,
+		NutThickness:     3.175,
+		Insert:           "10-32x6.35",
+	},
+}
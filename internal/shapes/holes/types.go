@@ -0,0 +1,67 @@
+package holes
+
+// Type selects the kind of hole that is cut for a screw.
+type Type string
+
+const (
+	// TypeRound is a hole of the screw's nominal radius.
+	TypeRound Type = "round"
+
+	// TypeTapped is a hole that the screw's thread is tapped into.
+	TypeTapped Type = "tapped"
+
+	// TypeCageNut is a square hole for a cage nut that fits the screw.
+	TypeCageNut Type = "cage-nut"
+
+	// TypeCountersunk is a clearance hole for a countersunk screw.
+	TypeCountersunk Type = "countersunk"
+
+	// TypeNutTrap is a clearance hole with a pocket for the screw's nut at the
+	// back.
+	TypeNutTrap Type = "nut-trap"
+
+	// TypeHeatSetInsert is a bore for the screw's heat-set insert.
+	TypeHeatSetInsert Type = "heat-set-insert"
+)
+
+// Types lists all kinds of holes.
+var Types = []Type{TypeRound, TypeTapped, TypeCageNut, TypeCountersunk, TypeNutTrap, TypeHeatSetInsert}
+
+// ProfileFor returns the profile of the given kind of hole for screw. Heat-set
+// insert bores are cut for the insert the screw names. It panics for unknown
+// types and inserts.
+func ProfileFor(holeType Type, screw Screw) Profile { //nolint:ireturn
+	switch holeType {
+	case TypeRound:
+		return Round{Radius: screw.Radius}
+	case TypeTapped:
+		return Round{Radius: screw.TapDrillRadius}
+	case TypeCageNut:
+		return Square{Edge: CageNutHoleEdge}
+	case TypeCountersunk:
+		return Countersunk{
+			Radius:     screw.ClearanceRadius,
+			HeadRadius: screw.HeadRadius,
+			Angle:      screw.CountersinkAngle,
+		}
+	case TypeNutTrap:
+		return NutTrap{
+			Radius:       screw.ClearanceRadius,
+			NutWidth:     screw.NutWidth,
+			NutThickness: screw.NutThickness,
+		}
+	case TypeHeatSetInsert:
+		insert, ok := Inserts[screw.Insert]
+		if !ok {
+			panic("unknown insert " + screw.Insert)
+		}
+
+		return HeatSetInsert{
+			Radius:     screw.ClearanceRadius,
+			BoreRadius: insert.BoreRadius,
+			Depth:      insert.Length,
+		}
+	default:
+		panic("unknown hole type " + string(holeType))
+	}
+}
@@ -0,0 +1,93 @@
+package shapes
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/ljanyst/ghostscad/primitive"
+
+	"github.com/yeldiRium/3d-rack-brackets/internal/ghostscad"
+)
+
+// Dimensions of anchor markers in mm.
+const (
+	markerShaftLength = 6
+	markerShaftRadius = 0.3
+	markerTipLength   = 2
+	markerTipRadius   = 1
+	markerUpLength    = 3
+	markerLabelSize   = 2
+)
+
+// NewAnchorMarkers creates debug geometry for every anchor of the given parts.
+// Each anchor is marked by a highlighted arrow that starts at the anchor and
+// points along its normal, a short bar pointing along its up and a label with
+// the part's and the anchor's names. The parts' anchors must have been
+// resolved.
+func NewAnchorMarkers(parts ...Anchored) *primitive.List {
+	markers := primitive.NewList()
+
+	for _, part := range parts {
+		partTransform := part.GetAnchorTransform()
+		if partTransform == nil {
+			panic("cannot mark the anchors of " + part.Name() + " without resolving them")
+		}
+
+		anchors := part.Anchors()
+		for _, name := range slices.Sorted(maps.Keys(anchors)) {
+			anchor := anchors[name]
+			marker := ghostscad.NewMultMatrix(
+				partTransform.Mul4(anchorFrame(anchor)),
+				newMarker(part.Name()+"."+anchor.Name()),
+			)
+			marker.Highlight()
+			markers.Add(marker)
+		}
+	}
+
+	return markers
+}
+
+// anchorFrame is the transform from the anchor's parent's origin to a frame
+// at the anchor, whose z axis is the anchor's normal and whose y axis is its
+// up.
+func anchorFrame(anchor Anchor) mgl64.Mat4 {
+	normal := anchor.Normal().Normalize()
+	up := anchor.Up()
+
+	return mgl64.Mat4FromCols(
+		up.Cross(normal).Vec4(0),
+		up.Vec4(0),
+		normal.Vec4(0),
+		anchor.Translation().Vec4(1),
+	)
+}
+
+// newMarker creates an arrow along the z axis with a bar along the y axis and
+// a label at its tip.
+func newMarker(label string) *primitive.List {
+	shaft := primitive.NewCylinder(markerShaftLength, markerShaftRadius)
+	shaft.Center = false
+
+	tip := primitive.NewCylinder(markerTipLength, markerTipRadius)
+	tip.Center = false
+	tip.RTop = 0
+
+	up := primitive.NewCylinder(markerUpLength, markerShaftRadius)
+	up.Center = false
+
+	text := primitive.NewText(label)
+	text.Size = markerLabelSize
+	text.Valign = "center"
+
+	return primitive.NewList(
+		shaft,
+		primitive.NewTranslation(mgl64.Vec3{0, 0, markerShaftLength}, tip),
+		primitive.NewRotation(mgl64.Vec3{-90, 0, 0}, up),
+		primitive.NewTranslation(
+			mgl64.Vec3{markerTipRadius, 0, markerShaftLength + markerTipLength},
+			primitive.NewLinearExtrusion(markerShaftRadius, text),
+		),
+	)
+}
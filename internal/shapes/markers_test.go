@@ -0,0 +1,67 @@
+package shapes
+
+import (
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yeldiRium/3d-rack-brackets/internal/ghostscad"
+)
+
+func TestNewAnchorMarkers(t *testing.T) {
+	t.Parallel()
+
+	t.Run("places a highlighted marker at every anchor.", func(t *testing.T) {
+		t.Parallel()
+
+		fooOne := NewFoo("fooOne", 2)
+		fooTwo := NewFoo("fooTwo", 2)
+		err := fooOne.Anchors()["top"].Connect(fooTwo.Anchors()["bottom"], 0)
+		require.NoError(t, err)
+		err = ResolveAnchors(fooOne)
+		require.NoError(t, err)
+
+		markers := NewAnchorMarkers(fooOne, fooTwo)
+
+		require.Len(t, markers.Items, 6)
+		for _, item := range markers.Items {
+			assert.Equal(t, "#", item.Prefix())
+		}
+
+		// The third marker of fooTwo is at its top anchor, which is at z=3.
+		topMarker, ok := markers.Items[5].(*ghostscad.MultMatrix)
+		require.True(t, ok)
+		assertVec3InDelta(t, mgl64.Vec3{0, 0, 3}, mgl64.TransformCoordinate(mgl64.Vec3{}, topMarker.Matrix), 1e-12)
+		assertVec3InDelta(t, mgl64.Vec3{0, 0, 1}, mgl64.TransformNormal(mgl64.Vec3{0, 0, 1}, topMarker.Matrix), 1e-12)
+		assertVec3InDelta(t, mgl64.Vec3{0, 1, 0}, mgl64.TransformNormal(mgl64.Vec3{0, 1, 0}, topMarker.Matrix), 1e-12)
+	})
+
+	t.Run("panics for parts whose anchors were not resolved.", func(t *testing.T) {
+		t.Parallel()
+
+		foo := NewFoo("foo", 2)
+
+		assert.Panics(t, func() {
+			NewAnchorMarkers(foo)
+		})
+	})
+}
+
+func TestAnchorFrame(t *testing.T) {
+	t.Parallel()
+
+	t.Run("points the z axis along the normal and the y axis along the up.", func(t *testing.T) {
+		t.Parallel()
+
+		anchor := NewOrientedAnchor("anchor", nil, mgl64.Vec3{1, 2, 3}, mgl64.Vec3{1, 0, 0}, mgl64.Vec3{0, 0, 1})
+
+		frame := anchorFrame(anchor)
+
+		assertVec3InDelta(t, mgl64.Vec3{1, 2, 3}, mgl64.TransformCoordinate(mgl64.Vec3{}, frame), 1e-12)
+		assertVec3InDelta(t, mgl64.Vec3{1, 0, 0}, mgl64.TransformNormal(mgl64.Vec3{0, 0, 1}, frame), 1e-12)
+		assertVec3InDelta(t, mgl64.Vec3{0, 0, 1}, mgl64.TransformNormal(mgl64.Vec3{0, 1, 0}, frame), 1e-12)
+		assert.InDelta(t, 1, frame.Mat3().Det(), 1e-12)
+	})
+}
@@ -0,0 +1,34 @@
+package shapes
+
+import (
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+// PrintOrientation describes how a part is best printed: which of its sides
+// lies on the build plate, and how it is turned about the plate's normal
+// afterwards.
+type PrintOrientation struct {
+	// FaceDown is the direction in the part's own frame that points down onto
+	// the build plate.
+	FaceDown mgl64.Vec3
+
+	// Rotation is the angle in degrees by which the part is turned
+	// counter-clockwise about z once it lies on FaceDown.
+	Rotation float64
+}
+
+// Printable is a part that declares its preferred print orientation.
+type Printable interface {
+	PrintOrientation() PrintOrientation
+}
+
+// FlatOrientation prints a part the way it is modelled.
+var FlatOrientation = PrintOrientation{FaceDown: mgl64.Vec3{0, 0, -1}}
+
+// Matrix returns the rotation that turns the part from its own frame into
+// its print orientation. FaceDown is turned onto -z the shortest way.
+func (orientation PrintOrientation) Matrix() mgl64.Mat4 {
+	layDown := rotationFromVec3ToVec3(orientation.FaceDown, mgl64.Vec3{0, 0, -1})
+
+	return mgl64.HomogRotate3DZ(mgl64.DegToRad(orientation.Rotation)).Mul4(layDown)
+}
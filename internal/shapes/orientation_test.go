@@ -0,0 +1,48 @@
+package shapes
+
+import (
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrintOrientationMatrix(t *testing.T) {
+	t.Parallel()
+
+	t.Run("keeps flat parts as they are.", func(t *testing.T) {
+		t.Parallel()
+
+		assertMat4InDelta(t, mgl64.Ident4(), FlatOrientation.Matrix(), 1e-9)
+	})
+
+	t.Run("turns the face down onto the build plate.", func(t *testing.T) {
+		t.Parallel()
+
+		for _, faceDown := range []mgl64.Vec3{{1, 0, 0}, {0, -1, 0}, {0, 0, 1}, {1, 1, 0}} {
+			matrix := PrintOrientation{FaceDown: faceDown}.Matrix()
+
+			down := mgl64.TransformNormal(faceDown.Normalize(), matrix)
+			assert.InDeltaSlice(t, []float64{0, 0, -1}, down[:], 1e-9, "face down %v", faceDown)
+		}
+	})
+
+	t.Run("lays parts on their backs like the rails are printed.", func(t *testing.T) {
+		t.Parallel()
+
+		matrix := PrintOrientation{FaceDown: mgl64.Vec3{0, 1, 0}}.Matrix()
+
+		assertMat4InDelta(t, mgl64.HomogRotate3DX(mgl64.DegToRad(-90)), matrix, 1e-9)
+	})
+
+	t.Run("turns the part about z afterwards.", func(t *testing.T) {
+		t.Parallel()
+
+		matrix := PrintOrientation{FaceDown: mgl64.Vec3{-1, 0, 0}, Rotation: 90}.Matrix()
+
+		// The part's y lies along the plate's y after laying it down, and
+		// along -x after turning it.
+		y := mgl64.TransformNormal(mgl64.Vec3{0, 1, 0}, matrix)
+		assert.InDeltaSlice(t, []float64{-1, 0, 0}, y[:], 1e-9)
+	})
+}
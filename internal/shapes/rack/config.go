@@ -0,0 +1,561 @@
+package rack
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/go-gl/mathgl/mgl64"
+
+	"github.com/yeldiRium/3d-rack-brackets/internal/shapes/holes"
+)
+
+var (
+	ErrInvalidConfig = errors.New("invalid rack config")
+)
+
+// holePitchTolerance is the difference in mm that the hole pitches may add up
+// to beyond the segment height, to allow for rounding in custom pitches.
+const holePitchTolerance = 1e-6
+
+// HoleStandards maps the supported hole standards to the pitches between the
+// consecutive holes of a rack unit, see Config.HolePitches. 19" and 10" racks
+// following EIA-310 share the same vertical hole pattern, so it serves both.
+var HoleStandards = map[string][]float64{
+	"eia-310": {15.875, 15.875, 12.7},
+}
+
+// Sides selects the sides of the spine that carry side braces.
+type Sides string
+
+const (
+	SidesLeft  Sides = "left"
+	SidesRight Sides = "right"
+	SidesBoth  Sides = "both"
+)
+
+// AllSides lists every valid value of Sides.
+var AllSides = []Sides{SidesLeft, SidesRight, SidesBoth}
+
+// Joint selects how the pieces of a split rail are joined.
+type Joint string
+
+const (
+	// JointPin pins the pieces together. The piece below carries the pins and
+	// the piece above the matching sockets.
+	JointPin Joint = "pin"
+
+	// JointDovetail slides the pieces together from the back along a dovetail
+	// that the piece below carries.
+	JointDovetail Joint = "dovetail"
+
+	// JointSplicePlate bolts a plate across the split onto the back of the
+	// rail, through the holes next to the split.
+	JointSplicePlate Joint = "splice-plate"
+)
+
+// AllJoints lists every valid value of Joint.
+var AllJoints = []Joint{JointPin, JointDovetail, JointSplicePlate}
+
+// jointClearance is the gap in mm between the parts of a joint, so that they
+// can be fitted together after printing.
+const jointClearance = 0.2
+
+// dovetailSlope is how much a dovetail widens per mm of depth on each side.
+const dovetailSlope = 1.0 / 6
+
+// jointWall is the thinnest wall in mm that joints leave between themselves,
+// the holes and the faces of the spine. Pins and dovetails are no thinner
+// either.
+const jointWall = 1.0
+
+// Config describes the dimensions of a rack. All lengths are in millimetres.
+type Config struct {
+	// Units is the height of the rack in rack units. Each unit is one segment.
+	Units uint8
+
+	// Hole is the profile of the holes along the spine.
+	Hole holes.Profile
+
+	SpineWidth      float64
+	SpineThickness  float64
+	SpineInlayWidth float64
+
+	SegmentHeight float64
+
+	// HolePitches are the distances between the consecutive holes of a rack
+	// unit, from bottom to top. The last pitch spans the boundary to the next
+	// unit, which lies at its middle. The pitches must add up to the segment
+	// height, so that the pattern continues across units.
+	HolePitches []float64
+
+	FootLength         float64
+	FootThicknessFront float64
+	FootThicknessBack  float64
+	FootSpacerHeight   float64
+
+	// FootHoles is the number of holes through the foot that attach the rack
+	// to a desk. They are spread evenly along the foot behind the spine.
+	FootHoles uint8
+
+	// FootHole is the profile of the holes through the foot. Their fronts face
+	// the desk.
+	FootHole holes.Profile
+
+	SideBracePadding         float64
+	SideBraceInnerPadding    float64
+	SideBraceAttachmentDepth float64
+	SideBraceWidth           float64
+
+	// SideBraceSides selects the sides of the spine that are braced. The
+	// braces on the right side are mirror images of those on the left.
+	SideBraceSides Sides
+
+	// BuildVolume is the size of the printer's build volume along x, y and z.
+	// Rails that are too long for it are split into pieces, which are printed
+	// lying on their backs. Units whose side braces are too long for it are
+	// left unbraced, see bracesUnit. The zero value disables both.
+	BuildVolume mgl64.Vec3
+
+	// Joint selects how the pieces of a split rail are joined. JointDepth is
+	// how far pins and dovetails reach into the piece above, or how far splice
+	// plates reach past their bolts.
+	Joint      Joint
+	JointDepth float64
+}
+
+// DefaultConfig returns the configuration of a 3U rack with M6 screw holes.
+func DefaultConfig() Config {
+	return Config{
+		Units: 3,
+
+		Hole: holes.Round{Radius: 3.0},
+
+		SpineWidth:      15.875,
+		SpineThickness:  10.0,
+		SpineInlayWidth: 3.0,
+
+		SegmentHeight: 44.45,
+
+		HolePitches: slices.Clone(HoleStandards["eia-310"]),
+
+		FootLength:         170,
+		FootThicknessFront: 15,
+		FootThicknessBack:  10,
+		FootSpacerHeight:   5,
+
+		FootHoles: 0,
+		FootHole:  holes.Round{Radius: 3.0},
+
+		SideBracePadding:         10,
+		SideBraceInnerPadding:    2,
+		SideBraceAttachmentDepth: 20,
+		SideBraceWidth:           3.0,
+
+		SideBraceSides: SidesBoth,
+
+		BuildVolume: mgl64.Vec3{256, 256, 256},
+
+		Joint:      JointPin,
+		JointDepth: 8,
+	}
+}
+
+// Validate checks that the configuration describes a rack that can actually be
+// built. All problems are reported at once, each wrapping ErrInvalidConfig.
+func (config Config) Validate() error {
+	var errs []error
+	fail := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%w: %s", ErrInvalidConfig, fmt.Sprintf(format, args...)))
+	}
+
+	if config.Units == 0 {
+		fail("units must be at least 1")
+	}
+	if !slices.Contains(AllSides, config.SideBraceSides) {
+		fail("side brace sides must be one of left, right or both, got %q", config.SideBraceSides)
+	}
+	if !slices.Contains(AllJoints, config.Joint) {
+		fail("joint must be one of pin, dovetail or splice-plate, got %q", config.Joint)
+	}
+
+	positive := []struct {
+		name  string
+		value float64
+	}{
+		{"spine width", config.SpineWidth},
+		{"spine thickness", config.SpineThickness},
+		{"segment height", config.SegmentHeight},
+		{"foot length", config.FootLength},
+		{"foot thickness front", config.FootThicknessFront},
+		{"foot thickness back", config.FootThicknessBack},
+		{"side brace padding", config.SideBracePadding},
+		{"side brace inner padding", config.SideBraceInnerPadding},
+		{"side brace attachment depth", config.SideBraceAttachmentDepth},
+		{"side brace width", config.SideBraceWidth},
+		{"joint depth", config.JointDepth},
+	}
+	for _, dimension := range positive {
+		if dimension.value <= 0 {
+			fail("%s must be positive, got %g", dimension.name, dimension.value)
+		}
+	}
+	if config.Hole == nil {
+		fail("hole profile must be set")
+	}
+	if config.FootHoles > 0 && config.FootHole == nil {
+		fail("foot hole profile must be set")
+	}
+	if len(config.HolePitches) == 0 {
+		fail("hole pitches must not be empty")
+	}
+	for _, pitch := range config.HolePitches {
+		if pitch <= 0 {
+			fail("hole pitches must be positive, got %g", pitch)
+		}
+	}
+
+	nonNegative := []struct {
+		name  string
+		value float64
+	}{
+		{"spine inlay width", config.SpineInlayWidth},
+		{"foot spacer height", config.FootSpacerHeight},
+		{"build volume x", config.BuildVolume.X()},
+		{"build volume y", config.BuildVolume.Y()},
+		{"build volume z", config.BuildVolume.Z()},
+	}
+	for _, dimension := range nonNegative {
+		if dimension.value < 0 {
+			fail("%s must not be negative, got %g", dimension.name, dimension.value)
+		}
+	}
+
+	if len(errs) > 0 {
+		// The relational checks below are meaningless for nonsensical values.
+		return errors.Join(errs...)
+	}
+
+	if err := config.Hole.Validate(config.SpineThickness); err != nil {
+		fail("%s", err)
+	}
+	holeWidth := 2 * config.Hole.FootprintRadius()
+	if holeWidth >= config.SpineWidth {
+		fail("hole width %g must be smaller than spine width %g", holeWidth, config.SpineWidth)
+	}
+	pitchSum := 0.0
+	for _, pitch := range config.HolePitches {
+		pitchSum += pitch
+	}
+	if math.Abs(pitchSum-config.SegmentHeight) > holePitchTolerance {
+		fail("hole pitches add up to %g, but must add up to the segment height %g", pitchSum, config.SegmentHeight)
+	}
+	// Since the unit boundary lies in the middle of the last pitch, this also
+	// keeps the outer holes from cutting through the segment's ends.
+	for _, pitch := range config.HolePitches {
+		if pitch <= holeWidth {
+			fail("hole pitch %g must be larger than the hole width %g, otherwise the holes overlap", pitch, holeWidth)
+		}
+	}
+	if config.FootLength <= config.SpineThickness {
+		fail("foot length %g must be larger than spine thickness %g", config.FootLength, config.SpineThickness)
+	}
+	if config.FootHoles > 0 {
+		config.validateFootHoles(fail)
+	}
+	if 2*config.SideBracePadding >= config.SegmentHeight {
+		fail("side brace padding %g must be less than half the segment height %g", config.SideBracePadding, config.SegmentHeight)
+	}
+	if 2*config.SideBraceInnerPadding >= config.SegmentHeight {
+		fail("side brace inner padding %g must be less than half the segment height %g", config.SideBraceInnerPadding, config.SegmentHeight)
+	}
+	if config.splitsRail() {
+		config.validateSplit(fail)
+	}
+	if lowest := config.Units - 1; !config.bracesUnit(lowest) {
+		length, depth := config.sideBraceSize(lowest)
+		fail("the side braces of the lowest unit are %.1f x %.1f mm and do not fit the build volume %g x %g x %g", length, depth, config.BuildVolume.X(), config.BuildVolume.Y(), config.BuildVolume.Z())
+	}
+	// The brace of the lowest unit has the shortest reach towards the foot, so
+	// if its attachment fits, all others do as well.
+	if lowestBraceReach := config.sideBraceFootOffsetZ(config.Units - 1); config.SideBraceAttachmentDepth >= lowestBraceReach {
+		fail("side brace attachment depth %g is too large for a %dU rack with foot length %g", config.SideBraceAttachmentDepth, config.Units, config.FootLength)
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateFootHoles checks that the holes through the foot fit between the
+// side braces and do not overlap each other or the spine's inlay.
+func (config Config) validateFootHoles(fail func(format string, args ...any)) {
+	offsets := config.footHoleOffsets()
+	// The foot's thickness changes linearly, so the outer holes pass through
+	// its thinnest parts.
+	thickness := min(config.footThicknessAt(offsets[0]), config.footThicknessAt(offsets[len(offsets)-1]))
+	if err := config.FootHole.Validate(thickness); err != nil {
+		fail("foot hole: %s", err)
+	}
+
+	holeWidth := 2 * config.FootHole.FootprintRadius()
+	if holeWidth >= config.footWidth() {
+		fail("foot hole width %g must be smaller than foot width %g", holeWidth, config.footWidth())
+	}
+	if pitch := config.footHolePitch(); pitch <= holeWidth {
+		fail("%d foot holes of width %g do not fit behind the spine, they are only %g apart", config.FootHoles, holeWidth, pitch)
+	}
+}
+
+// validateSplit checks that the pieces of the rail fit the build volume and
+// that their joints fit the rail.
+func (config Config) validateSplit(fail func(format string, args ...any)) {
+	bedWidth := min(config.BuildVolume.X(), config.BuildVolume.Y())
+	if config.SpineWidth > bedWidth {
+		fail("spine width %g must fit the build plate's width %g", config.SpineWidth, bedWidth)
+	}
+	if config.SpineThickness > config.BuildVolume.Z() {
+		fail("spine thickness %g must fit the build volume's height %g", config.SpineThickness, config.BuildVolume.Z())
+	}
+	if config.maxPieceUnits() == 0 {
+		fail("a single segment of height %g does not fit the build plate's length %g", config.SegmentHeight, max(config.BuildVolume.X(), config.BuildVolume.Y()))
+
+		return
+	}
+	if len(config.pieceUnits()) == 1 {
+		// Rails in one piece have no joints.
+		return
+	}
+
+	holeWidth := 2 * config.Hole.FootprintRadius()
+	switch config.Joint {
+	case JointPin, JointDovetail:
+		if 2*config.JointDepth >= config.SegmentHeight {
+			fail("joint depth %g must be less than half the segment height %g", config.JointDepth, config.SegmentHeight)
+		}
+		if config.JointDepth < jointWall {
+			fail("joint depth %g must be at least %g", config.JointDepth, jointWall)
+		}
+		if config.Joint == JointPin && config.pinRadius() < jointWall {
+			fail("pins do not fit beside holes of width %g in spine width %g", holeWidth, config.SpineWidth)
+		}
+		if config.Joint == JointDovetail && config.dovetailWidth(0) < jointWall {
+			fail("dovetails of depth %g do not fit beside holes of width %g in spine width %g", config.JointDepth, holeWidth, config.SpineWidth)
+		}
+	case JointSplicePlate:
+		if config.JointDepth <= config.Hole.FootprintRadius() {
+			fail("joint depth %g must be larger than the hole radius %g, so that the splice plate holds its bolts", config.JointDepth, config.Hole.FootprintRadius())
+		}
+	}
+}
+
+// holeOffsets returns the distances of the holes of a rack unit from the
+// unit's bottom.
+func (config Config) holeOffsets() []float64 {
+	offsets := make([]float64, 0, len(config.HolePitches))
+	offset := config.HolePitches[len(config.HolePitches)-1] / 2
+	for _, pitch := range config.HolePitches {
+		offsets = append(offsets, offset)
+		offset += pitch
+	}
+
+	return offsets
+}
+
+func (config Config) footLengthWithInlay() float64 {
+	return config.FootLength + config.SpineInlayWidth
+}
+
+// footThicknessAt returns the thickness of the foot's plate at the given
+// distance from its front.
+func (config Config) footThicknessAt(offset float64) float64 {
+	return config.FootThicknessFront + (config.FootThicknessBack-config.FootThicknessFront)*offset/config.footLengthWithInlay()
+}
+
+// footHolePitch is the distance between the holes through the foot, and
+// between the outer ones and the spine's inlay and the foot's back.
+func (config Config) footHolePitch() float64 {
+	inlay := config.SpineThickness + config.SpineInlayWidth
+
+	return (config.footLengthWithInlay() - inlay) / float64(config.FootHoles+1)
+}
+
+// footHoleOffsets returns the distances of the holes through the foot from
+// its front.
+func (config Config) footHoleOffsets() []float64 {
+	inlay := config.SpineThickness + config.SpineInlayWidth
+	offsets := make([]float64, 0, config.FootHoles)
+	for i := range config.FootHoles {
+		offsets = append(offsets, inlay+float64(i+1)*config.footHolePitch())
+	}
+
+	return offsets
+}
+
+func (config Config) footWidth() float64 {
+	return config.SpineWidth
+}
+
+func (config Config) bracesLeft() bool {
+	return config.SideBraceSides == SidesLeft || config.SideBraceSides == SidesBoth
+}
+
+func (config Config) bracesRight() bool {
+	return config.SideBraceSides == SidesRight || config.SideBraceSides == SidesBoth
+}
+
+func (config Config) footWidthWithSideBraces() float64 {
+	width := config.footWidth()
+	if config.bracesLeft() {
+		width += config.SideBraceWidth
+	}
+	if config.bracesRight() {
+		width += config.SideBraceWidth
+	}
+
+	return width
+}
+
+// footSpineOffsetX is the offset of the spine's center from the foot's center,
+// which leaves room for the braces on either side.
+func (config Config) footSpineOffsetX() float64 {
+	offset := 0.0
+	if config.bracesLeft() {
+		offset -= config.SideBraceWidth / 2
+	}
+	if config.bracesRight() {
+		offset += config.SideBraceWidth / 2
+	}
+
+	return offset
+}
+
+// sideBraceFootOffsetZ is the distance from the spine at which the brace of
+// the given height unit meets the foot.
+func (config Config) sideBraceFootOffsetZ(heightUnit uint8) float64 {
+	return math.Sqrt(float64(config.Units-heightUnit)/float64(config.Units)) * config.FootLength * 2 / 3
+}
+
+// sideBraceSize returns the size of the brace of the given height unit as it
+// lies on the build plate: its length along the spine, from the top of its
+// segment down to the foot, and its depth away from the spine.
+func (config Config) sideBraceSize(heightUnit uint8) (length, depth float64) {
+	length = float64(config.Units-heightUnit)*config.SegmentHeight + config.FootSpacerHeight
+
+	return length, max(config.SpineThickness, config.sideBraceFootOffsetZ(heightUnit))
+}
+
+// bracesUnit reports whether the segment of the given height unit carries
+// side braces. Braces are not split like the rail, and the braces of higher
+// units reach further down to the foot, so only the units whose braces fit the
+// build volume are braced.
+func (config Config) bracesUnit(heightUnit uint8) bool {
+	if !config.splitsRail() {
+		return true
+	}
+
+	length, depth := config.sideBraceSize(heightUnit)
+	bedLength := max(config.BuildVolume.X(), config.BuildVolume.Y())
+	bedWidth := min(config.BuildVolume.X(), config.BuildVolume.Y())
+
+	return max(length, depth) <= bedLength && min(length, depth) <= bedWidth && config.SideBraceWidth <= config.BuildVolume.Z()
+}
+
+// splitsRail reports whether the rail is split into pieces.
+func (config Config) splitsRail() bool {
+	return config.BuildVolume != mgl64.Vec3{}
+}
+
+// jointProtrusion is how far a piece's joint reaches beyond its top.
+func (config Config) jointProtrusion() float64 {
+	if config.Joint == JointSplicePlate {
+		return 0
+	}
+
+	return config.JointDepth
+}
+
+// maxPieceUnits is the number of units of the longest piece that fits the
+// build plate, including the joint on its top.
+func (config Config) maxPieceUnits() uint8 {
+	bedLength := max(config.BuildVolume.X(), config.BuildVolume.Y())
+
+	return uint8(min(math.Floor((bedLength-config.jointProtrusion())/config.SegmentHeight), math.MaxUint8))
+}
+
+// pieceUnits returns the number of units of every piece of the rail, from top
+// to bottom. The units are spread as evenly as possible, with the longer
+// pieces on top.
+func (config Config) pieceUnits() []uint8 {
+	if !config.splitsRail() || config.Units <= config.maxPieceUnits() {
+		return []uint8{config.Units}
+	}
+
+	maxUnits := int(config.maxPieceUnits())
+	pieces := (int(config.Units) + maxUnits - 1) / maxUnits
+	units := make([]uint8, 0, pieces)
+	for i := range pieces {
+		pieceUnits := int(config.Units) / pieces
+		if i < int(config.Units)%pieces {
+			pieceUnits++
+		}
+		units = append(units, uint8(pieceUnits))
+	}
+
+	return units
+}
+
+// splitBelow reports whether the rail is split below the segment of the given
+// unit, counted from the top.
+func (config Config) splitBelow(unit uint8) bool {
+	if !config.splitsRail() {
+		return false
+	}
+
+	bottom := uint8(0)
+	for _, units := range config.pieceUnits() {
+		bottom += units
+		if unit == bottom-1 {
+			return unit < config.Units-1
+		}
+	}
+
+	return false
+}
+
+// jointBand returns the distances from the spine's center between which the
+// features of pins and dovetails lie on either side of the holes, so that they
+// leave room for a wall to the holes.
+func (config Config) jointBand() (inner, outer float64) {
+	return config.Hole.FootprintRadius() + jointWall, config.SpineWidth / 2
+}
+
+// pinOffset is the distance of the pins from the spine's center and pinRadius
+// their radius. The pins' sockets are centered between the holes and the
+// faces of the spine and leave a wall to either.
+func (config Config) pinOffset() float64 {
+	inner, outer := config.jointBand()
+
+	return (inner + outer - jointWall) / 2
+}
+
+func (config Config) pinRadius() float64 {
+	inner, outer := config.jointBand()
+
+	return (outer-jointWall-inner)/2 - jointClearance
+}
+
+// dovetailWidth is the width of each of the two dovetails at the given distance
+// from their base. They sit on the faces of the spine and widen towards the
+// holes, until their sockets leave a wall to the holes at full depth.
+func (config Config) dovetailWidth(depth float64) float64 {
+	inner, outer := config.jointBand()
+	socketDepth := config.JointDepth + jointClearance
+
+	return outer - inner - jointClearance - (socketDepth-depth)*dovetailSlope
+}
+
+// splicePlateThickness is the thickness of the plates that join the pieces of
+// a split rail.
+func (config Config) splicePlateThickness() float64 {
+	return config.SpineThickness / 2
+}
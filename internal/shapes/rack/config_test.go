@@ -0,0 +1,343 @@
+package rack
+
+import (
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yeldiRium/3d-rack-brackets/internal/shapes/holes"
+)
+
+func TestConfigValidate(t *testing.T) {
+	t.Parallel()
+
+	t.Run("accepts the default config.", func(t *testing.T) {
+		t.Parallel()
+
+		require.NoError(t, DefaultConfig().Validate())
+	})
+
+	t.Run("accepts every hole type for the default screw.", func(t *testing.T) {
+		t.Parallel()
+
+		for _, holeType := range holes.Types {
+			config := DefaultConfig()
+			config.Hole = holes.ProfileFor(holeType, holes.Screws["m6"])
+
+			assert.NoError(t, config.Validate(), holeType)
+		}
+	})
+
+	t.Run("rejects a rack without units.", func(t *testing.T) {
+		t.Parallel()
+
+		config := DefaultConfig()
+		config.Units = 0
+
+		err := config.Validate()
+		require.ErrorIs(t, err, ErrInvalidConfig)
+		assert.ErrorContains(t, err, "units must be at least 1")
+	})
+
+	t.Run("rejects unknown side brace sides.", func(t *testing.T) {
+		t.Parallel()
+
+		config := DefaultConfig()
+		config.SideBraceSides = "front"
+
+		err := config.Validate()
+		require.ErrorIs(t, err, ErrInvalidConfig)
+		assert.ErrorContains(t, err, `side brace sides must be one of left, right or both, got "front"`)
+	})
+
+	t.Run("reports all non-positive dimensions at once.", func(t *testing.T) {
+		t.Parallel()
+
+		config := DefaultConfig()
+		config.FootLength = 0
+		config.SpineWidth = -1
+
+		err := config.Validate()
+		require.ErrorIs(t, err, ErrInvalidConfig)
+		assert.ErrorContains(t, err, "foot length must be positive")
+		assert.ErrorContains(t, err, "spine width must be positive")
+	})
+
+	t.Run("rejects screw holes wider than the spine.", func(t *testing.T) {
+		t.Parallel()
+
+		config := DefaultConfig()
+		config.Hole = holes.Round{Radius: 8}
+
+		err := config.Validate()
+		require.ErrorIs(t, err, ErrInvalidConfig)
+		assert.ErrorContains(t, err, "must be smaller than spine width")
+	})
+
+	t.Run("rejects side brace attachments that reach past the spine.", func(t *testing.T) {
+		t.Parallel()
+
+		config := DefaultConfig()
+		config.Units = 12
+		config.FootLength = 40
+
+		err := config.Validate()
+		require.ErrorIs(t, err, ErrInvalidConfig)
+		assert.ErrorContains(t, err, "side brace attachment depth")
+	})
+
+	t.Run("rejects hole pitches that do not add up to the segment height.", func(t *testing.T) {
+		t.Parallel()
+
+		config := DefaultConfig()
+		config.HolePitches = []float64{15.875, 15.875, 15.875}
+
+		err := config.Validate()
+		require.ErrorIs(t, err, ErrInvalidConfig)
+		assert.ErrorContains(t, err, "hole pitches add up to 47.625, but must add up to the segment height 44.45")
+	})
+
+	t.Run("rejects hole pitches that let the holes overlap.", func(t *testing.T) {
+		t.Parallel()
+
+		config := DefaultConfig()
+		config.HolePitches = []float64{20, 20, 4.45}
+
+		err := config.Validate()
+		require.ErrorIs(t, err, ErrInvalidConfig)
+		assert.ErrorContains(t, err, "hole pitch 4.45 must be larger than the hole width 6")
+	})
+
+	t.Run("rejects foot holes that do not fit the thinnest part of the foot.", func(t *testing.T) {
+		t.Parallel()
+
+		config := DefaultConfig()
+		config.FootHoles = 2
+		config.FootHole = holes.NutTrap{Radius: 3, NutWidth: 10, NutThickness: 12}
+
+		err := config.Validate()
+		require.ErrorIs(t, err, ErrInvalidConfig)
+		assert.ErrorContains(t, err, "foot hole: nut thickness 12 must be less than plate thickness")
+	})
+
+	t.Run("rejects more foot holes than fit behind the spine.", func(t *testing.T) {
+		t.Parallel()
+
+		config := DefaultConfig()
+		config.FootHoles = 40
+
+		err := config.Validate()
+		require.ErrorIs(t, err, ErrInvalidConfig)
+		assert.ErrorContains(t, err, "40 foot holes of width 6 do not fit behind the spine")
+	})
+
+	t.Run("rejects unknown joints.", func(t *testing.T) {
+		t.Parallel()
+
+		config := DefaultConfig()
+		config.Joint = "glue"
+
+		err := config.Validate()
+		require.ErrorIs(t, err, ErrInvalidConfig)
+		assert.ErrorContains(t, err, `joint must be one of pin, dovetail or splice-plate, got "glue"`)
+	})
+
+	t.Run("rejects build volumes that do not fit a single segment.", func(t *testing.T) {
+		t.Parallel()
+
+		config := DefaultConfig()
+		config.BuildVolume = mgl64.Vec3{50, 40, 20}
+
+		err := config.Validate()
+		require.ErrorIs(t, err, ErrInvalidConfig)
+		assert.ErrorContains(t, err, "a single segment of height 44.45 does not fit the build plate's length 50")
+	})
+
+	t.Run("rejects build volumes that do not fit the side braces of the lowest unit.", func(t *testing.T) {
+		t.Parallel()
+
+		config := DefaultConfig()
+		config.BuildVolume = mgl64.Vec3{60, 60, 60}
+
+		err := config.Validate()
+		require.ErrorIs(t, err, ErrInvalidConfig)
+		assert.ErrorContains(t, err, "the side braces of the lowest unit are 49.5 x 65.4 mm and do not fit the build volume 60 x 60 x 60")
+	})
+
+	t.Run("rejects joints that reach past the first hole.", func(t *testing.T) {
+		t.Parallel()
+
+		config := DefaultConfig()
+		config.Units = 7
+		config.JointDepth = 25
+
+		err := config.Validate()
+		require.ErrorIs(t, err, ErrInvalidConfig)
+		assert.ErrorContains(t, err, "joint depth 25 must be less than half the segment height 44.45")
+	})
+
+	t.Run("rejects dovetails that are wider than the spine.", func(t *testing.T) {
+		t.Parallel()
+
+		config := DefaultConfig()
+		config.Units = 7
+		config.SpineWidth = 10
+		config.Joint = JointDovetail
+		config.JointDepth = 20
+
+		err := config.Validate()
+		require.ErrorIs(t, err, ErrInvalidConfig)
+		assert.ErrorContains(t, err, "dovetails of depth 20 do not fit beside holes of width 6 in spine width 10")
+	})
+
+	t.Run("rejects joints that do not fit beside the holes.", func(t *testing.T) {
+		t.Parallel()
+
+		for _, test := range []struct {
+			joint   Joint
+			hole    holes.Type
+			message string
+		}{
+			{JointPin, holes.TypeCageNut, "pins do not fit beside holes of width 9.5 in spine width 15.875"},
+			{JointPin, holes.TypeHeatSetInsert, "pins do not fit beside holes of width 8 in spine width 15.875"},
+			{JointDovetail, holes.TypeCageNut, "dovetails of depth 8 do not fit beside holes of width 9.5 in spine width 15.875"},
+		} {
+			config := DefaultConfig()
+			config.Units = 7
+			config.Joint = test.joint
+			config.Hole = holes.ProfileFor(test.hole, holes.Screws["m6"])
+
+			err := config.Validate()
+			require.ErrorIs(t, err, ErrInvalidConfig)
+			assert.ErrorContains(t, err, test.message)
+		}
+	})
+
+	t.Run("accepts joints beside the holes of split rails.", func(t *testing.T) {
+		t.Parallel()
+
+		for _, joint := range AllJoints {
+			config := DefaultConfig()
+			config.Units = 7
+			config.Joint = joint
+
+			assert.NoError(t, config.Validate(), joint)
+		}
+	})
+
+	t.Run("rejects splice plates that do not reach past their bolts.", func(t *testing.T) {
+		t.Parallel()
+
+		config := DefaultConfig()
+		config.Units = 7
+		config.Joint = JointSplicePlate
+		config.JointDepth = 2
+
+		err := config.Validate()
+		require.ErrorIs(t, err, ErrInvalidConfig)
+		assert.ErrorContains(t, err, "joint depth 2 must be larger than the hole radius 3")
+	})
+
+	t.Run("rejects an empty hole pattern.", func(t *testing.T) {
+		t.Parallel()
+
+		config := DefaultConfig()
+		config.HolePitches = nil
+
+		err := config.Validate()
+		require.ErrorIs(t, err, ErrInvalidConfig)
+		assert.ErrorContains(t, err, "hole pitches must not be empty")
+	})
+}
+
+func TestConfigHoleOffsets(t *testing.T) {
+	t.Parallel()
+
+	t.Run("places the holes of the EIA-310 standard.", func(t *testing.T) {
+		t.Parallel()
+
+		assert.InDeltaSlice(t, []float64{6.35, 22.225, 38.1}, DefaultConfig().holeOffsets(), 1e-12)
+	})
+
+	t.Run("continues the pattern across unit boundaries.", func(t *testing.T) {
+		t.Parallel()
+
+		config := DefaultConfig()
+		config.HolePitches = []float64{10, 20, 14.45}
+
+		offsets := config.holeOffsets()
+
+		assert.InDeltaSlice(t, []float64{7.225, 17.225, 37.225}, offsets, 1e-12)
+		// The distance from the last hole of a unit to the first one of the next
+		// unit is the last pitch.
+		assert.InDelta(t, 14.45, config.SegmentHeight-offsets[2]+offsets[0], 1e-12)
+	})
+}
+
+func TestConfigFootHoleOffsets(t *testing.T) {
+	t.Parallel()
+
+	config := DefaultConfig()
+	config.FootHoles = 3
+
+	// The holes divide the foot behind the spine's inlay, which ends 13mm from
+	// the foot's front, into equal parts.
+	assert.InDeltaSlice(t, []float64{53, 93, 133}, config.footHoleOffsets(), 1e-12)
+	assert.InDelta(t, 15, config.footThicknessAt(0), 1e-12)
+	assert.InDelta(t, 10, config.footThicknessAt(config.footLengthWithInlay()), 1e-12)
+}
+
+func TestConfigPieceUnits(t *testing.T) {
+	t.Parallel()
+
+	t.Run("keeps rails that fit the build volume in one piece.", func(t *testing.T) {
+		t.Parallel()
+
+		config := DefaultConfig()
+		config.Units = 5
+
+		assert.Equal(t, []uint8{5}, config.pieceUnits())
+		for unit := range config.Units {
+			assert.False(t, config.splitBelow(unit))
+		}
+	})
+
+	t.Run("spreads the units of long rails evenly, the longer pieces on top.", func(t *testing.T) {
+		t.Parallel()
+
+		config := DefaultConfig()
+		config.Units = 11
+
+		assert.Equal(t, []uint8{4, 4, 3}, config.pieceUnits())
+		assert.True(t, config.splitBelow(3))
+		assert.True(t, config.splitBelow(7))
+		assert.False(t, config.splitBelow(10))
+	})
+
+	t.Run("leaves room for the joints on top of the pieces.", func(t *testing.T) {
+		t.Parallel()
+
+		config := DefaultConfig()
+		config.Units = 5
+		config.BuildVolume = mgl64.Vec3{5 * config.SegmentHeight, 100, 100}
+
+		assert.Equal(t, []uint8{3, 2}, config.pieceUnits())
+
+		// Splice plates are separate parts, so they take no room on the pieces.
+		config.Joint = JointSplicePlate
+
+		assert.Equal(t, []uint8{5}, config.pieceUnits())
+	})
+
+	t.Run("does not split rails without a build volume.", func(t *testing.T) {
+		t.Parallel()
+
+		config := DefaultConfig()
+		config.Units = 20
+		config.BuildVolume = mgl64.Vec3{}
+
+		assert.Equal(t, []uint8{20}, config.pieceUnits())
+	})
+}
@@ -1,126 +1,66 @@
 package rack
 
 import (
-	"bufio"
-	"fmt"
-
 	"github.com/go-gl/mathgl/mgl64"
 	"github.com/ljanyst/ghostscad/primitive"
 
 	"github.com/yeldiRium/3d-rack-brackets/internal/shapes"
 )
 
-const (
-	rackFootLength             = 170
-	rackFootLengthWithInlay    = rackFootLength + rackSpineInlayWidth
-	RackFootThicknessFront     = 15
-	rackFootThicknessBack      = 10
-	rackFootWidth              = rackSpineWidth
-	rackFootWidthWithSideBrace = rackFootWidth + sideBraceWidth
-	rackFootSpacerHeight       = 5
-)
-
 type RackFoot struct {
-	primitive.ParentImpl
-
-	prefix string
-
-	name     string
-	contents *primitive.List
-
-	anchors         map[string]shapes.Anchor
-	anchorTransform *primitive.Transform
+	shapes.AnchoredBase
 }
 
-func NewRackFoot(name string) *RackFoot {
+func NewRackFoot(name string, config Config) *RackFoot {
 	footBox := primitive.NewRotation(
 		mgl64.Vec3{0, 90, 0},
 		primitive.NewLinearExtrusion(
-			rackFootWidthWithSideBrace,
+			config.footWidthWithSideBraces(),
 			primitive.NewPolygon([]mgl64.Vec2{
 				{0, 0},
-				{RackFootThicknessFront + rackFootSpacerHeight, 0},
-				{rackFootThicknessBack + rackFootSpacerHeight, rackFootLengthWithInlay},
-				{rackFootSpacerHeight, rackFootLengthWithInlay},
-				{rackFootSpacerHeight, rackSpineThickness + rackSpineInlayWidth},
-				{0, rackSpineThickness + rackSpineInlayWidth},
+				{config.FootThicknessFront + config.FootSpacerHeight, 0},
+				{config.FootThicknessBack + config.FootSpacerHeight, config.footLengthWithInlay()},
+				{config.FootSpacerHeight, config.footLengthWithInlay()},
+				{config.FootSpacerHeight, config.SpineThickness + config.SpineInlayWidth},
+				{0, config.SpineThickness + config.SpineInlayWidth},
 			}),
 		),
 	)
 
-	rackFoot := &RackFoot{
-		name:     name,
-		contents: primitive.NewList(),
+	var foot primitive.Primitive = footBox
+	if config.FootHoles > 0 {
+		difference := []primitive.Primitive{footBox}
+		for _, offset := range config.footHoleOffsets() {
+			thickness := config.footThicknessAt(offset)
+			difference = append(difference, primitive.NewTranslation(
+				mgl64.Vec3{config.footSpineOffsetX(), offset, -config.FootSpacerHeight - thickness/2},
+				config.FootHole.Cutout(thickness),
+			))
+		}
+		foot = primitive.NewDifference(difference...)
 	}
-	rackFoot.contents.Add(footBox)
-	rackFoot.anchors = map[string]shapes.Anchor{
-		"top": shapes.NewAnchor(
+
+	rackFoot := &RackFoot{}
+	rackFoot.AnchoredBase = shapes.MakeAnchoredBase(rackFoot, name)
+	rackFoot.Add(foot)
+	rackFoot.AddAnchors(
+		shapes.NewAnchor(
 			"top",
 			rackFoot,
-			primitive.NewTranslation(mgl64.Vec3{
-				-sideBraceWidth / 2,
-				(rackSpineThickness / 2) + rackSpineInlayWidth,
+			mgl64.Vec3{
+				config.footSpineOffsetX(),
+				(config.SpineThickness / 2) + config.SpineInlayWidth,
 				0,
-			}),
+			},
 			mgl64.Vec3{0, 0, 1},
 		),
-	}
+	)
 
 	return rackFoot
 }
 
-func (foot *RackFoot) Anchors() map[string]shapes.Anchor {
-	return foot.anchors
-}
-
-func (foot *RackFoot) SetAnchorTransform(transform primitive.Transform) error {
-	if foot.anchorTransform != nil {
-		// TODO check if the preexisting anchorTransform might be identical to
-		// transform. If so, don't return an error.
-		return fmt.Errorf("trying to set conflicting anchor transforms")
-	}
-
-	foot.anchorTransform = &transform
-
-	return nil
-}
-
-func (foot *RackFoot) GetAnchorTransform() *primitive.Transform {
-	return foot.anchorTransform
-}
-
-func (foot *RackFoot) Disable() primitive.Primitive { //nolint:ireturn
-	foot.prefix = "*"
-
-	return foot
-}
-
-func (foot *RackFoot) ShowOnly() primitive.Primitive { //nolint:ireturn
-	foot.prefix = "!"
-
-	return foot
-}
-
-func (foot *RackFoot) Highlight() primitive.Primitive { //nolint:ireturn
-	foot.prefix = "#"
-
-	return foot
-}
-
-func (foot *RackFoot) Transparent() primitive.Primitive { //nolint:ireturn
-	foot.prefix = "%"
-
-	return foot
-}
-
-func (foot *RackFoot) Prefix() string {
-	return foot.prefix
-}
-
-func (foot *RackFoot) Render(w *bufio.Writer) {
-	if foot.anchorTransform == nil {
-		panic("cannot render foot without resolving its anchors")
-	}
-	foot.anchorTransform.Add(foot.contents)
-	foot.anchorTransform.Render(w)
+// PrintOrientation turns the foot onto its side, so that its profile, which
+// is extruded along x, is printed layer by layer.
+func (foot *RackFoot) PrintOrientation() shapes.PrintOrientation {
+	return shapes.PrintOrientation{FaceDown: mgl64.Vec3{-1, 0, 0}}
 }
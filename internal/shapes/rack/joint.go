@@ -0,0 +1,92 @@
+package rack
+
+import (
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/ljanyst/ghostscad/primitive"
+)
+
+// overcut is how far sockets reach beyond the faces they are cut into, so that
+// the faces of the difference do not coincide.
+const overcut = 0.5
+
+// newJointTenon creates the half of the joint that a segment below a split
+// carries on its top. It returns nil for joints without tenons.
+func newJointTenon(config Config) primitive.Primitive { //nolint:ireturn
+	top := config.SegmentHeight / 2
+
+	switch config.Joint {
+	case JointPin:
+		return primitive.NewTranslation(mgl64.Vec3{0, 0, top}, newPins(config, config.JointDepth, 0))
+	case JointDovetail:
+		return primitive.NewTranslation(mgl64.Vec3{0, 0, top}, newDovetail(config, 0, config.JointDepth, 0, 0))
+	case JointSplicePlate:
+		return nil
+	default:
+		panic("unknown joint " + string(config.Joint))
+	}
+}
+
+// newJointSocket creates the cutout that a segment above a split receives the
+// tenon of the segment below with. It returns nil for joints without sockets.
+func newJointSocket(config Config) primitive.Primitive { //nolint:ireturn
+	bottom := -config.SegmentHeight / 2
+
+	switch config.Joint {
+	case JointPin:
+		return primitive.NewTranslation(mgl64.Vec3{0, 0, bottom - overcut}, newPins(config, config.JointDepth+jointClearance+overcut, jointClearance))
+	case JointDovetail:
+		return primitive.NewTranslation(mgl64.Vec3{0, 0, bottom}, newDovetail(config, -overcut, config.JointDepth+jointClearance, jointClearance, overcut))
+	case JointSplicePlate:
+		return nil
+	default:
+		panic("unknown joint " + string(config.Joint))
+	}
+}
+
+// newPins creates two pins of the given length that start at z=0, one on
+// either side of the holes, widened by clearance.
+func newPins(config Config, length, clearance float64) *primitive.List {
+	pins := primitive.NewList()
+	for _, x := range []float64{-config.pinOffset(), config.pinOffset()} {
+		pin := primitive.NewCylinder(length, config.pinRadius()+clearance)
+		pin.Center = false
+		pins.Add(primitive.NewTranslation(mgl64.Vec3{x, 0, 0}, pin))
+	}
+
+	return pins
+}
+
+// newDovetail creates two dovetails between the heights from and to, one on
+// either face of the spine, that widen towards the holes and run through the
+// spine from front to back. They are widened by clearance towards the holes
+// and reach overhang beyond the spine's faces. Their tips end in a straight
+// edge of jointWall instead of an acute corner, which would break off.
+func newDovetail(config Config, from, to, clearance, overhang float64) primitive.Primitive { //nolint:ireturn
+	_, outer := config.jointBand()
+	outer += overhang
+	tip := config.SpineWidth/2 - config.dovetailWidth(to-jointWall) - clearance
+	base := config.SpineWidth/2 - config.dovetailWidth(from) - clearance
+
+	// The outlines are drawn in the x-z plane and extruded along y.
+	outlines := primitive.NewList(
+		primitive.NewPolygon([]mgl64.Vec2{
+			{base, from},
+			{outer, from},
+			{outer, to},
+			{tip, to},
+			{tip, to - jointWall},
+		}),
+		primitive.NewPolygon([]mgl64.Vec2{
+			{-outer, from},
+			{-base, from},
+			{-tip, to - jointWall},
+			{-tip, to},
+			{-outer, to},
+		}),
+	)
+
+	return primitive.NewRotation(
+		mgl64.Vec3{90, 0, 0},
+		primitive.NewLinearExtrusion(config.SpineThickness+2*overhang, outlines),
+	)
+}
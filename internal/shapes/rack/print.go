@@ -0,0 +1,151 @@
+package rack
+
+import (
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/ljanyst/ghostscad/primitive"
+
+	"github.com/yeldiRium/3d-rack-brackets/internal/ghostscad"
+	"github.com/yeldiRium/3d-rack-brackets/internal/mesh"
+	"github.com/yeldiRium/3d-rack-brackets/internal/shapes"
+)
+
+// PrintPart is a part of the rack that is printed on its own. It consists of
+// one or more of the rack's anchored parts, e.g. the segments of a rail piece,
+// which keep their assembled placement relative to each other.
+type PrintPart struct {
+	Name  string
+	Kind  Part
+	Parts []shapes.Anchored
+
+	// Orientation rotates the first of Parts from its own frame into the
+	// orientation it is printed in, as declared by its PrintOrientation.
+	Orientation mgl64.Mat4
+}
+
+// PrintParts lists the given kinds of parts as they are printed: rail pieces,
+// splice plates, side braces and the foot. The anchors of the rack must have
+// been resolved.
+func (rack *Rack) PrintParts(kinds ...Part) []PrintPart {
+	selected := map[Part]bool{}
+	for _, kind := range kinds {
+		selected[kind] = true
+	}
+
+	var printParts []PrintPart
+	if selected[PartSegments] {
+		for _, piece := range rack.Pieces {
+			parts := make([]shapes.Anchored, 0, len(piece.Segments))
+			for _, segment := range piece.Segments {
+				parts = append(parts, segment)
+			}
+			printParts = append(printParts, newPrintPart(piece.Name, PartSegments, parts...))
+		}
+		for _, splicePlate := range rack.SplicePlates {
+			printParts = append(printParts, newPrintPart(splicePlate.Name(), PartSegments, splicePlate))
+		}
+	}
+	if selected[PartSideBraces] {
+		for _, sideBrace := range rack.SideBraces {
+			printParts = append(printParts, newPrintPart(sideBrace.Name(), PartSideBraces, sideBrace))
+		}
+	}
+	if selected[PartFoot] && rack.Foot != nil {
+		printParts = append(printParts, newPrintPart(rack.Foot.Name(), PartFoot, rack.Foot))
+	}
+
+	return printParts
+}
+
+// newPrintPart creates a print part in the print orientation of its first
+// part. Parts that do not declare one are printed as they are modelled.
+func newPrintPart(name string, kind Part, parts ...shapes.Anchored) PrintPart {
+	orientation := shapes.FlatOrientation
+	if printable, ok := parts[0].(shapes.Printable); ok {
+		orientation = printable.PrintOrientation()
+	}
+
+	return PrintPart{Name: name, Kind: kind, Parts: parts, Orientation: orientation.Matrix()}
+}
+
+// Primitive returns the part in its print orientation, lying on z=0 with the
+// origin of its first part above the origin.
+func (printPart PrintPart) Primitive() (primitive.Primitive, error) { //nolint:ireturn
+	orientation := printPart.orientation()
+	box, err := printPart.boundsAt(orientation)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]primitive.Primitive, 0, len(printPart.Parts))
+	for _, part := range printPart.Parts {
+		item, ok := part.(primitive.Primitive)
+		if !ok {
+			panic("the parts of the rack are primitives. this should not happen")
+		}
+		items = append(items, item)
+	}
+
+	return ghostscad.NewMultMatrix(drop(box).Mul4(orientation), items...), nil
+}
+
+// Bounds returns the bounding box of the part in its print orientation, see
+// Primitive.
+func (printPart PrintPart) Bounds() (mesh.Box, error) {
+	box, err := printPart.boundsAt(printPart.orientation())
+	if err != nil {
+		return mesh.Box{}, err
+	}
+
+	return box.Transformed(drop(box)), nil
+}
+
+// orientation moves the parts from their place in the assembly into the print
+// orientation, with the origin of the first part at the origin.
+func (printPart PrintPart) orientation() mgl64.Mat4 {
+	origin := printPart.Parts[0].GetAnchorTransform()
+	if origin == nil {
+		panic("cannot print " + printPart.Name + " without resolving its anchors")
+	}
+
+	return printPart.Orientation.Mul4(origin.Inv())
+}
+
+// boundsAt returns the bounding box of the parts after moving them from their
+// place in the assembly by transform.
+func (printPart PrintPart) boundsAt(transform mgl64.Mat4) (mesh.Box, error) {
+	result := mesh.EmptyBox()
+	for _, part := range printPart.Parts {
+		box, err := part.Bounds(transform)
+		if err != nil {
+			return mesh.Box{}, err
+		}
+		result = result.Union(box)
+	}
+
+	return result, nil
+}
+
+// drop returns the translation that moves box down onto z=0.
+func drop(box mesh.Box) mgl64.Mat4 {
+	if box.IsEmpty() {
+		return mgl64.Ident4()
+	}
+
+	return mgl64.Translate3D(0, 0, -box.Min.Z())
+}
+
+// Bounds returns the bounding box of the given parts together, at the places
+// their anchors were resolved to. It fails for parts whose anchors were not
+// resolved.
+func Bounds(parts ...shapes.Anchored) (mesh.Box, error) {
+	result := mesh.EmptyBox()
+	for _, part := range parts {
+		box, err := part.Bounds(mgl64.Ident4())
+		if err != nil {
+			return mesh.Box{}, err
+		}
+		result = result.Union(box)
+	}
+
+	return result, nil
+}
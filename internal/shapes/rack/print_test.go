@@ -0,0 +1,155 @@
+package rack
+
+import (
+	"math"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yeldiRium/3d-rack-brackets/internal/ghostscad"
+	"github.com/yeldiRium/3d-rack-brackets/internal/mesh"
+	"github.com/yeldiRium/3d-rack-brackets/internal/shapes"
+)
+
+func TestRackPrintParts(t *testing.T) {
+	t.Parallel()
+
+	t.Run("lists the selected parts by their names.", func(t *testing.T) {
+		t.Parallel()
+
+		config := DefaultConfig()
+		config.Units = 7
+		config.Joint = JointSplicePlate
+		config.SideBraceSides = SidesLeft
+
+		rack := MakeRack(config)
+		require.NoError(t, shapes.ResolveAnchors(rack.Foot))
+
+		var names []string
+		for _, printPart := range rack.PrintParts(PartSegments, PartFoot) {
+			names = append(names, printPart.Name)
+		}
+
+		assert.Equal(t, []string{"rail-0", "rail-1", "spliceplate-0", "foot"}, names)
+		// The braces of the top two units do not fit the build volume.
+		assert.Len(t, rack.PrintParts(PartSideBraces), 5)
+	})
+
+	t.Run("lays rail pieces on their backs on z=0.", func(t *testing.T) {
+		t.Parallel()
+
+		config := DefaultConfig()
+		config.Units = 7
+
+		rack := MakeRack(config)
+		require.NoError(t, shapes.ResolveAnchors(rack.Foot))
+
+		for _, printPart := range rack.PrintParts(PartSegments) {
+			shape, err := printPart.Primitive()
+			require.NoError(t, err)
+			transform, ok := shape.(*ghostscad.MultMatrix)
+			require.True(t, ok)
+
+			for i, part := range printPart.Parts {
+				placement := transform.Matrix.Mul4(*part.GetAnchorTransform())
+				center := mgl64.TransformCoordinate(mgl64.Vec3{}, placement)
+				back := mgl64.TransformCoordinate(mgl64.Vec3{0, config.SpineThickness / 2, 0}, placement)
+
+				assert.InDeltaSlice(t, []float64{0, -float64(i) * config.SegmentHeight, config.SpineThickness / 2}, center[:], 1e-9, "%s segment %d", printPart.Name, i)
+				assert.InDelta(t, 0, back.Z(), 1e-9, "%s segment %d", printPart.Name, i)
+			}
+		}
+	})
+	t.Run("lays every part on z=0.", func(t *testing.T) {
+		t.Parallel()
+
+		config := DefaultConfig()
+		config.Units = 7
+		config.Joint = JointSplicePlate
+
+		rack := MakeRack(config)
+		require.NoError(t, shapes.ResolveAnchors(rack.Foot))
+
+		for _, printPart := range rack.PrintParts(Parts...) {
+			shape, err := printPart.Primitive()
+			require.NoError(t, err)
+			partMesh, err := mesh.FromPrimitive(shape)
+			require.NoError(t, err)
+			box, err := printPart.Bounds()
+			require.NoError(t, err)
+
+			assert.InDelta(t, 0, partMesh.Bounds().Min.Z(), 1e-9, printPart.Name)
+			assert.InDelta(t, 0, box.Min.Z(), 1e-9, printPart.Name)
+		}
+	})
+	t.Run("fits the split rail into the build volume.", func(t *testing.T) {
+		t.Parallel()
+
+		config := DefaultConfig()
+		config.Units = 9
+		config.BuildVolume = mgl64.Vec3{180, 180, 180}
+
+		rack := MakeRack(config)
+		require.NoError(t, shapes.ResolveAnchors(rack.Foot))
+
+		for _, printPart := range rack.PrintParts(PartSegments) {
+			box, err := printPart.Bounds()
+			require.NoError(t, err)
+
+			assert.True(t, box.Fits(config.BuildVolume), "%s measures %v", printPart.Name, box.Size())
+		}
+	})
+	t.Run("turns every part onto the side it declares to be printed on.", func(t *testing.T) {
+		t.Parallel()
+
+		config := DefaultConfig()
+		config.Units = 7
+		config.Joint = JointSplicePlate
+
+		rack := MakeRack(config)
+		require.NoError(t, shapes.ResolveAnchors(rack.Foot))
+
+		for _, printPart := range rack.PrintParts(Parts...) {
+			printable, ok := printPart.Parts[0].(shapes.Printable)
+			require.True(t, ok, printPart.Name)
+
+			faceDown := mgl64.TransformNormal(printable.PrintOrientation().FaceDown, printPart.Orientation)
+			assert.InDeltaSlice(t, []float64{0, 0, -1}, faceDown[:], 1e-9, printPart.Name)
+		}
+	})
+}
+
+func TestBounds(t *testing.T) {
+	t.Parallel()
+
+	t.Run("measures the parts together at their resolved places.", func(t *testing.T) {
+		t.Parallel()
+
+		config := DefaultConfig()
+		config.Units = 2
+
+		rack := MakeRack(config)
+		require.NoError(t, shapes.ResolveAnchors(rack.Foot))
+
+		first, err := Bounds(rack.Segments[0])
+		require.NoError(t, err)
+		second, err := Bounds(rack.Segments[1])
+		require.NoError(t, err)
+		both, err := Bounds(rack.Segments[0], rack.Segments[1])
+		require.NoError(t, err)
+
+		assert.InDelta(t, config.SegmentHeight, math.Abs(second.Min.Z()-first.Min.Z()), 1e-5)
+		assert.Equal(t, first.Union(second), both)
+	})
+
+	t.Run("fails for parts whose anchors were not resolved.", func(t *testing.T) {
+		t.Parallel()
+
+		rack := MakeRack(DefaultConfig())
+
+		_, err := Bounds(rack.Foot)
+		assert.ErrorContains(t, err, "cannot measure foot without resolving its anchors")
+	})
+}
@@ -6,49 +6,95 @@ import (
 	"github.com/ljanyst/ghostscad/primitive"
 )
 
-const (
-	screwRadiusM6 = 3.0
+// Part identifies a kind of part of the rack.
+type Part string
 
-	rackSpineWidth      = 15.875
-	rackSpineThickness  = 10.0
-	rackSpineInlayWidth = 3.0
+const (
+	PartSegments   Part = "segments"
+	PartSideBraces Part = "braces"
+	PartFoot       Part = "foot"
 )
 
+// Parts lists all kinds of parts in the order they are assembled.
+var Parts = []Part{PartSegments, PartSideBraces, PartFoot}
+
+// RailPiece is a run of consecutive segments that is printed as one part.
+type RailPiece struct {
+	Name     string
+	Segments []*RackSegment
+}
+
 type Rack struct {
 	primitive.ParentImpl
 	primitive.List
 
-	Foot *RackFoot
+	Segments     []*RackSegment
+	Pieces       []*RailPiece
+	SplicePlates []*SplicePlate
+	SideBraces   []*SideBrace
+	Foot         *RackFoot
 }
 
-func MakeRack(heightUnits uint8) *Rack {
+// MakeRack assembles a rack from the given config. The config is expected to
+// be valid, see Config.Validate.
+func MakeRack(config Config) *Rack {
 	rack := &Rack{}
 
-	if heightUnits == 0 {
+	if config.Units == 0 {
 		return rack
 	}
 
 	var previousSegment *RackSegment
+	piece := &RailPiece{Name: "rail-0"}
+	rack.Pieces = append(rack.Pieces, piece)
 
-	for i := range heightUnits {
-		nextSegment := NewRackSegment(fmt.Sprintf("segment-%d", i))
-		nextBrace := NewSideBrace(fmt.Sprintf("sidebrace-%d", i), heightUnits, i)
+	for i := range config.Units {
+		nextSegment := NewRackSegment(fmt.Sprintf("segment-%d", i), config, i)
 
 		if previousSegment != nil {
 			if err := previousSegment.Anchors()["bottom"].Connect(nextSegment.Anchors()["top"], 0); err != nil {
 				panic("failed to connect rack segments. this should not happen")
 			}
 		}
-		if err := nextSegment.Anchors()["left"].Connect(nextBrace.Anchors()["segmentattach"], 0); err != nil {
-			panic("failed to attach side brace to rack segment")
+		rack.Segments = append(rack.Segments, nextSegment)
+		rack.Add(nextSegment)
+		piece.Segments = append(piece.Segments, nextSegment)
+
+		if config.splitBelow(i) {
+			if config.Joint == JointSplicePlate {
+				splicePlate := NewSplicePlate(fmt.Sprintf("spliceplate-%d", len(rack.SplicePlates)), config)
+				if err := nextSegment.Anchors()["back"].Connect(splicePlate.Anchors()["segmentattach"], 0); err != nil {
+					panic("failed to attach splice plate to rack segment")
+				}
+				rack.SplicePlates = append(rack.SplicePlates, splicePlate)
+				rack.Add(splicePlate)
+			}
+
+			piece = &RailPiece{Name: fmt.Sprintf("rail-%d", len(rack.Pieces))}
+			rack.Pieces = append(rack.Pieces, piece)
+		}
+
+		if config.bracesLeft() && config.bracesUnit(i) {
+			leftBrace := NewSideBrace(fmt.Sprintf("sidebrace-left-%d", i), config, i)
+			if err := nextSegment.Anchors()["left"].Connect(leftBrace.Anchors()["segmentattach"], 0); err != nil {
+				panic("failed to attach side brace to rack segment")
+			}
+			rack.SideBraces = append(rack.SideBraces, leftBrace)
+			rack.Add(leftBrace)
+		}
+		if config.bracesRight() && config.bracesUnit(i) {
+			rightBrace := NewMirroredSideBrace(fmt.Sprintf("sidebrace-right-%d", i), config, i)
+			if err := nextSegment.Anchors()["right"].Connect(rightBrace.Anchors()["segmentattach"], 0); err != nil {
+				panic("failed to attach side brace to rack segment")
+			}
+			rack.SideBraces = append(rack.SideBraces, rightBrace)
+			rack.Add(rightBrace)
 		}
 
 		previousSegment = nextSegment
-		rack.Add(nextSegment)
-		rack.Add(nextBrace)
 	}
 
-	foot := NewRackFoot("foot")
+	foot := NewRackFoot("foot", config)
 	if err := foot.Anchors()["top"].Connect(previousSegment.Anchors()["bottom"], 0); err != nil {
 		panic("failed to connect rack segments. this should not happen")
 	}
@@ -57,3 +103,34 @@ func MakeRack(heightUnits uint8) *Rack {
 
 	return rack
 }
+
+// Select returns a list containing only the given kinds of parts, keeping the
+// order in which they were assembled. The anchors of the rack must have been
+// resolved already, since the selected parts are still rendered in place.
+func (rack *Rack) Select(parts ...Part) *primitive.List {
+	selected := map[Part]bool{}
+	for _, part := range parts {
+		selected[part] = true
+	}
+
+	list := primitive.NewList()
+	for _, item := range rack.Items {
+		switch item.(type) {
+		case *RackSegment, *SplicePlate:
+			if !selected[PartSegments] {
+				continue
+			}
+		case *SideBrace:
+			if !selected[PartSideBraces] {
+				continue
+			}
+		case *RackFoot:
+			if !selected[PartFoot] {
+				continue
+			}
+		}
+		list.Add(item)
+	}
+
+	return list
+}
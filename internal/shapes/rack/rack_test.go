@@ -0,0 +1,144 @@
+package rack
+
+import (
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yeldiRium/3d-rack-brackets/internal/mesh"
+	"github.com/yeldiRium/3d-rack-brackets/internal/printability"
+	"github.com/yeldiRium/3d-rack-brackets/internal/shapes"
+)
+
+func TestMakeRack(t *testing.T) {
+	t.Parallel()
+
+	t.Run("braces the sides selected in the config.", func(t *testing.T) {
+		t.Parallel()
+
+		for sides, expectedBraces := range map[Sides]int{
+			SidesLeft:  3,
+			SidesRight: 3,
+			SidesBoth:  6,
+		} {
+			config := DefaultConfig()
+			config.SideBraceSides = sides
+
+			rack := MakeRack(config)
+
+			assert.Len(t, rack.SideBraces, expectedBraces, "sides %s", sides)
+			require.NoError(t, shapes.ResolveAnchors(rack.Foot), "sides %s", sides)
+		}
+	})
+
+	t.Run("places the right braces as mirror images of the left ones.", func(t *testing.T) {
+		t.Parallel()
+
+		rack := MakeRack(DefaultConfig())
+		require.NoError(t, shapes.ResolveAnchors(rack.Foot))
+
+		point := mgl64.Vec3{20, 7, 1}
+		mirroredPoint := mgl64.Vec3{20, 7, -1}
+		for i := 0; i < len(rack.SideBraces); i += 2 {
+			left := mgl64.TransformCoordinate(point, *rack.SideBraces[i].GetAnchorTransform())
+			right := mgl64.TransformCoordinate(mirroredPoint, *rack.SideBraces[i+1].GetAnchorTransform())
+
+			assert.InDeltaSlice(t, []float64{-left[0], left[1], left[2]}, right[:], 1e-9, "brace %d", i/2)
+		}
+	})
+
+	t.Run("centers the spine on a foot that is braced on both sides.", func(t *testing.T) {
+		t.Parallel()
+
+		config := DefaultConfig()
+		rack := MakeRack(config)
+		require.NoError(t, shapes.ResolveAnchors(rack.Foot))
+
+		for _, segment := range rack.Segments {
+			assert.InDelta(t, 0, segment.GetAnchorTransform().At(0, 3), 1e-12)
+		}
+		assert.InDelta(t, config.SpineWidth+2*config.SideBraceWidth, config.footWidthWithSideBraces(), 1e-12)
+	})
+	t.Run("splits long rails into pieces.", func(t *testing.T) {
+		t.Parallel()
+
+		config := DefaultConfig()
+		config.Units = 7
+
+		rack := MakeRack(config)
+		require.NoError(t, shapes.ResolveAnchors(rack.Foot))
+
+		require.Len(t, rack.Pieces, 2)
+		assert.Equal(t, "rail-0", rack.Pieces[0].Name)
+		assert.Equal(t, rack.Segments[:4], rack.Pieces[0].Segments)
+		assert.Equal(t, rack.Segments[4:], rack.Pieces[1].Segments)
+		assert.Empty(t, rack.SplicePlates)
+	})
+
+	t.Run("braces only the units whose braces fit the build volume.", func(t *testing.T) {
+		t.Parallel()
+
+		config := DefaultConfig()
+		config.Units = 7
+		config.SideBraceSides = SidesLeft
+
+		rack := MakeRack(config)
+		require.NoError(t, shapes.ResolveAnchors(rack.Foot))
+
+		var names []string
+		for _, printPart := range rack.PrintParts(PartSideBraces) {
+			names = append(names, printPart.Name)
+
+			box, err := printPart.Bounds()
+			require.NoError(t, err)
+			assert.True(t, box.Fits(config.BuildVolume), "%s measures %v", printPart.Name, box.Size())
+		}
+		// The braces of the top two units would reach 316 and 272 mm down to
+		// the foot, which is more than the build plate's length.
+		assert.Equal(t, []string{"sidebrace-left-2", "sidebrace-left-3", "sidebrace-left-4", "sidebrace-left-5", "sidebrace-left-6"}, names)
+	})
+
+	t.Run("keeps the joints clear of the holes.", func(t *testing.T) {
+		t.Parallel()
+
+		for _, joint := range []Joint{JointPin, JointDovetail} {
+			config := DefaultConfig()
+			config.Units = 7
+			config.Joint = joint
+
+			rack := MakeRack(config)
+			require.NoError(t, shapes.ResolveAnchors(rack.Foot))
+
+			for _, printPart := range rack.PrintParts(PartSegments) {
+				shape, err := printPart.Primitive()
+				require.NoError(t, err)
+				partMesh, err := mesh.FromPrimitive(shape)
+				require.NoError(t, err)
+
+				thinWalls := printability.ThinWalls(partMesh, printability.Settings{MinWallThickness: 0.8})
+				assert.Empty(t, thinWalls, "%s joint of %s", joint, printPart.Name)
+			}
+		}
+	})
+
+	t.Run("bolts splice plates across the splits.", func(t *testing.T) {
+		t.Parallel()
+
+		config := DefaultConfig()
+		config.Units = 11
+		config.Joint = JointSplicePlate
+
+		rack := MakeRack(config)
+		require.NoError(t, shapes.ResolveAnchors(rack.Foot))
+
+		require.Len(t, rack.SplicePlates, 2)
+		for i, segment := range []*RackSegment{rack.Segments[3], rack.Segments[7]} {
+			plate := *rack.SplicePlates[i].GetAnchorTransform()
+			split := mgl64.TransformCoordinate(mgl64.Vec3{0, 0, -config.SegmentHeight / 2}, *segment.GetAnchorTransform())
+
+			assert.InDeltaSlice(t, split[:], []float64{plate.At(0, 3), plate.At(1, 3) - (config.SpineThickness+config.splicePlateThickness())/2, plate.At(2, 3)}, 1e-9, "plate %d", i)
+		}
+	})
+}
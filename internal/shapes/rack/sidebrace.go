@@ -1,8 +1,6 @@
 package rack
 
 import (
-	"bufio"
-	"fmt"
 	"math"
 
 	"github.com/go-gl/mathgl/mgl64"
@@ -11,139 +9,85 @@ import (
 	"github.com/yeldiRium/3d-rack-brackets/internal/shapes"
 )
 
-const (
-	sideBracePadding         = 10
-	sideBraceInnerPadding    = 2
-	sideBraceAttachmentDepth = 20
-	sideBraceWidth           = 3.0
-)
-
 type SideBrace struct {
-	primitive.ParentImpl
-
-	prefix string
-
-	name     string
-	contents *primitive.List
-
-	anchors         map[string]shapes.Anchor
-	anchorTransform *primitive.Transform
+	shapes.AnchoredBase
 }
 
 // NewSideBrace constructs a side brace.
 // heightUnit is the number of the segment the brace belongs to.
 //
-//	0 is the lowest brace.
+//	0 is the top brace.
 //	This is used to calculate the connection point to the rack foot.
-func NewSideBrace(name string, totalHeight, heightUnit uint8) *SideBrace {
-	footOffsetY := float64(totalHeight-heightUnit-1)*rackSegmentHeight + rackFootSpacerHeight
-	footOffsetZ := math.Sqrt(float64(totalHeight-heightUnit)/float64(totalHeight)) * rackFootLength * 2 / 3
+func NewSideBrace(name string, config Config, heightUnit uint8) *SideBrace {
+	totalHeight := config.Units
+	footOffsetY := float64(totalHeight-heightUnit-1)*config.SegmentHeight + config.FootSpacerHeight
+	footOffsetZ := config.sideBraceFootOffsetZ(heightUnit)
 
-	scaledAttachmentDepth := sideBraceAttachmentDepth * math.Pow(0.8, float64(totalHeight-heightUnit-1))
+	scaledAttachmentDepth := config.SideBraceAttachmentDepth * math.Pow(0.8, float64(totalHeight-heightUnit-1))
 
 	shape := primitive.NewPolygon([]mgl64.Vec2{
 		{0, 0},
-		{rackSegmentHeight, 0},
-		{rackSegmentHeight, rackSpineThickness},
-		{rackSegmentHeight - sideBracePadding, rackSpineThickness},
-		{rackSegmentHeight + footOffsetY, footOffsetZ - scaledAttachmentDepth},
-		{rackSegmentHeight + footOffsetY, footOffsetZ},
-		{sideBracePadding, rackSpineThickness},
-		{0, rackSpineThickness},
+		{config.SegmentHeight, 0},
+		{config.SegmentHeight, config.SpineThickness},
+		{config.SegmentHeight - config.SideBracePadding, config.SpineThickness},
+		{config.SegmentHeight + footOffsetY, footOffsetZ - scaledAttachmentDepth},
+		{config.SegmentHeight + footOffsetY, footOffsetZ},
+		{config.SideBracePadding, config.SpineThickness},
+		{0, config.SpineThickness},
 	})
 
-	cutoutDepth := math.Pow(float64(totalHeight-heightUnit)/float64(totalHeight), 1.05) * rackFootLength / 3
+	cutoutDepth := math.Pow(float64(totalHeight-heightUnit)/float64(totalHeight), 1.05) * config.FootLength / 3
 	cutout := primitive.NewDifference(primitive.NewPolygon([]mgl64.Vec2{
-		{rackSegmentHeight/2 - sideBraceInnerPadding, rackSpineThickness},
-		{rackSegmentHeight + footOffsetY, footOffsetZ - scaledAttachmentDepth/2},
-		{rackSegmentHeight/2 + sideBraceInnerPadding, rackSpineThickness},
+		{config.SegmentHeight/2 - config.SideBraceInnerPadding, config.SpineThickness},
+		{config.SegmentHeight + footOffsetY, footOffsetZ - scaledAttachmentDepth/2},
+		{config.SegmentHeight/2 + config.SideBraceInnerPadding, config.SpineThickness},
 	}), primitive.NewPolygon([]mgl64.Vec2{
-		{0, rackSpineThickness + cutoutDepth},
-		{0, rackFootLength},
-		{rackSegmentHeight + footOffsetY, rackFootLength},
-		{rackSegmentHeight + footOffsetY, rackSpineThickness + cutoutDepth},
+		{0, config.SpineThickness + cutoutDepth},
+		{0, config.FootLength},
+		{config.SegmentHeight + footOffsetY, config.FootLength},
+		{config.SegmentHeight + footOffsetY, config.SpineThickness + cutoutDepth},
 	}))
 
 	finalShape := primitive.NewDifference(shape, cutout)
 
 	extrusion := primitive.NewLinearExtrusion(
-		sideBraceWidth,
+		config.SideBraceWidth,
 		finalShape,
 	)
 
-	sideBrace := &SideBrace{
-		name:     name,
-		contents: primitive.NewList(),
-	}
-	sideBrace.contents.Add(extrusion)
-	sideBrace.anchors = map[string]shapes.Anchor{
-		"segmentattach": shapes.NewAnchor(
+	sideBrace := &SideBrace{}
+	sideBrace.AnchoredBase = shapes.MakeAnchoredBase(sideBrace, name)
+	sideBrace.Add(extrusion)
+	sideBrace.AddAnchors(
+		// Up along the spine is -x in the brace's profile.
+		shapes.NewOrientedAnchor(
 			"segmentattach",
 			sideBrace,
-			primitive.NewTranslation(mgl64.Vec3{
-				rackSegmentHeight / 2,
-				rackSpineThickness / 2,
-				-sideBraceWidth / 2,
-			}),
-			mgl64.Vec3{0, 0, 1},
+			mgl64.Vec3{
+				config.SegmentHeight / 2,
+				config.SpineThickness / 2,
+				-config.SideBraceWidth / 2,
+			},
+			mgl64.Vec3{0, 0, -1},
+			mgl64.Vec3{-1, 0, 0},
 		),
-	}
-
-	return sideBrace
-}
-
-func (sideBrace *SideBrace) Anchors() map[string]shapes.Anchor {
-	return sideBrace.anchors
-}
-
-func (sideBrace *SideBrace) SetAnchorTransform(transform primitive.Transform) error {
-	if sideBrace.anchorTransform != nil {
-		// TODO check if the preexisting anchorTransform might be identical to
-		// transform. If so, don't return an error.
-		return fmt.Errorf("trying to set conflicting anchor transforms")
-	}
-
-	sideBrace.anchorTransform = &transform
-
-	return nil
-}
-
-func (sideBrace *SideBrace) GetAnchorTransform() *primitive.Transform {
-	return sideBrace.anchorTransform
-}
-
-func (sideBrace *SideBrace) Disable() primitive.Primitive { //nolint:ireturn
-	sideBrace.prefix = "*"
-
-	return sideBrace
-}
-
-func (sideBrace *SideBrace) ShowOnly() primitive.Primitive { //nolint:ireturn
-	sideBrace.prefix = "!"
+	)
 
 	return sideBrace
 }
 
-func (sideBrace *SideBrace) Highlight() primitive.Primitive { //nolint:ireturn
-	sideBrace.prefix = "#"
-
-	return sideBrace
+// PrintOrientation prints the side brace as it is modelled, since it is
+// extruded along z already.
+func (sideBrace *SideBrace) PrintOrientation() shapes.PrintOrientation {
+	return shapes.FlatOrientation
 }
 
-func (sideBrace *SideBrace) Transparent() primitive.Primitive { //nolint:ireturn
-	sideBrace.prefix = "%"
+// NewMirroredSideBrace constructs a side brace for the right side of the
+// spine. It is the mirror image of the brace NewSideBrace constructs for the
+// left side.
+func NewMirroredSideBrace(name string, config Config, heightUnit uint8) *SideBrace {
+	sideBrace := NewSideBrace(name, config, heightUnit)
+	sideBrace.Mirror(mgl64.Vec3{0, 0, 1})
 
 	return sideBrace
 }
-
-func (sideBrace *SideBrace) Prefix() string {
-	return sideBrace.prefix
-}
-
-func (sideBrace *SideBrace) Render(w *bufio.Writer) {
-	if sideBrace.anchorTransform == nil {
-		panic("cannot render side brace without resolving its anchors")
-	}
-	sideBrace.anchorTransform.Add(sideBrace.contents)
-	sideBrace.anchorTransform.Render(w)
-}
@@ -0,0 +1,51 @@
+package rack
+
+import (
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/ljanyst/ghostscad/primitive"
+
+	"github.com/yeldiRium/3d-rack-brackets/internal/shapes"
+	"github.com/yeldiRium/3d-rack-brackets/internal/shapes/holes"
+)
+
+// SplicePlate joins two pieces of a split rail. It is bolted onto the back of
+// the rail through the holes on either side of the split.
+type SplicePlate struct {
+	shapes.AnchoredBase
+}
+
+// NewSplicePlate constructs a splice plate that attaches to the back of the
+// segment above the split.
+func NewSplicePlate(name string, config Config) *SplicePlate {
+	boltPitch := config.HolePitches[len(config.HolePitches)-1]
+	thickness := config.splicePlateThickness()
+
+	plate := primitive.NewCube(mgl64.Vec3{config.SpineWidth, thickness, boltPitch + 2*config.JointDepth})
+	// The bolts pass through the rail first, so the plate only needs round
+	// holes that are as wide as the rail's.
+	cutout := primitive.NewRotation(
+		mgl64.Vec3{-90, 0, 0},
+		holes.Round{Radius: config.Hole.FootprintRadius()}.Cutout(thickness),
+	)
+
+	splicePlate := &SplicePlate{}
+	splicePlate.AnchoredBase = shapes.MakeAnchoredBase(splicePlate, name)
+	splicePlate.Add(primitive.NewDifference(
+		plate,
+		primitive.NewTranslation(mgl64.Vec3{0, 0, boltPitch / 2}, cutout),
+		primitive.NewTranslation(mgl64.Vec3{0, 0, -boltPitch / 2}, cutout),
+	))
+	splicePlate.AddAnchors(
+		// The split lies at the plate's center, half a segment below the
+		// center of the segment above it.
+		shapes.NewAnchor("segmentattach", splicePlate, mgl64.Vec3{0, -thickness / 2, config.SegmentHeight / 2}, mgl64.Vec3{0, -1, 0}),
+	)
+
+	return splicePlate
+}
+
+// PrintOrientation lays the splice plate on the side that faces away from
+// the rail, like the rail's segments.
+func (splicePlate *SplicePlate) PrintOrientation() shapes.PrintOrientation {
+	return shapes.PrintOrientation{FaceDown: mgl64.Vec3{0, 1, 0}}
+}
@@ -1,27 +1,42 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"os"
+	"os/signal"
 	"runtime/pprof"
 
 	"github.com/alecthomas/kong"
 
+	"github.com/yeldiRium/3d-rack-brackets/internal/cmd/check"
+	"github.com/yeldiRium/3d-rack-brackets/internal/cmd/estimate"
 	"github.com/yeldiRium/3d-rack-brackets/internal/cmd/globals"
+	"github.com/yeldiRium/3d-rack-brackets/internal/cmd/inspect"
+	"github.com/yeldiRium/3d-rack-brackets/internal/cmd/plate"
 	"github.com/yeldiRium/3d-rack-brackets/internal/cmd/render"
+	"github.com/yeldiRium/3d-rack-brackets/internal/cmd/serve"
+	"github.com/yeldiRium/3d-rack-brackets/internal/cmd/watch"
 )
 
 var cli struct {
 	Debug      bool   `help:"Enable debug mode."`
 	CPUProfile string `type:"path"`
 
-	Render render.RenderCmd `cmd:"" help:"render the rack"`
+	Render   render.RenderCmd     `cmd:"" help:"render the rack"`
+	Inspect  inspect.InspectCmd   `cmd:"" help:"print the rack's anchor graph"`
+	Watch    watch.WatchCmd       `cmd:"" help:"render the rack again whenever its design changes"`
+	Serve    serve.ServeCmd       `cmd:"" help:"preview the rack in the browser"`
+	Estimate estimate.EstimateCmd `cmd:"" help:"estimate the filament the rack takes"`
+	Plate    plate.PlateCmd       `cmd:"" help:"arrange the rack's parts on build plates"`
+	Check    check.CheckCmd       `cmd:"" help:"find the parts of the rack that are hard to print"`
 }
 
 func main() {
 	ctx := kong.Parse(&cli,
 		kong.UsageOnError(),
+		render.DefaultVars(),
 	)
 
 	logLevel := slog.LevelInfo
@@ -39,10 +54,15 @@ func main() {
 	}
 	defer stopProfiling()
 
+	interruptContext, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
 	err := ctx.Run(&globals.Globals{
-		Debug:  cli.Debug,
-		Logger: logger,
-		Stdout: os.Stdout,
+		Context: interruptContext,
+		Debug:   cli.Debug,
+		Logger:  logger,
+		Stdout:  os.Stdout,
+		Cleanup: stopProfiling,
 	})
 	ctx.FatalIfErrorf(err)
 }